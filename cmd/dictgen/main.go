@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/storage"
+)
+
+// dictgen trains the dictionary used to compress stored response bodies.
+//
+// Each argument is a response body or a directory of them, such as the
+// testdata fixtures or the root of a filesystem response cache:
+//
+//	go run ./cmd/dictgen -out pkg/storage/dictionaries/responses-v1.dict \
+//		pkg/retrieve/players/testdata pkg/testutils/testdata
+func main() {
+	out := flag.String("out", "", "Path to write the dictionary to")
+	size := flag.Int("size", 16*1024, "Maximum size of the dictionary in bytes")
+	flag.Parse()
+
+	if *out == "" || flag.NArg() == 0 {
+		log.Fatalf("Usage: dictgen -out <path> <sample path>...")
+	}
+
+	paths := make([]string, 0)
+	for _, root := range flag.Args() {
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			// Metadata written alongside cached responses isn't a response body.
+			if entry.Type().IsRegular() && !strings.HasSuffix(path, ".meta.json") {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatalf("Failed to find samples in %s: %v", root, err)
+		}
+	}
+	// Sample order changes the result, so keep it stable between runs.
+	sort.Strings(paths)
+
+	samples := make([][]byte, 0, len(paths))
+	for _, path := range paths {
+		sample, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Failed to read sample: %v", err)
+		}
+		samples = append(samples, sample)
+	}
+	log.Printf("Training dictionary from %d samples", len(samples))
+
+	dictionary := storage.TrainDictionary(samples, *size)
+	err := os.WriteFile(*out, dictionary, 0o644)
+	if err != nil {
+		log.Fatalf("Failed to write dictionary: %v", err)
+	}
+	log.Printf("Wrote %d byte dictionary to %s", len(dictionary), *out)
+}
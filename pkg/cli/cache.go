@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	ucli "github.com/urfave/cli/v2"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+	"github.com/crbednarz/moonkinmetrics/pkg/storage"
+)
+
+var cacheCommand = &ucli.Command{
+	Name:  "cache",
+	Usage: "Inspect and maintain the response cache",
+	Subcommands: []*ucli.Command{
+		{
+			Name:   "stats",
+			Usage:  "Summarize cached responses by namespace and endpoint family",
+			Action: runCacheStats,
+		},
+		{
+			Name:      "get",
+			Usage:     "Print a cached response body",
+			ArgsUsage: "<url>",
+			Action:    runCacheGet,
+		},
+		{
+			Name:   "rm",
+			Usage:  "Remove cached responses",
+			Action: runCacheRemove,
+			Flags: []ucli.Flag{
+				&ucli.StringFlag{
+					Name:     "prefix",
+					Usage:    "Remove responses whose request URL starts with this prefix",
+					Required: true,
+				},
+			},
+		},
+		{
+			Name:   "vacuum",
+			Usage:  "Rebuild the cache database to reclaim unused space",
+			Action: runCacheVacuum,
+		},
+		{
+			Name:      "export",
+			Usage:     "Export cached responses to a portable archive",
+			ArgsUsage: "<archive>",
+			Action:    runCacheExport,
+		},
+		{
+			Name:      "import",
+			Usage:     "Import cached responses from an archive",
+			ArgsUsage: "<archive>",
+			Action:    runCacheImport,
+		},
+	},
+}
+
+func buildSqliteStorage(c *ucli.Context) (*storage.Sqlite, error) {
+	responseStorage, err := buildStorage(c)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build storage: %w", err)
+	}
+
+	sqlite, ok := responseStorage.(*storage.Sqlite)
+	if !ok {
+		return nil, fmt.Errorf("cache maintenance is only supported for sqlite storage")
+	}
+	return sqlite, nil
+}
+
+func runCacheStats(c *ucli.Context) error {
+	sqlite, err := buildSqliteStorage(c)
+	if err != nil {
+		return err
+	}
+
+	stats, err := sqlite.Stats()
+	if err != nil {
+		return fmt.Errorf("unable to read cache stats: %w", err)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAMESPACE\tFAMILY\tROWS\tBYTES\tEXPIRED")
+	for _, family := range stats {
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%d\t%d\n", family.Namespace, family.Family, family.Rows, family.Bytes, family.Expired)
+	}
+	return writer.Flush()
+}
+
+func runCacheGet(c *ucli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("expected a single url argument")
+	}
+
+	request, err := api.RequestFromUrl(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	sqlite, err := buildSqliteStorage(c)
+	if err != nil {
+		return err
+	}
+
+	response, err := sqlite.Get(&request)
+	if err != nil {
+		return fmt.Errorf("unable to get %s: %w", request.Id(), err)
+	}
+
+	var output bytes.Buffer
+	err = json.Indent(&output, response.Body, "", "  ")
+	if err != nil {
+		return fmt.Errorf("stored body is not valid json: %w", err)
+	}
+	output.WriteByte('\n')
+
+	log.Printf("Stored at %s, expires at %s", response.Timestamp, response.Expires)
+	_, err = output.WriteTo(os.Stdout)
+	return err
+}
+
+func runCacheRemove(c *ucli.Context) error {
+	sqlite, err := buildSqliteStorage(c)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := sqlite.DeletePrefix(c.String("prefix"))
+	if err != nil {
+		return fmt.Errorf("unable to remove responses: %w", err)
+	}
+	log.Printf("Removed %d entries", deleted)
+	return nil
+}
+
+func runCacheVacuum(c *ucli.Context) error {
+	sqlite, err := buildSqliteStorage(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := sqlite.Vacuum()
+	if err != nil {
+		return fmt.Errorf("unable to vacuum storage: %w", err)
+	}
+	log.Printf("Storage vacuumed: %d bytes before, %d bytes after", result.BytesBefore, result.BytesAfter)
+	return nil
+}
+
+func runCacheExport(c *ucli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("expected a single archive argument")
+	}
+
+	sqlite, err := buildSqliteStorage(c)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(c.Args().First())
+	if err != nil {
+		return fmt.Errorf("unable to create archive: %w", err)
+	}
+	defer file.Close()
+
+	count, err := sqlite.Export(file)
+	if err != nil {
+		return fmt.Errorf("unable to export cache: %w", err)
+	}
+	log.Printf("Exported %d entries to %s", count, c.Args().First())
+	return file.Close()
+}
+
+func runCacheImport(c *ucli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("expected a single archive argument")
+	}
+
+	sqlite, err := buildSqliteStorage(c)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(c.Args().First())
+	if err != nil {
+		return fmt.Errorf("unable to open archive: %w", err)
+	}
+	defer file.Close()
+
+	count, err := sqlite.Import(file)
+	if err != nil {
+		return fmt.Errorf("unable to import cache: %w", err)
+	}
+	log.Printf("Imported %d entries from %s", count, c.Args().First())
+	return nil
+}
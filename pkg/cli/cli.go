@@ -8,14 +8,18 @@ import (
 	"net/http"
 	"os"
 	"runtime/pprof"
+	"strconv"
 	"strings"
+	"time"
 
 	ucli "github.com/urfave/cli/v2"
 
 	"github.com/crbednarz/moonkinmetrics/pkg/api"
+	"github.com/crbednarz/moonkinmetrics/pkg/legality"
 	"github.com/crbednarz/moonkinmetrics/pkg/monitor"
+	"github.com/crbednarz/moonkinmetrics/pkg/retrieve/classes"
+	"github.com/crbednarz/moonkinmetrics/pkg/retrieve/keystones"
 	"github.com/crbednarz/moonkinmetrics/pkg/retrieve/seasons"
-	"github.com/crbednarz/moonkinmetrics/pkg/retrieve/talents"
 	"github.com/crbednarz/moonkinmetrics/pkg/scan"
 	"github.com/crbednarz/moonkinmetrics/pkg/serialize"
 	"github.com/crbednarz/moonkinmetrics/pkg/site"
@@ -25,27 +29,54 @@ import (
 	"go.opentelemetry.io/otel/metric"
 )
 
-func expandBracketArg(arg string) []string {
+func expandBracketArg(arg string, registry *wow.ClassRegistry) []string {
 	brackets := []string{arg}
 	if arg == "shuffle" || arg == "blitz" {
-		brackets = make([]string, 0, len(wow.SpecByClass)*3)
-		for class, specs := range wow.SpecByClass {
-			classSlug := strings.ReplaceAll(class, " ", "")
-			for _, spec := range specs {
-				specSlug := strings.ReplaceAll(spec, " ", "")
-				slug := strings.ToLower(fmt.Sprintf("%s-%s-%s", arg, classSlug, specSlug))
-				brackets = append(brackets, slug)
-			}
+		specs := registry.Specs()
+		brackets = make([]string, 0, len(specs))
+		for _, spec := range specs {
+			slug := fmt.Sprintf("%s-%s-%s", arg, wow.Slugify(spec.ClassName), spec.Slug)
+			brackets = append(brackets, slug)
 		}
 	}
 	return brackets
 }
 
+// buildClassRegistry retrieves the playable classes and specializations,
+// falling back to the hardcoded list if the API is unavailable.
+func buildClassRegistry(scanner *scan.Scanner) *wow.ClassRegistry {
+	registry, err := classes.GetClassRegistry(scanner)
+	if err != nil {
+		log.Printf("Unable to retrieve class registry, using defaults: %v", err)
+		return wow.DefaultClassRegistry()
+	}
+	log.Printf("Class registry retrieved: %d specs", len(registry.Specs()))
+	return registry
+}
+
 type bracketScanOptions struct {
 	Region    api.Region
 	Bracket   string
 	Output    string
 	MinRating uint
+	// MinGamesPlayed excludes entries with fewer games played this season.
+	MinGamesPlayed uint
+	// MinWinRate excludes entries with a lower win rate, between 0 and 1.
+	MinWinRate float64
+	// Season is the PvP season to scan, or zero for the current season.
+	Season int
+	// Historical is set when Season is a past season.
+	Historical bool
+	// Equipment enables scanning and exporting the equipment of each entry.
+	Equipment bool
+	Registry  *wow.ClassRegistry
+	Legality  site.LegalityPolicy
+	// SavedLoadouts enables exporting every loadout each entry has saved.
+	SavedLoadouts bool
+	// Summaries enables retrieving each entry's profile summary, marking
+	// those who last logged in StaleAfter before the snapshot as stale.
+	Summaries  bool
+	StaleAfter time.Duration
 }
 
 type scannerConfiguration struct {
@@ -68,9 +99,9 @@ func runTalentScan(c *ucli.Context) error {
 		return fmt.Errorf("unable to build API scanner: %w", err)
 	}
 
-	trees, err := talents.GetTalentTrees(scanner)
+	trees, err := getTalentTrees(c, scanner)
 	if err != nil {
-		return fmt.Errorf("unable to retrieve talent trees: %w", err)
+		return err
 	}
 
 	for i := range trees {
@@ -81,41 +112,156 @@ func runTalentScan(c *ucli.Context) error {
 		}
 	}
 	log.Printf("Talents retrieved: %d total", len(trees))
+
+	// The schema is published alongside the talents for consumers of the output.
+	schemaPath := fmt.Sprintf("%s/talents/talents.schema.json", c.Path("output"))
+	err = os.WriteFile(schemaPath, serialize.TalentsSchema, 0o644)
+	if err != nil {
+		return fmt.Errorf("unable to write talents schema: %w", err)
+	}
 	return nil
 }
 
 func runPveScan(c *ucli.Context) error {
-	return errors.New("runPveScan not implemented")
+	region := api.Region(c.String("region"))
+	policy, err := buildLegalityPolicy(c)
+	if err != nil {
+		return err
+	}
+
+	scanner, err := buildScanner(c, &bnetScannerConfiguration)
+	if err != nil {
+		return fmt.Errorf("unable to build API scanner: %w", err)
+	}
+
+	trees, err := getTalentTrees(c, scanner)
+	if err != nil {
+		return err
+	}
+
+	leaderboard, err := keystones.GetCurrentLeaderboard(scanner, region)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve keystone leaderboard: %w", err)
+	}
+	log.Printf("Keystone leaderboard retrieved: %v entries", len(leaderboard.Entries))
+
+	leaderboard = leaderboard.FilterByMinRating(c.Uint("min-rating"))
+
+	enrichOptions := site.EnrichOptions{
+		Legality:      policy,
+		SavedLoadouts: c.Bool("saved-loadouts"),
+	}
+	enrichedLeaderboards, unresolved, err := site.EnrichLeaderboard(scanner, &leaderboard, trees, buildClassRegistry(scanner), enrichOptions)
+	if err != nil {
+		return fmt.Errorf("failed to enrich leaderboard: %w", err)
+	}
+	log.Printf("Unresolved entries: %d", len(unresolved))
+	logIllegalLoadouts(enrichedLeaderboards, policy)
+
+	for i := range enrichedLeaderboards {
+		leaderboard := &enrichedLeaderboards[i]
+		if c.Bool("summaries") {
+			err = scanSummaries(scanner, leaderboard, c.Duration("stale-after"))
+			if err != nil {
+				return err
+			}
+		}
+
+		path := fmt.Sprintf("%s/pve/%s", c.Path("output"), leaderboard.Bracket)
+		err = writeLeaderboard(leaderboard, path, region)
+		if err != nil {
+			return err
+		}
+
+		if c.Bool("equipment") {
+			err = scanEquipment(scanner, leaderboard, fmt.Sprintf("%s/equipment", path))
+			if err != nil {
+				return err
+			}
+		}
+
+		if enrichOptions.SavedLoadouts {
+			err = writeSavedLoadouts(leaderboard, fmt.Sprintf("%s/saved-loadouts", path))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// As with PvP, left out entries are archived alongside the leaderboards.
+	path := fmt.Sprintf("%s/pve/%s", c.Path("output"), leaderboard.Bracket)
+	return writeUnresolved(unresolved, leaderboard.Timestamp, path, leaderboard.Bracket, region)
 }
 
 func runLadderScan(c *ucli.Context) error {
 	region := api.Region(c.String("region"))
+	policy, err := buildLegalityPolicy(c)
+	if err != nil {
+		return err
+	}
 
 	scanner, err := buildScanner(c, &bnetScannerConfiguration)
 	if err != nil {
 		return fmt.Errorf("unable to build API scanner: %w", err)
 	}
 
-	trees, err := talents.GetTalentTrees(scanner)
+	trees, err := getTalentTrees(c, scanner)
 	if err != nil {
-		return fmt.Errorf("unable to retrieve talent trees: %w", err)
+		return err
 	}
 
-	brackets := expandBracketArg(c.String("bracket"))
-	for _, bracket := range brackets {
-		log.Printf("Scanning bracket: %s", bracket)
-		err = scanBracket(
-			scanner,
-			trees,
-			bracketScanOptions{
-				Region:    region,
-				Bracket:   bracket,
-				MinRating: c.Uint("min-rating"),
-				Output:    c.Path("output"),
-			},
-		)
+	seasonIds, err := expandSeasonArg(scanner, c.String("season"), region)
+	if err != nil {
+		return err
+	}
+
+	// Profiles only report current talents, so entries of past seasons are
+	// marked as such when exported.
+	currentSeasonId := 0
+	if c.String("season") != "" {
+		currentSeasonId, err = seasons.GetCurrentSeasonId(scanner, region)
 		if err != nil {
-			return fmt.Errorf("failed to scan bracket %s: %w", bracket, err)
+			return fmt.Errorf("unable to retrieve current season: %w", err)
+		}
+	}
+
+	registry := buildClassRegistry(scanner)
+	brackets := expandBracketArg(c.String("bracket"), registry)
+	for _, seasonId := range seasonIds {
+		for _, bracket := range brackets {
+			if seasonId != 0 {
+				log.Printf("Scanning bracket: %s (season %d)", bracket, seasonId)
+			} else {
+				log.Printf("Scanning bracket: %s", bracket)
+			}
+			err = scanBracket(
+				scanner,
+				trees,
+				bracketScanOptions{
+					Region:         region,
+					Bracket:        bracket,
+					MinRating:      c.Uint("min-rating"),
+					MinGamesPlayed: c.Uint("min-games"),
+					MinWinRate:     c.Float64("min-win-rate") / 100,
+					Output:         c.Path("output"),
+					Season:         seasonId,
+					Historical:     seasonId != 0 && seasonId != currentSeasonId,
+					Equipment:      c.Bool("equipment"),
+					Registry:       registry,
+					Legality:       policy,
+					SavedLoadouts:  c.Bool("saved-loadouts"),
+					Summaries:      c.Bool("summaries"),
+					StaleAfter:     c.Duration("stale-after"),
+				},
+			)
+			// Brackets such as per-spec shuffle don't exist in older seasons.
+			if c.String("season") == "all" && errors.Is(err, scan.ErrNotFound) {
+				log.Printf("Bracket %s not found in season %d, skipping", bracket, seasonId)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to scan bracket %s: %w", bracket, err)
+			}
 		}
 	}
 	return nil
@@ -132,53 +278,271 @@ func runClean(c *ucli.Context) error {
 	if err != nil {
 		return fmt.Errorf("unable to clean storage: %w", err)
 	}
-	log.Printf("Storage cleaned: %d entries removed", result.Deleted)
+	log.Printf(
+		"Storage cleaned: %d expired, %d evicted by age, %d evicted by size, %d history versions evicted, %d bytes reclaimed",
+		result.Deleted,
+		result.EvictedByAge,
+		result.EvictedBySize,
+		result.EvictedHistory,
+		result.ReclaimedBytes,
+	)
 	return nil
 }
 
 func scanBracket(scanner *scan.Scanner, trees []wow.TalentTree, options bracketScanOptions) error {
-	leaderboard, err := seasons.GetCurrentLeaderboard(scanner, options.Bracket, options.Region)
+	var leaderboard wow.Leaderboard
+	var err error
+	if options.Season == 0 {
+		leaderboard, err = seasons.GetCurrentLeaderboard(scanner, options.Bracket, options.Region)
+	} else {
+		leaderboard, err = seasons.GetSeasonLeaderboard(scanner, options.Season, options.Bracket, options.Region)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to retrieve leaderboard: %w", err)
 	}
 	log.Printf("Leaderboard retrieved: %v entries", len(leaderboard.Entries))
 
 	leaderboard = leaderboard.FilterByMinRating(options.MinRating)
-
-	enrichedLeaderboards, err := site.EnrichLeaderboard(scanner, &leaderboard, trees)
+	leaderboard = leaderboard.FilterByMinGamesPlayed(options.MinGamesPlayed)
+	leaderboard = leaderboard.FilterByMinWinRate(options.MinWinRate)
+	log.Printf("Leaderboard filtered: %v entries", len(leaderboard.Entries))
+
+	enrichOptions := site.EnrichOptions{
+		Legality:      options.Legality,
+		SavedLoadouts: options.SavedLoadouts,
+		Historical:    options.Historical,
+	}
+	enrichedLeaderboards, unresolved, err := site.EnrichLeaderboard(scanner, &leaderboard, trees, options.Registry, enrichOptions)
 	if err != nil {
 		return fmt.Errorf("failed to enrich leaderboard: %w", err)
 	}
+	log.Printf("Unresolved entries: %d", len(unresolved))
+	logIllegalLoadouts(enrichedLeaderboards, options.Legality)
+
+	basePath := fmt.Sprintf("%s/pvp", options.Output)
+	if options.Season != 0 {
+		basePath = fmt.Sprintf("%s/pvp/season-%d", options.Output, options.Season)
+	}
+
+	bracketPath := fmt.Sprintf("%s/%s", basePath, leaderboard.Bracket)
+	if strings.HasPrefix(leaderboard.Bracket, "shuffle") {
+		bracketPath = fmt.Sprintf("%s/shuffle", basePath)
+	}
+	if strings.HasPrefix(leaderboard.Bracket, "blitz") {
+		bracketPath = fmt.Sprintf("%s/blitz", basePath)
+	}
 
 	for i := range enrichedLeaderboards {
 		leaderboard := &enrichedLeaderboards[i]
+		if options.Summaries {
+			err = scanSummaries(scanner, leaderboard, options.StaleAfter)
+			if err != nil {
+				return err
+			}
+		}
 
-		data, err := serialize.ExportLeaderboardToJson(leaderboard)
+		err = writeLeaderboard(leaderboard, bracketPath, options.Region)
 		if err != nil {
-			return fmt.Errorf("failed to serialize leaderboard: %w", err)
+			return err
 		}
 
-		fileName := fmt.Sprintf("%s-%s.%s.json", leaderboard.ClassName, leaderboard.SpecName, options.Region)
-		fileName = strings.ReplaceAll(fileName, " ", "-")
-		fileName = strings.ToLower(fileName)
-
-		path := fmt.Sprintf("%s/pvp/%s", options.Output, leaderboard.Bracket)
-		if strings.HasPrefix(leaderboard.Bracket, "shuffle") {
-			path = fmt.Sprintf("%s/pvp/shuffle", options.Output)
+		if options.Equipment {
+			err = scanEquipment(scanner, leaderboard, fmt.Sprintf("%s/equipment", bracketPath))
+			if err != nil {
+				return err
+			}
 		}
-		if strings.HasPrefix(leaderboard.Bracket, "blitz") {
-			path = fmt.Sprintf("%s/pvp/blitz", options.Output)
+
+		if options.SavedLoadouts {
+			err = writeSavedLoadouts(leaderboard, fmt.Sprintf("%s/saved-loadouts", bracketPath))
+			if err != nil {
+				return err
+			}
 		}
-		err = os.MkdirAll(path, 0o755)
+	}
+
+	// Entries which were left out, whether their profile couldn't be found or
+	// their loadout was illegal, are archived alongside the leaderboards.
+	return writeUnresolved(unresolved, leaderboard.Timestamp, bracketPath, leaderboard.Bracket, options.Region)
+}
+
+func writeLeaderboard(leaderboard *site.EnrichedLeaderboard, path string, region api.Region) error {
+	data, err := serialize.ExportLeaderboardToJson(leaderboard)
+	if err != nil {
+		return fmt.Errorf("failed to serialize leaderboard: %w", err)
+	}
+
+	fileName := fmt.Sprintf("%s-%s.%s.json", leaderboard.ClassName, leaderboard.SpecName, region)
+	fileName = strings.ReplaceAll(fileName, " ", "-")
+	fileName = strings.ToLower(fileName)
+
+	err = os.MkdirAll(path, 0o755)
+	if err != nil {
+		return fmt.Errorf("unable to create leaderboard directory: %w", err)
+	}
+	path = fmt.Sprintf("%s/%s", path, fileName)
+	err = os.WriteFile(path, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("unable to write leaderboard: %w", err)
+	}
+	log.Printf("Exported %s", path)
+	return nil
+}
+
+func scanEquipment(scanner *scan.Scanner, leaderboard *site.EnrichedLeaderboard, path string) error {
+	err := site.EnrichEquipment(scanner, leaderboard)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve equipment: %w", err)
+	}
+
+	data, err := serialize.ExportEquipmentToJson(leaderboard)
+	if err != nil {
+		return fmt.Errorf("failed to serialize equipment: %w", err)
+	}
+
+	fileName := fmt.Sprintf("%s-%s.%s.json", leaderboard.ClassName, leaderboard.SpecName, leaderboard.Region)
+	fileName = strings.ReplaceAll(fileName, " ", "-")
+	fileName = strings.ToLower(fileName)
+
+	err = os.MkdirAll(path, 0o755)
+	if err != nil {
+		return fmt.Errorf("unable to create equipment directory: %w", err)
+	}
+	path = fmt.Sprintf("%s/%s", path, fileName)
+	err = os.WriteFile(path, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("unable to write equipment: %w", err)
+	}
+	log.Printf("Exported %s", path)
+	return nil
+}
+
+func scanSummaries(scanner *scan.Scanner, leaderboard *site.EnrichedLeaderboard, staleAfter time.Duration) error {
+	stale, err := site.EnrichSummaries(scanner, leaderboard, staleAfter)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve character summaries: %w", err)
+	}
+	log.Printf(
+		"Character summaries retrieved [Class: %s, Spec: %s, Stale talents: %d]",
+		leaderboard.ClassName,
+		leaderboard.SpecName,
+		stale,
+	)
+	return nil
+}
+
+func writeSavedLoadouts(leaderboard *site.EnrichedLeaderboard, path string) error {
+	data, err := serialize.ExportSavedLoadoutsToJson(leaderboard)
+	if err != nil {
+		return fmt.Errorf("failed to serialize saved loadouts: %w", err)
+	}
+
+	fileName := fmt.Sprintf("%s-%s.%s.json", leaderboard.ClassName, leaderboard.SpecName, leaderboard.Region)
+	fileName = strings.ReplaceAll(fileName, " ", "-")
+	fileName = strings.ToLower(fileName)
+
+	err = os.MkdirAll(path, 0o755)
+	if err != nil {
+		return fmt.Errorf("unable to create saved loadouts directory: %w", err)
+	}
+	path = fmt.Sprintf("%s/%s", path, fileName)
+	err = os.WriteFile(path, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("unable to write saved loadouts: %w", err)
+	}
+	log.Printf("Exported %s", path)
+	return nil
+}
+
+func writeUnresolved(unresolved []site.UnresolvedEntry, timestamp time.Time, path string, bracket string, region api.Region) error {
+	data, err := serialize.ExportUnresolvedToJson(unresolved, timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to serialize unresolved entries: %w", err)
+	}
+
+	err = os.MkdirAll(path, 0o755)
+	if err != nil {
+		return fmt.Errorf("unable to create leaderboard directory: %w", err)
+	}
+	path = fmt.Sprintf("%s/unresolved-%s.%s.json", path, bracket, region)
+	err = os.WriteFile(path, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("unable to write unresolved entries: %w", err)
+	}
+	log.Printf("Exported %s", path)
+	return nil
+}
+
+// expandSeasonArg resolves the --season flag to a list of season ids.
+// Zero refers to the current season, which is scanned when no season is given.
+func expandSeasonArg(scanner *scan.Scanner, arg string, region api.Region) ([]int, error) {
+	switch arg {
+	case "":
+		return []int{0}, nil
+	case "all":
+		index, err := seasons.GetSeasonsIndex(scanner, region)
 		if err != nil {
-			return fmt.Errorf("unable to create pvp directory: %w", err)
+			return nil, fmt.Errorf("unable to retrieve seasons index: %w", err)
 		}
-		path = fmt.Sprintf("%s/%s", path, fileName)
-		os.WriteFile(path, data, 0o644)
-		log.Printf("Exported %s", path)
+		seasonIds := make([]int, len(index.Seasons))
+		for i, season := range index.Seasons {
+			seasonIds[i] = season.Id
+		}
+		return seasonIds, nil
+	default:
+		seasonId, err := strconv.Atoi(arg)
+		if err != nil || seasonId <= 0 {
+			return nil, fmt.Errorf("invalid season: %s", arg)
+		}
+		return []int{seasonId}, nil
+	}
+}
+
+// buildLegalityPolicy reads the legality flags shared by leaderboard scans.
+// Point limits are given as "class,spec,hero", with zero leaving a section
+// unchecked, and default to the current patch's limits.
+func buildLegalityPolicy(c *ucli.Context) (site.LegalityPolicy, error) {
+	policy := site.LegalityPolicy{
+		KeepIllegal: c.Bool("keep-illegal"),
 	}
 
-	return nil
+	arg := c.String("max-talent-points")
+	if arg == "" {
+		policy.Options = append(policy.Options, legality.WithPointLimits(legality.CurrentPointLimits))
+		return policy, nil
+	}
+
+	parts := strings.Split(arg, ",")
+	if len(parts) != 3 {
+		return policy, fmt.Errorf("invalid max talent points: %s", arg)
+	}
+	limits := make([]int, len(parts))
+	for i, part := range parts {
+		limit, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || limit < 0 {
+			return policy, fmt.Errorf("invalid max talent points: %s", arg)
+		}
+		limits[i] = limit
+	}
+
+	policy.Options = append(policy.Options, legality.WithPointLimits(legality.PointLimits{
+		Class: limits[0],
+		Spec:  limits[1],
+		Hero:  limits[2],
+	}))
+	return policy, nil
+}
+
+func logIllegalLoadouts(leaderboards []site.EnrichedLeaderboard, policy site.LegalityPolicy) {
+	illegal := 0
+	for i := range leaderboards {
+		illegal += leaderboards[i].IllegalLoadouts
+	}
+	if policy.KeepIllegal {
+		log.Printf("Illegal loadouts kept: %d", illegal)
+	} else {
+		log.Printf("Illegal loadouts dropped: %d", illegal)
+	}
 }
 
 func buildScanner(c *ucli.Context, config *scannerConfiguration) (*scan.Scanner, error) {
@@ -208,34 +572,83 @@ func buildScanner(c *ucli.Context, config *scannerConfiguration) (*scan.Scanner,
 		log.Printf("Authentication complete")
 	}
 
-	storage, err := buildStorage(c)
+	responseStorage, err := buildStorage(c)
 	if err != nil {
 		return nil, fmt.Errorf("unable to build storage: %w", err)
 	}
 	log.Printf("Storage initialized")
 
+	if memoryCacheSize := c.Uint64("memory-cache-size"); memoryCacheSize > 0 {
+		responseStorage = storage.NewMemoryCache(responseStorage, int64(memoryCacheSize)*1024*1024)
+	}
+
 	var meter metric.Meter
 	if c.String("collector") != "" {
 		meter = otel.Meter(config.MetricsName)
 	}
 
+	lifespanPolicy, err := buildLifespanPolicy(c)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build lifespan policy: %w", err)
+	}
+
 	return scan.NewScanner(
-		storage,
+		responseStorage,
 		client,
 		scan.WithMetrics(meter),
+		scan.WithLifespanPolicy(lifespanPolicy),
 	)
 }
 
+// buildLifespanPolicy combines rules from the lifespan config file with those
+// passed as flags. Flag rules are applied last, so they win ties.
+func buildLifespanPolicy(c *ucli.Context) (*scan.LifespanPolicy, error) {
+	policy := &scan.LifespanPolicy{}
+	if c.Path("lifespan-config") != "" {
+		loadedPolicy, err := scan.LoadLifespanPolicy(c.Path("lifespan-config"))
+		if err != nil {
+			return nil, err
+		}
+		policy = loadedPolicy
+	}
+
+	for _, ruleText := range c.StringSlice("lifespan") {
+		rule, err := scan.ParseLifespanRule(ruleText)
+		if err != nil {
+			return nil, err
+		}
+		policy.Rules = append(policy.Rules, rule)
+	}
+	return policy, nil
+}
+
 func buildStorage(c *ucli.Context) (storage.ResponseStorage, error) {
 	offline := c.Bool("offline")
 	err := os.MkdirAll(c.Path("cache-dir"), 0o755)
 	if err != nil {
-		return nil, fmt.Errorf("unable to create pvp directory: %w", err)
+		return nil, fmt.Errorf("unable to create cache directory: %w", err)
+	}
+
+	switch c.String("storage") {
+	case "sqlite":
+		storagePath := fmt.Sprintf("%s/wow.db", c.Path("cache-dir"))
+		return storage.NewSqlite(storagePath, storage.SqliteOptions{
+			NoExpire:    offline,
+			HistorySize: c.Int("history-size"),
+			MaxSize:     int64(c.Uint64("max-size")) * 1024 * 1024,
+			MaxAge:      c.Duration("max-age"),
+		})
+	case "fs":
+		if c.Uint64("max-size") > 0 || c.Duration("max-age") > 0 {
+			return nil, fmt.Errorf("eviction limits are only supported by sqlite storage")
+		}
+		storagePath := fmt.Sprintf("%s/responses", c.Path("cache-dir"))
+		return storage.NewFilesystem(storagePath, storage.FilesystemOptions{
+			NoExpire: offline,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage type: %s", c.String("storage"))
 	}
-	storagePath := fmt.Sprintf("%s/wow.db", c.Path("cache-dir"))
-	return storage.NewSqlite(storagePath, storage.SqliteOptions{
-		NoExpire: offline,
-	})
 }
 
 func Run(ctx context.Context) error {
@@ -279,11 +692,38 @@ func Run(ctx context.Context) error {
 				Usage: "Cache directory",
 				Value: ".",
 			},
+			&ucli.StringFlag{
+				Name:  "storage",
+				Usage: "Cache storage backend (sqlite or fs)",
+				Value: "sqlite",
+			},
+			&ucli.PathFlag{
+				Name:  "lifespan-config",
+				Usage: "JSON file of cache lifespan rules by namespace and path prefix",
+			},
+			&ucli.StringSliceFlag{
+				Name:  "lifespan",
+				Usage: "Cache lifespan rule in the form namespace:/path/prefix=duration",
+			},
+			&ucli.IntFlag{
+				Name:  "history-size",
+				Usage: "Number of versions of each response to retain in sqlite storage (0 to disable)",
+				Value: 0,
+			},
+			&ucli.Uint64Flag{
+				Name:  "memory-cache-size",
+				Usage: "Size in MiB of the in-memory response cache (0 to disable)",
+				Value: 256,
+			},
 			&ucli.PathFlag{
 				Name:  "perf",
 				Usage: "Enable performance profiling",
 				Value: "",
 			},
+			&ucli.PathFlag{
+				Name:  "ingame-trees",
+				Usage: "JSON file, or directory of JSON files, of ingame talent trees for specs missing from the Battle.net API",
+			},
 			&ucli.StringFlag{
 				Name:  "collector",
 				Usage: "URL of the OpenTelemetry collector",
@@ -323,16 +763,66 @@ func Run(ctx context.Context) error {
 				Name:   "clean",
 				Usage:  "Clean up expired cache entries",
 				Action: runClean,
+				Flags: []ucli.Flag{
+					&ucli.Uint64Flag{
+						Name:  "max-size",
+						Usage: "Evict least recently used entries until the cache is at most this many MiB (0 to disable)",
+					},
+					&ucli.DurationFlag{
+						Name:  "max-age",
+						Usage: "Evict entries stored longer ago than this, even if unexpired (0 to disable)",
+					},
+				},
 			},
+			cacheCommand,
+			ingameTreesCommand,
 			{
 				Name:   "talents",
 				Usage:  "Export talents to JSON",
 				Action: runTalentScan,
+				Subcommands: []*ucli.Command{
+					talentDiffCommand,
+				},
 			},
 			{
 				Name:   "pve",
 				Usage:  "Export pve leaderboards to JSON",
 				Action: runPveScan,
+				Flags: []ucli.Flag{
+					&ucli.StringFlag{
+						Name:  "region",
+						Usage: "Region to scan",
+					},
+					&ucli.UintFlag{
+						Name:  "min-rating",
+						Usage: "Minimum run rating to include",
+					},
+					&ucli.BoolFlag{
+						Name:  "equipment",
+						Usage: "Also export item, enchant and gem popularity",
+					},
+					&ucli.BoolFlag{
+						Name:  "summaries",
+						Usage: "Also retrieve each player's profile summary, marking those with stale talents",
+					},
+					&ucli.DurationFlag{
+						Name:  "stale-after",
+						Usage: "Mark players who last logged in this long before the leaderboard was retrieved as having stale talents",
+						Value: time.Hour * 24 * 7,
+					},
+					&ucli.BoolFlag{
+						Name:  "saved-loadouts",
+						Usage: "Also export every loadout players have saved, weighted per player",
+					},
+					&ucli.BoolFlag{
+						Name:  "keep-illegal",
+						Usage: "Keep loadouts which aren't legal for their talent tree, rather than dropping them",
+					},
+					&ucli.StringFlag{
+						Name:  "max-talent-points",
+						Usage: "Points available in the class, spec and hero trees as \"class,spec,hero\" (0 to leave unchecked), defaulting to the current patch",
+					},
+				},
 			},
 			{
 				Name:   "ladder",
@@ -347,11 +837,48 @@ func Run(ctx context.Context) error {
 						Name:  "region",
 						Usage: "Region to scan",
 					},
+					&ucli.StringFlag{
+						Name:  "season",
+						Usage: "PvP season id to scan, or \"all\" for every season (defaults to the current season)",
+					},
 					&ucli.UintFlag{
 						Name:  "min-rating",
 						Usage: "Minimum rating to include",
 						Value: 1400,
 					},
+					&ucli.UintFlag{
+						Name:  "min-games",
+						Usage: "Minimum games played this season to include",
+					},
+					&ucli.Float64Flag{
+						Name:  "min-win-rate",
+						Usage: "Minimum win rate to include, as a percentage",
+					},
+					&ucli.BoolFlag{
+						Name:  "equipment",
+						Usage: "Also export item, enchant and gem popularity",
+					},
+					&ucli.BoolFlag{
+						Name:  "summaries",
+						Usage: "Also retrieve each player's profile summary, marking those with stale talents",
+					},
+					&ucli.DurationFlag{
+						Name:  "stale-after",
+						Usage: "Mark players who last logged in this long before the leaderboard was retrieved as having stale talents",
+						Value: time.Hour * 24 * 7,
+					},
+					&ucli.BoolFlag{
+						Name:  "saved-loadouts",
+						Usage: "Also export every loadout players have saved, weighted per player",
+					},
+					&ucli.BoolFlag{
+						Name:  "keep-illegal",
+						Usage: "Keep loadouts which aren't legal for their talent tree, rather than dropping them",
+					},
+					&ucli.StringFlag{
+						Name:  "max-talent-points",
+						Usage: "Points available in the class, spec and hero trees as \"class,spec,hero\" (0 to leave unchecked), defaulting to the current patch",
+					},
 					&ucli.UintFlag{
 						Name:  "max-entries",
 						Usage: "Maximum entries to include",
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	ucli "github.com/urfave/cli/v2"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/serialize"
+	"github.com/crbednarz/moonkinmetrics/pkg/talentdiff"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+var talentDiffCommand = &ucli.Command{
+	Name:      "diff",
+	Usage:     "Compare two directories of exported talents",
+	ArgsUsage: "<old-dir> <new-dir>",
+	Action:    runTalentDiff,
+	Flags: []ucli.Flag{
+		&ucli.BoolFlag{
+			Name:  "json",
+			Usage: "Write the diff as JSON rather than text",
+		},
+	},
+}
+
+func runTalentDiff(c *ucli.Context) error {
+	if c.NArg() != 2 {
+		return fmt.Errorf("expected old and new directory arguments")
+	}
+
+	oldTrees, err := loadExportedTalents(c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	newTrees, err := loadExportedTalents(c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	diff := talentdiff.Compare(oldTrees, newTrees)
+	if !c.Bool("json") {
+		return talentdiff.WriteText(os.Stdout, &diff)
+	}
+
+	output, err := serialize.ExportTalentDiffToJson(&diff)
+	if err != nil {
+		return fmt.Errorf("unable to serialize talent diff: %w", err)
+	}
+	_, err = os.Stdout.Write(append(output, '\n'))
+	return err
+}
+
+// loadExportedTalents reads every tree written by the talents command to a
+// directory. Either the output directory or its talents directory may be given.
+func loadExportedTalents(dir string) ([]wow.TalentTree, error) {
+	talentsDir := filepath.Join(dir, "talents")
+	if info, err := os.Stat(talentsDir); err == nil && info.IsDir() {
+		dir = talentsDir
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	trees := make([]wow.TalentTree, 0, len(paths))
+	for _, path := range paths {
+		if strings.HasSuffix(path, ".schema.json") {
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		tree, err := serialize.ImportTalentsFromJson(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read talents from %s: %w", path, err)
+		}
+		trees = append(trees, tree)
+	}
+
+	if len(trees) == 0 {
+		return nil, fmt.Errorf("no exported talents found in %s", dir)
+	}
+	return trees, nil
+}
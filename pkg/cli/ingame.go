@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	ucli "github.com/urfave/cli/v2"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/hack"
+	"github.com/crbednarz/moonkinmetrics/pkg/retrieve/talents"
+	"github.com/crbednarz/moonkinmetrics/pkg/scan"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+var ingameTreesCommand = &ucli.Command{
+	Name:  "ingame-trees",
+	Usage: "Manage ingame talent trees used for specs missing from the Battle.net API",
+	Subcommands: []*ucli.Command{
+		{
+			Name:      "validate",
+			Usage:     "Validate ingame tree definitions",
+			ArgsUsage: "<path.json|dir>",
+			Action:    runIngameTreesValidate,
+		},
+		{
+			Name:   "convert",
+			Usage:  "Convert client trait table CSV exports into an ingame tree definition",
+			Action: runIngameTreesConvert,
+			Flags: []ucli.Flag{
+				&ucli.PathFlag{
+					Name:     "nodes",
+					Usage:    "TraitNode CSV export",
+					Required: true,
+				},
+				&ucli.PathFlag{
+					Name:     "edges",
+					Usage:    "TraitEdge CSV export",
+					Required: true,
+				},
+				&ucli.PathFlag{
+					Name:     "entries",
+					Usage:    "TraitNodeXTraitNodeEntry CSV export",
+					Required: true,
+				},
+				&ucli.IntFlag{
+					Name:     "trait-tree",
+					Usage:    "Trait tree id of the class",
+					Required: true,
+				},
+				&ucli.StringFlag{
+					Name:     "class-name",
+					Usage:    "Class name, e.g. \"Death Knight\"",
+					Required: true,
+				},
+				&ucli.IntFlag{
+					Name:     "class-id",
+					Usage:    "Class id",
+					Required: true,
+				},
+				&ucli.StringFlag{
+					Name:     "spec-name",
+					Usage:    "Spec name, e.g. \"Frost\"",
+					Required: true,
+				},
+				&ucli.IntFlag{
+					Name:     "spec-id",
+					Usage:    "Spec id",
+					Required: true,
+				},
+				&ucli.PathFlag{
+					Name:  "out",
+					Usage: "File to write the tree to (defaults to stdout)",
+				},
+			},
+		},
+	},
+}
+
+// getTalentTrees retrieves talent trees, including any ingame trees passed
+// with --ingame-trees.
+func getTalentTrees(c *ucli.Context, scanner *scan.Scanner) ([]wow.TalentTree, error) {
+	opts := make([]talents.TalentTreeOption, 0, 1)
+	if c.Path("ingame-trees") != "" {
+		ingameTrees, err := hack.LoadIngameTrees(c.Path("ingame-trees"))
+		if err != nil {
+			return nil, fmt.Errorf("unable to load ingame trees: %w", err)
+		}
+		log.Printf("Ingame trees loaded: %d", len(ingameTrees))
+		opts = append(opts, talents.WithIngameTrees(ingameTrees))
+	}
+
+	trees, err := talents.GetTalentTrees(scanner, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve talent trees: %w", err)
+	}
+	return trees, nil
+}
+
+func runIngameTreesValidate(c *ucli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("expected a single path argument")
+	}
+
+	trees, err := hack.LoadIngameTrees(c.Args().First())
+	if err != nil {
+		return err
+	}
+	for _, tree := range trees {
+		log.Printf("Valid ingame tree: %s - %s (%d nodes)", tree.ClassName, tree.SpecName, len(tree.Nodes))
+	}
+	return nil
+}
+
+func runIngameTreesConvert(c *ucli.Context) error {
+	files := make([]*os.File, 0, 3)
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	open := func(name string) (*os.File, error) {
+		file, err := os.Open(c.Path(name))
+		if err != nil {
+			return nil, fmt.Errorf("unable to open %s: %w", name, err)
+		}
+		files = append(files, file)
+		return file, nil
+	}
+
+	nodes, err := open("nodes")
+	if err != nil {
+		return err
+	}
+	edges, err := open("edges")
+	if err != nil {
+		return err
+	}
+	entries, err := open("entries")
+	if err != nil {
+		return err
+	}
+
+	tree, err := hack.IngameTreeFromTraitTables(
+		hack.TraitTables{Nodes: nodes, Edges: edges, NodeEntries: entries},
+		c.Int("trait-tree"),
+	)
+	if err != nil {
+		return err
+	}
+	tree.ClassName = c.String("class-name")
+	tree.ClassId = c.Int("class-id")
+	tree.SpecName = c.String("spec-name")
+	tree.SpecId = c.Int("spec-id")
+
+	err = hack.ValidateIngameTree(&tree)
+	if err != nil {
+		return fmt.Errorf("converted tree is invalid: %w", err)
+	}
+
+	output, err := json.MarshalIndent(tree, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to serialize ingame tree: %w", err)
+	}
+	output = append(output, '\n')
+
+	if c.Path("out") == "" {
+		_, err = os.Stdout.Write(output)
+		return err
+	}
+	err = os.WriteFile(c.Path("out"), output, 0o644)
+	if err != nil {
+		return fmt.Errorf("unable to write ingame tree: %w", err)
+	}
+	log.Printf("Ingame tree written: %s (%d nodes)", c.Path("out"), len(tree.Nodes))
+	return nil
+}
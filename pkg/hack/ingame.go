@@ -6,8 +6,10 @@ import (
 )
 
 type IngameNode struct {
-	Id        int   `json:"id"`
-	LockedBy  []int `json:"locked_by"`
+	Id       int   `json:"id"`
+	LockedBy []int `json:"locked_by"`
+	// Choice marks a node where one of its two talents is selected.
+	Choice    bool  `json:"choice,omitempty"`
 	Flags     int   `json:"flags"`
 	PosX      int   `json:"pos_x"`
 	PosY      int   `json:"pos_y"`
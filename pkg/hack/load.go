@@ -0,0 +1,176 @@
+package hack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LoadIngameTrees reads ingame tree definitions from a JSON file, or from every
+// JSON file in a directory. Each file holds either a single tree or a list of
+// trees. Every tree is validated, and no two trees may share a spec.
+func LoadIngameTrees(path string) ([]IngameTree, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{path}
+	if info.IsDir() {
+		paths, err = filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(paths)
+	}
+
+	trees := make([]IngameTree, 0, len(paths))
+	specPaths := make(map[int]string)
+	for _, treePath := range paths {
+		fileTrees, err := loadIngameTreeFile(treePath)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tree := range fileTrees {
+			err = ValidateIngameTree(&tree)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ingame tree in %s: %w", treePath, err)
+			}
+			if otherPath, ok := specPaths[tree.SpecId]; ok {
+				return nil, fmt.Errorf("ingame tree for spec %d is defined in both %s and %s", tree.SpecId, otherPath, treePath)
+			}
+			specPaths[tree.SpecId] = treePath
+			trees = append(trees, tree)
+		}
+	}
+	return trees, nil
+}
+
+func loadIngameTreeFile(path string) ([]IngameTree, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimSpace(data)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if len(data) > 0 && data[0] == '[' {
+		var trees []IngameTree
+		err = decoder.Decode(&trees)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ingame trees in %s: %w", path, err)
+		}
+		return trees, nil
+	}
+
+	var tree IngameTree
+	err = decoder.Decode(&tree)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ingame tree in %s: %w", path, err)
+	}
+	return []IngameTree{tree}, nil
+}
+
+// ValidateIngameTree checks that a tree can be turned into a talent tree:
+// nodes must be unique, have one talent or be a choice of two, and only be
+// locked by other nodes of the tree without forming a cycle.
+func ValidateIngameTree(tree *IngameTree) error {
+	problems := make([]string, 0)
+	if tree.ClassName == "" || tree.SpecName == "" {
+		problems = append(problems, "class and spec names are required")
+	}
+	if tree.ClassId <= 0 || tree.SpecId <= 0 {
+		problems = append(problems, "class and spec ids are required")
+	}
+	if len(tree.Nodes) == 0 {
+		problems = append(problems, "tree has no nodes")
+	}
+
+	nodes := make(map[int]*IngameNode, len(tree.Nodes))
+	for i := range tree.Nodes {
+		node := &tree.Nodes[i]
+		if node.Id <= 0 {
+			problems = append(problems, fmt.Sprintf("node %d has an invalid id", node.Id))
+			continue
+		}
+		if _, ok := nodes[node.Id]; ok {
+			problems = append(problems, fmt.Sprintf("node %d is defined more than once", node.Id))
+			continue
+		}
+		nodes[node.Id] = node
+
+		if node.Choice && len(node.TalentIds) != 2 {
+			problems = append(problems, fmt.Sprintf("choice node %d has %d talents, expected 2", node.Id, len(node.TalentIds)))
+		} else if !node.Choice && len(node.TalentIds) != 1 {
+			problems = append(problems, fmt.Sprintf("node %d has %d talents, expected 1", node.Id, len(node.TalentIds)))
+		}
+		for _, talentId := range node.TalentIds {
+			if talentId <= 0 {
+				problems = append(problems, fmt.Sprintf("node %d has an invalid talent id %d", node.Id, talentId))
+			}
+		}
+	}
+
+	for _, node := range tree.Nodes {
+		for _, lockedById := range node.LockedBy {
+			if lockedById == node.Id {
+				problems = append(problems, fmt.Sprintf("node %d is locked by itself", node.Id))
+			} else if _, ok := nodes[lockedById]; !ok {
+				problems = append(problems, fmt.Sprintf("node %d is locked by unknown node %d", node.Id, lockedById))
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		if nodeId, ok := findLockCycle(tree.Nodes, nodes); ok {
+			problems = append(problems, fmt.Sprintf("node %d is part of a cycle of prerequisites", nodeId))
+		}
+	}
+
+	if len(problems) != 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func findLockCycle(treeNodes []IngameNode, nodes map[int]*IngameNode) (int, bool) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make(map[int]int, len(nodes))
+
+	var visit func(nodeId int) (int, bool)
+	visit = func(nodeId int) (int, bool) {
+		switch states[nodeId] {
+		case visiting:
+			return nodeId, true
+		case visited:
+			return 0, false
+		}
+		states[nodeId] = visiting
+		for _, lockedById := range nodes[nodeId].LockedBy {
+			if cycleId, ok := visit(lockedById); ok {
+				return cycleId, true
+			}
+		}
+		states[nodeId] = visited
+		return 0, false
+	}
+
+	for _, node := range treeNodes {
+		if cycleId, ok := visit(node.Id); ok {
+			return cycleId, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,158 @@
+package hack
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const validIngameTree = `{
+	"class_name": "Druid",
+	"class_id": 11,
+	"spec_name": "Balance",
+	"spec_id": 102,
+	"nodes": [
+		{"id": 1, "locked_by": [], "flags": 0, "pos_x": 600, "pos_y": 600, "talent_ids": [10]},
+		{"id": 2, "locked_by": [1], "choice": true, "flags": 0, "pos_x": 600, "pos_y": 1200, "talent_ids": [20, 21]}
+	]
+}`
+
+func writeIngameTree(t *testing.T, dir string, name string, contents string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	err := os.WriteFile(path, []byte(contents), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write ingame tree: %v", err)
+	}
+	return path
+}
+
+func TestLoadIngameTreesFromFile(t *testing.T) {
+	path := writeIngameTree(t, t.TempDir(), "balance.json", validIngameTree)
+
+	trees, err := LoadIngameTrees(path)
+	if err != nil {
+		t.Fatalf("failed to load ingame trees: %v", err)
+	}
+	if len(trees) != 1 {
+		t.Fatalf("expected 1 tree, got %d", len(trees))
+	}
+	if trees[0].SpecId != 102 || len(trees[0].Nodes) != 2 {
+		t.Fatalf("expected spec 102 with 2 nodes, got spec %d with %d nodes", trees[0].SpecId, len(trees[0].Nodes))
+	}
+	if !slices.Equal(trees[0].Nodes[1].TalentIds, []int{20, 21}) {
+		t.Fatalf("expected talents [20 21], got %v", trees[0].Nodes[1].TalentIds)
+	}
+	if trees[0].Nodes[0].Choice || !trees[0].Nodes[1].Choice {
+		t.Fatalf("expected only node 2 to be a choice node")
+	}
+}
+
+func TestLoadIngameTreesFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeIngameTree(t, dir, "balance.json", validIngameTree)
+	feral := strings.ReplaceAll(validIngameTree, `"spec_id": 102`, `"spec_id": 103`)
+	writeIngameTree(t, dir, "feral.json", "["+feral+"]")
+	writeIngameTree(t, dir, "notes.txt", "not a tree")
+
+	trees, err := LoadIngameTrees(dir)
+	if err != nil {
+		t.Fatalf("failed to load ingame trees: %v", err)
+	}
+	if len(trees) != 2 {
+		t.Fatalf("expected 2 trees, got %d", len(trees))
+	}
+	if trees[0].SpecId != 102 || trees[1].SpecId != 103 {
+		t.Fatalf("expected specs 102 and 103, got %d and %d", trees[0].SpecId, trees[1].SpecId)
+	}
+}
+
+func TestLoadIngameTreesRejectsDuplicateSpecs(t *testing.T) {
+	dir := t.TempDir()
+	writeIngameTree(t, dir, "a.json", validIngameTree)
+	writeIngameTree(t, dir, "b.json", validIngameTree)
+
+	_, err := LoadIngameTrees(dir)
+	if err == nil {
+		t.Fatalf("expected error for duplicate specs")
+	}
+}
+
+func TestLoadIngameTreesRejectsUnknownFields(t *testing.T) {
+	contents := strings.ReplaceAll(validIngameTree, `"talent_ids"`, `"talents"`)
+	path := writeIngameTree(t, t.TempDir(), "balance.json", contents)
+
+	_, err := LoadIngameTrees(path)
+	if err == nil {
+		t.Fatalf("expected error for unknown fields")
+	}
+}
+
+func TestValidateIngameTree(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(tree *IngameTree)
+		problem string
+	}{
+		{
+			name:    "missing spec",
+			modify:  func(tree *IngameTree) { tree.SpecId = 0 },
+			problem: "ids are required",
+		},
+		{
+			name:    "duplicate node",
+			modify:  func(tree *IngameTree) { tree.Nodes[1].Id = 1 },
+			problem: "more than once",
+		},
+		{
+			name:    "too many talents",
+			modify:  func(tree *IngameTree) { tree.Nodes[1].TalentIds = []int{20, 21} },
+			problem: "expected 1",
+		},
+		{
+			name: "choice without two talents",
+			modify: func(tree *IngameTree) {
+				tree.Nodes[1].Choice = true
+				tree.Nodes[1].TalentIds = []int{20, 21, 22}
+			},
+			problem: "expected 2",
+		},
+		{
+			name:    "unknown prerequisite",
+			modify:  func(tree *IngameTree) { tree.Nodes[1].LockedBy = []int{3} },
+			problem: "unknown node 3",
+		},
+		{
+			name:    "cycle",
+			modify:  func(tree *IngameTree) { tree.Nodes[0].LockedBy = []int{2} },
+			problem: "cycle",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := IngameTree{
+				ClassName: "Druid",
+				ClassId:   11,
+				SpecName:  "Balance",
+				SpecId:    102,
+				Nodes: []IngameNode{
+					{Id: 1, TalentIds: []int{10}},
+					{Id: 2, LockedBy: []int{1}, TalentIds: []int{20}},
+				},
+			}
+			err := ValidateIngameTree(&tree)
+			if err != nil {
+				t.Fatalf("expected valid tree, got %v", err)
+			}
+
+			test.modify(&tree)
+			err = ValidateIngameTree(&tree)
+			if err == nil || !strings.Contains(err.Error(), test.problem) {
+				t.Fatalf("expected error containing %q, got %v", test.problem, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package hack
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// TraitTables are CSV exports of the client's trait data tables, as produced
+// by common DB2 export tools with a header row of column names.
+type TraitTables struct {
+	// Nodes is the TraitNode table.
+	Nodes io.Reader
+	// Edges is the TraitEdge table.
+	Edges io.Reader
+	// NodeEntries is the TraitNodeXTraitNodeEntry table.
+	NodeEntries io.Reader
+}
+
+const (
+	// traitNodeTypeSelection is the TraitNode type of choice nodes. Other
+	// nodes are single or tiered, and hold one talent.
+	traitNodeTypeSelection = 2
+	// traitEdgeTypeSufficientForAvailability is the TraitEdge type of
+	// prerequisites, where any one fully ranked node is enough. Other edges
+	// are visual only, or types no longer used by talent trees.
+	traitEdgeTypeSufficientForAvailability = 2
+)
+
+// IngameTreeFromTraitTables builds the class and spec nodes of a trait tree
+// from client data. Hero talent nodes are skipped, as hero trees aren't part of
+// ingame trees. Class, spec and node ownership aren't part of these tables, so
+// the names and ids of the returned tree must be filled in by the caller, and
+// nodes for other specs are dropped once their talents are retrieved.
+func IngameTreeFromTraitTables(tables TraitTables, traitTreeId int) (IngameTree, error) {
+	nodeRows, err := readTraitTable(tables.Nodes, "ID", "TraitTreeID", "PosX", "PosY", "Type", "Flags", "TraitSubTreeID")
+	if err != nil {
+		return IngameTree{}, fmt.Errorf("failed to read trait nodes: %w", err)
+	}
+	edgeRows, err := readTraitTable(tables.Edges, "LeftTraitNodeID", "RightTraitNodeID", "Type")
+	if err != nil {
+		return IngameTree{}, fmt.Errorf("failed to read trait edges: %w", err)
+	}
+	entryRows, err := readTraitTable(tables.NodeEntries, "TraitNodeID", "TraitNodeEntryID", "_Index")
+	if err != nil {
+		return IngameTree{}, fmt.Errorf("failed to read trait node entries: %w", err)
+	}
+
+	nodes := make([]IngameNode, 0)
+	nodeIndices := make(map[int]int)
+	for _, row := range nodeRows {
+		if row["TraitTreeID"] != traitTreeId || row["TraitSubTreeID"] != 0 {
+			continue
+		}
+		nodeIndices[row["ID"]] = len(nodes)
+		nodes = append(nodes, IngameNode{
+			Id:        row["ID"],
+			Choice:    row["Type"] == traitNodeTypeSelection,
+			Flags:     row["Flags"],
+			PosX:      row["PosX"],
+			PosY:      row["PosY"],
+			LockedBy:  []int{},
+			TalentIds: []int{},
+		})
+	}
+	if len(nodes) == 0 {
+		return IngameTree{}, fmt.Errorf("no nodes found for trait tree %d", traitTreeId)
+	}
+
+	sort.SliceStable(entryRows, func(i, j int) bool {
+		return entryRows[i]["_Index"] < entryRows[j]["_Index"]
+	})
+	for _, row := range entryRows {
+		index, ok := nodeIndices[row["TraitNodeID"]]
+		if !ok {
+			continue
+		}
+		node := &nodes[index]
+		node.TalentIds = append(node.TalentIds, row["TraitNodeEntryID"])
+	}
+
+	for _, row := range edgeRows {
+		if row["Type"] != traitEdgeTypeSufficientForAvailability {
+			continue
+		}
+		index, ok := nodeIndices[row["RightTraitNodeID"]]
+		if !ok {
+			continue
+		}
+		if _, ok := nodeIndices[row["LeftTraitNodeID"]]; !ok {
+			continue
+		}
+		node := &nodes[index]
+		node.LockedBy = append(node.LockedBy, row["LeftTraitNodeID"])
+	}
+
+	// Nodes without any entries are used by the client for decoration.
+	talentNodes := make([]IngameNode, 0, len(nodes))
+	for _, node := range nodes {
+		if len(node.TalentIds) != 0 {
+			talentNodes = append(talentNodes, node)
+		}
+	}
+	return IngameTree{Nodes: talentNodes}, nil
+}
+
+// readTraitTable reads the given integer columns from every row of a CSV table.
+func readTraitTable(reader io.Reader, columns ...string) ([]map[string]int, error) {
+	if reader == nil {
+		return nil, fmt.Errorf("table is missing")
+	}
+
+	csvReader := csv.NewReader(reader)
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	columnIndices := make(map[string]int, len(columns))
+	for _, column := range columns {
+		columnIndices[column] = -1
+	}
+	for i, name := range header {
+		if _, ok := columnIndices[name]; ok {
+			columnIndices[name] = i
+		}
+	}
+	for column, index := range columnIndices {
+		if index == -1 {
+			return nil, fmt.Errorf("missing column %s", column)
+		}
+	}
+
+	rows := make([]map[string]int, 0)
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]int, len(columns))
+		for column, index := range columnIndices {
+			value, err := strconv.Atoi(record[index])
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s on line %d: %w", column, len(rows)+2, err)
+			}
+			row[column] = value
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
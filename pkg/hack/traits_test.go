@@ -0,0 +1,93 @@
+package hack
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const traitNodesCsv = `ID,TraitTreeID,PosX,PosY,Type,Flags,TraitSubTreeID
+1,100,600,600,0,0,0
+2,100,600,1200,2,0,0
+3,100,1200,1200,0,0,0
+4,100,1800,1200,0,0,0
+5,100,9000,600,0,0,50
+6,200,600,600,0,0,0
+`
+
+const traitEdgesCsv = `ID,VisualStyle,LeftTraitNodeID,RightTraitNodeID,Type
+1,1,1,2,2
+2,1,1,3,2
+3,1,5,4,2
+4,1,6,1,2
+5,1,2,3,0
+6,1,3,2,1
+`
+
+const traitNodeEntriesCsv = `ID,TraitNodeID,TraitNodeEntryID,_Index
+1,1,10,0
+2,2,21,1
+3,2,20,0
+4,3,30,0
+5,5,50,0
+6,6,60,0
+`
+
+func TestIngameTreeFromTraitTables(t *testing.T) {
+	tree, err := IngameTreeFromTraitTables(TraitTables{
+		Nodes:       strings.NewReader(traitNodesCsv),
+		Edges:       strings.NewReader(traitEdgesCsv),
+		NodeEntries: strings.NewReader(traitNodeEntriesCsv),
+	}, 100)
+	if err != nil {
+		t.Fatalf("failed to convert trait tables: %v", err)
+	}
+
+	// Node 4 has no entries, node 5 is a hero node, and node 6 is another tree.
+	// Edges between nodes 2 and 3 aren't prerequisites.
+	if len(tree.Nodes) != 3 {
+		t.Fatalf("expected 3 nodes, got %d", len(tree.Nodes))
+	}
+
+	expected := []IngameNode{
+		{Id: 1, PosX: 600, PosY: 600, LockedBy: []int{}, TalentIds: []int{10}},
+		{Id: 2, PosX: 600, PosY: 1200, LockedBy: []int{1}, Choice: true, TalentIds: []int{20, 21}},
+		{Id: 3, PosX: 1200, PosY: 1200, LockedBy: []int{1}, TalentIds: []int{30}},
+	}
+	for i, node := range tree.Nodes {
+		if node.Id != expected[i].Id || node.PosX != expected[i].PosX || node.PosY != expected[i].PosY {
+			t.Errorf("expected node %d at %d,%d, got node %d at %d,%d", expected[i].Id, expected[i].PosX, expected[i].PosY, node.Id, node.PosX, node.PosY)
+		}
+		if node.Choice != expected[i].Choice {
+			t.Errorf("expected node %d choice to be %t, got %t", node.Id, expected[i].Choice, node.Choice)
+		}
+		if !slices.Equal(node.LockedBy, expected[i].LockedBy) {
+			t.Errorf("expected node %d to be locked by %v, got %v", node.Id, expected[i].LockedBy, node.LockedBy)
+		}
+		if !slices.Equal(node.TalentIds, expected[i].TalentIds) {
+			t.Errorf("expected node %d to have talents %v, got %v", node.Id, expected[i].TalentIds, node.TalentIds)
+		}
+	}
+}
+
+func TestIngameTreeFromTraitTablesMissingColumn(t *testing.T) {
+	_, err := IngameTreeFromTraitTables(TraitTables{
+		Nodes:       strings.NewReader("ID,TraitTreeID\n1,100\n"),
+		Edges:       strings.NewReader(traitEdgesCsv),
+		NodeEntries: strings.NewReader(traitNodeEntriesCsv),
+	}, 100)
+	if err == nil || !strings.Contains(err.Error(), "missing column") {
+		t.Fatalf("expected missing column error, got %v", err)
+	}
+}
+
+func TestIngameTreeFromTraitTablesUnknownTree(t *testing.T) {
+	_, err := IngameTreeFromTraitTables(TraitTables{
+		Nodes:       strings.NewReader(traitNodesCsv),
+		Edges:       strings.NewReader(traitEdgesCsv),
+		NodeEntries: strings.NewReader(traitNodeEntriesCsv),
+	}, 300)
+	if err == nil {
+		t.Fatalf("expected error for unknown trait tree")
+	}
+}
@@ -0,0 +1,345 @@
+// Package legality checks whether loadouts could actually be selected in game.
+//
+// Loadouts reported by the API occasionally include talents from a stale
+// tree, or are missing nodes, so any loadout which fails these checks
+// shouldn't be trusted for popularity statistics.
+package legality
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+type ViolationKind string
+
+const (
+	// KindUnknownNode is a node which isn't part of the tree section it was
+	// selected in.
+	KindUnknownNode ViolationKind = "unknown_node"
+	// KindUnknownTalent is a talent which isn't an option of its node.
+	KindUnknownTalent ViolationKind = "unknown_talent"
+	// KindDuplicateNode is a node selected more than once with the same talent.
+	KindDuplicateNode ViolationKind = "duplicate_node"
+	// KindChoice is a choice node with more than one of its options selected.
+	KindChoice ViolationKind = "choice"
+	// KindRank is a node selected beyond its max rank.
+	KindRank ViolationKind = "rank"
+	// KindLocked is a node selected without any of its prerequisites fully
+	// ranked.
+	KindLocked ViolationKind = "locked"
+	// KindGate is a node selected before enough points were spent above it.
+	KindGate ViolationKind = "gate"
+	// KindPoints is a section with more points spent than are available.
+	KindPoints ViolationKind = "points"
+	// KindHeroTree is a loadout with nodes from more than one hero tree.
+	KindHeroTree ViolationKind = "hero_tree"
+)
+
+type Section string
+
+const (
+	SectionClass Section = "class"
+	SectionSpec  Section = "spec"
+	SectionHero  Section = "hero"
+)
+
+// Violation is a single reason a loadout isn't legal.
+type Violation struct {
+	Kind    ViolationKind
+	Section Section
+	// NodeId is the offending node, or zero for violations of a whole section.
+	NodeId  int
+	Message string
+}
+
+// IllegalLoadoutError is returned by Check for loadouts with violations.
+type IllegalLoadoutError struct {
+	Violations []Violation
+}
+
+func (e *IllegalLoadoutError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return fmt.Sprintf("illegal loadout: %s", strings.Join(messages, "; "))
+}
+
+// PointLimits is the number of points available in each section of a tree.
+// Zero leaves a section's total unchecked.
+type PointLimits struct {
+	Class int
+	Spec  int
+	Hero  int
+}
+
+// CurrentPointLimits are the points available at max level in patch 12.0,
+// which are 34 class, 34 spec and 13 hero points, plus room for the nodes
+// each spec is granted for free in its class and hero trees.
+var CurrentPointLimits = PointLimits{
+	Class: 37,
+	Spec:  34,
+	Hero:  14,
+}
+
+type checkOptions struct {
+	PointLimits PointLimits
+}
+
+type Option interface {
+	apply(*checkOptions)
+}
+
+type pointLimitsOption PointLimits
+
+func (o pointLimitsOption) apply(options *checkOptions) {
+	options.PointLimits = PointLimits(o)
+}
+
+// WithPointLimits checks the points spent in each section against limits.
+// The tree doesn't record how many points a character has to spend, so
+// totals are only checked when limits are given, such as
+// CurrentPointLimits. Nodes granted for free can't be told apart from
+// purchased ones, so limits should include them.
+func WithPointLimits(limits PointLimits) Option {
+	return pointLimitsOption(limits)
+}
+
+// Check validates a loadout against its tree, returning an
+// *IllegalLoadoutError listing every violation found.
+func Check(loadout *wow.Loadout, tree *wow.TalentTree, opts ...Option) error {
+	options := checkOptions{}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+
+	violations := make([]Violation, 0)
+	violations = checkSection(violations, SectionClass, loadout.ClassNodes, tree.ClassNodes, tree.ClassGates, options.PointLimits.Class)
+	violations = checkSection(violations, SectionSpec, loadout.SpecNodes, tree.SpecNodes, tree.SpecGates, options.PointLimits.Spec)
+	violations = checkHeroNodes(violations, loadout.HeroNodes, tree, options.PointLimits.Hero)
+
+	if len(violations) != 0 {
+		return &IllegalLoadoutError{Violations: violations}
+	}
+	return nil
+}
+
+func checkSection(
+	violations []Violation,
+	section Section,
+	selected []wow.LoadoutNode,
+	nodes []wow.TalentNode,
+	gates []wow.TalentGate,
+	pointLimit int,
+) []Violation {
+	nodeMap := make(map[int]*wow.TalentNode, len(nodes))
+	for i := range nodes {
+		nodeMap[nodes[i].Id] = &nodes[i]
+	}
+
+	ranks := make(map[int]int, len(selected))
+	talentIds := make(map[int]int, len(selected))
+	selectedIds := make([]int, 0, len(selected))
+	points := 0
+	for _, loadoutNode := range selected {
+		// Unselected nodes are occasionally reported with no ranks.
+		if loadoutNode.Rank == 0 {
+			continue
+		}
+
+		node, ok := nodeMap[loadoutNode.NodeId]
+		if !ok {
+			violations = append(violations, Violation{
+				Kind:    KindUnknownNode,
+				Section: section,
+				NodeId:  loadoutNode.NodeId,
+				Message: fmt.Sprintf("node %d is not in the %s tree", loadoutNode.NodeId, section),
+			})
+			continue
+		}
+
+		if previousTalentId, ok := talentIds[node.Id]; ok {
+			if previousTalentId != loadoutNode.TalentId && len(node.Talents) > 1 {
+				violations = append(violations, Violation{
+					Kind:    KindChoice,
+					Section: section,
+					NodeId:  node.Id,
+					Message: fmt.Sprintf(
+						"talents %d and %d are both selected in choice node %d",
+						previousTalentId,
+						loadoutNode.TalentId,
+						node.Id,
+					),
+				})
+			} else {
+				violations = append(violations, Violation{
+					Kind:    KindDuplicateNode,
+					Section: section,
+					NodeId:  node.Id,
+					Message: fmt.Sprintf("node %d is selected more than once", node.Id),
+				})
+			}
+			continue
+		}
+
+		if !hasTalent(node, loadoutNode.TalentId) {
+			violations = append(violations, Violation{
+				Kind:    KindUnknownTalent,
+				Section: section,
+				NodeId:  node.Id,
+				Message: fmt.Sprintf("talent %d is not an option of node %d", loadoutNode.TalentId, node.Id),
+			})
+		}
+
+		if loadoutNode.Rank < 0 || loadoutNode.Rank > node.MaxRank {
+			violations = append(violations, Violation{
+				Kind:    KindRank,
+				Section: section,
+				NodeId:  node.Id,
+				Message: fmt.Sprintf("node %d has rank %d of %d", node.Id, loadoutNode.Rank, node.MaxRank),
+			})
+		}
+
+		ranks[node.Id] = loadoutNode.Rank
+		talentIds[node.Id] = loadoutNode.TalentId
+		selectedIds = append(selectedIds, node.Id)
+		points += loadoutNode.Rank
+	}
+
+	violations = checkPrerequisites(violations, section, selectedIds, ranks, nodeMap)
+	violations = checkGates(violations, section, ranks, nodeMap, gates)
+
+	if pointLimit > 0 && points > pointLimit {
+		violations = append(violations, Violation{
+			Kind:    KindPoints,
+			Section: section,
+			Message: fmt.Sprintf("%d points spent in the %s tree, %d available", points, section, pointLimit),
+		})
+	}
+	return violations
+}
+
+// checkPrerequisites ensures every selected node is reachable, meaning at least
+// one node which unlocks it is fully ranked. Prerequisites which aren't part of
+// the section are ignored, as they've been filtered from the tree.
+func checkPrerequisites(
+	violations []Violation,
+	section Section,
+	selectedIds []int,
+	ranks map[int]int,
+	nodeMap map[int]*wow.TalentNode,
+) []Violation {
+	for _, nodeId := range selectedIds {
+		node := nodeMap[nodeId]
+		hasPrerequisite := false
+		unlocked := false
+		for _, lockedById := range node.LockedBy {
+			parent, ok := nodeMap[lockedById]
+			if !ok {
+				continue
+			}
+			hasPrerequisite = true
+			if ranks[parent.Id] >= parent.MaxRank {
+				unlocked = true
+				break
+			}
+		}
+
+		if hasPrerequisite && !unlocked {
+			violations = append(violations, Violation{
+				Kind:    KindLocked,
+				Section: section,
+				NodeId:  node.Id,
+				Message: fmt.Sprintf("node %d is selected without a fully ranked prerequisite", node.Id),
+			})
+		}
+	}
+	return violations
+}
+
+// checkGates ensures enough points were spent above each gate before nodes
+// below it were selected. Nodes granted for free count toward the total, so
+// this is slightly more lenient than the game.
+func checkGates(violations []Violation, section Section, ranks map[int]int, nodeMap map[int]*wow.TalentNode, gates []wow.TalentGate) []Violation {
+	for _, gate := range gates {
+		spent := 0
+		passedGate := false
+		for nodeId, rank := range ranks {
+			if nodeMap[nodeId].Row < gate.Row {
+				spent += rank
+			} else {
+				passedGate = true
+			}
+		}
+
+		if passedGate && spent < gate.RequiredPoints {
+			violations = append(violations, Violation{
+				Kind:    KindGate,
+				Section: section,
+				Message: fmt.Sprintf(
+					"row %d of the %s tree is selected with %d of %d required points spent",
+					gate.Row,
+					section,
+					spent,
+					gate.RequiredPoints,
+				),
+			})
+		}
+	}
+	return violations
+}
+
+// checkHeroNodes ensures every hero node comes from a single hero tree, then
+// checks that tree like any other section.
+func checkHeroNodes(violations []Violation, selected []wow.LoadoutNode, tree *wow.TalentTree, pointLimit int) []Violation {
+	if len(selected) == 0 {
+		return violations
+	}
+
+	heroTreeIndices := make(map[int]int)
+	for treeIndex := range tree.HeroTrees {
+		for _, node := range tree.HeroTrees[treeIndex].Nodes {
+			heroTreeIndices[node.Id] = treeIndex
+		}
+	}
+
+	selectedTree := -1
+	for _, loadoutNode := range selected {
+		treeIndex, ok := heroTreeIndices[loadoutNode.NodeId]
+		if !ok {
+			continue
+		}
+		if selectedTree == -1 {
+			selectedTree = treeIndex
+			continue
+		}
+		if treeIndex != selectedTree {
+			return append(violations, Violation{
+				Kind:    KindHeroTree,
+				Section: SectionHero,
+				Message: fmt.Sprintf(
+					"nodes are selected from both %s and %s",
+					tree.HeroTrees[selectedTree].Name,
+					tree.HeroTrees[treeIndex].Name,
+				),
+			})
+		}
+	}
+
+	var nodes []wow.TalentNode
+	if selectedTree != -1 {
+		nodes = tree.HeroTrees[selectedTree].Nodes
+	}
+	return checkSection(violations, SectionHero, selected, nodes, nil, pointLimit)
+}
+
+func hasTalent(node *wow.TalentNode, talentId int) bool {
+	for _, talent := range node.Talents {
+		if talent.Id == talentId {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package legality
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/testutils"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+func mockNode(id int, row int, maxRank int, lockedBy []int, talentIds ...int) wow.TalentNode {
+	talents := make([]wow.Talent, len(talentIds))
+	for i, talentId := range talentIds {
+		talents[i] = wow.Talent{Id: talentId}
+	}
+	return wow.TalentNode{
+		Id:       id,
+		Row:      row,
+		MaxRank:  maxRank,
+		LockedBy: lockedBy,
+		Talents:  talents,
+	}
+}
+
+// mockTree is a single column of spec nodes with a gate at row 3, and two
+// hero trees of a root and one child each.
+func mockTree() *wow.TalentTree {
+	return &wow.TalentTree{
+		SpecNodes: []wow.TalentNode{
+			mockNode(1, 1, 2, nil, 10),
+			mockNode(2, 2, 1, []int{1}, 20, 21),
+			mockNode(3, 3, 1, []int{2}, 30),
+		},
+		SpecGates: []wow.TalentGate{{Row: 3, RequiredPoints: 3}},
+		HeroTrees: []wow.HeroTree{
+			{Name: "First", Nodes: []wow.TalentNode{
+				mockNode(100, 1, 1, nil, 1000),
+				mockNode(101, 2, 1, []int{100}, 1010),
+			}},
+			{Name: "Second", Nodes: []wow.TalentNode{
+				mockNode(200, 1, 1, nil, 2000),
+				mockNode(201, 2, 1, []int{200}, 2010),
+			}},
+		},
+	}
+}
+
+func mockLoadout() *wow.Loadout {
+	return &wow.Loadout{
+		SpecNodes: []wow.LoadoutNode{
+			{NodeId: 1, TalentId: 10, Rank: 2},
+			{NodeId: 2, TalentId: 21, Rank: 1},
+			{NodeId: 3, TalentId: 30, Rank: 1},
+		},
+		HeroNodes: []wow.LoadoutNode{
+			{NodeId: 100, TalentId: 1000, Rank: 1},
+			{NodeId: 101, TalentId: 1010, Rank: 1},
+		},
+	}
+}
+
+func expectViolation(t *testing.T, err error, kind ViolationKind) {
+	t.Helper()
+	var illegal *IllegalLoadoutError
+	if !errors.As(err, &illegal) {
+		t.Fatalf("expected IllegalLoadoutError, got %v", err)
+	}
+	for _, violation := range illegal.Violations {
+		if violation.Kind == kind {
+			return
+		}
+	}
+	t.Fatalf("expected %s violation, got %v", kind, illegal.Violations)
+}
+
+func TestCheckAcceptsLegalLoadout(t *testing.T) {
+	err := Check(mockLoadout(), mockTree(), WithPointLimits(PointLimits{Spec: 4, Hero: 2}))
+	if err != nil {
+		t.Fatalf("expected legal loadout, got %v", err)
+	}
+}
+
+func TestCheckRejectsUnknownNode(t *testing.T) {
+	loadout := mockLoadout()
+	loadout.ClassNodes = append(loadout.ClassNodes, wow.LoadoutNode{NodeId: 1, TalentId: 10, Rank: 1})
+	expectViolation(t, Check(loadout, mockTree()), KindUnknownNode)
+}
+
+func TestCheckRejectsUnknownTalent(t *testing.T) {
+	loadout := mockLoadout()
+	loadout.SpecNodes[1].TalentId = 30
+	expectViolation(t, Check(loadout, mockTree()), KindUnknownTalent)
+}
+
+func TestCheckRejectsBothChoices(t *testing.T) {
+	loadout := mockLoadout()
+	loadout.SpecNodes = append(loadout.SpecNodes, wow.LoadoutNode{NodeId: 2, TalentId: 20, Rank: 1})
+	expectViolation(t, Check(loadout, mockTree()), KindChoice)
+}
+
+func TestCheckRejectsDuplicateNode(t *testing.T) {
+	loadout := mockLoadout()
+	loadout.SpecNodes = append(loadout.SpecNodes, wow.LoadoutNode{NodeId: 1, TalentId: 10, Rank: 1})
+	expectViolation(t, Check(loadout, mockTree()), KindDuplicateNode)
+}
+
+func TestCheckRejectsExcessRank(t *testing.T) {
+	loadout := mockLoadout()
+	loadout.SpecNodes[2].Rank = 2
+	expectViolation(t, Check(loadout, mockTree()), KindRank)
+}
+
+func TestCheckRejectsLockedNode(t *testing.T) {
+	loadout := mockLoadout()
+	loadout.SpecNodes[0].Rank = 1
+	expectViolation(t, Check(loadout, mockTree()), KindLocked)
+}
+
+func TestCheckRejectsGatedNode(t *testing.T) {
+	tree := mockTree()
+	tree.SpecGates[0].RequiredPoints = 4
+	expectViolation(t, Check(mockLoadout(), tree), KindGate)
+}
+
+func TestCheckRejectsExcessPoints(t *testing.T) {
+	err := Check(mockLoadout(), mockTree(), WithPointLimits(PointLimits{Spec: 3}))
+	expectViolation(t, err, KindPoints)
+}
+
+func TestCheckRejectsMixedHeroTrees(t *testing.T) {
+	loadout := mockLoadout()
+	loadout.HeroNodes[1] = wow.LoadoutNode{NodeId: 201, TalentId: 2010, Rank: 1}
+	expectViolation(t, Check(loadout, mockTree()), KindHeroTree)
+}
+
+func TestCheckRejectsMissingHeroRoot(t *testing.T) {
+	loadout := mockLoadout()
+	loadout.HeroNodes = loadout.HeroNodes[1:]
+	expectViolation(t, Check(loadout, mockTree()), KindLocked)
+}
+
+func TestCheckGameLoadouts(t *testing.T) {
+	data, err := testutils.LoadDruidGameData()
+	if err != nil {
+		t.Fatalf("failed to load game data: %v", err)
+	}
+	if len(data.Loadouts) == 0 {
+		t.Fatalf("expected saved loadouts, got none")
+	}
+
+	// The points available at max level in 11.0.2, including granted nodes.
+	limits := PointLimits{Class: 33, Spec: 30, Hero: 11}
+	for _, loadout := range data.Loadouts {
+		tree := data.Tree(loadout.SpecName)
+		if tree == nil {
+			t.Fatalf("no tree for %s", loadout.SpecName)
+		}
+
+		err = Check(&loadout.Loadout, tree, WithPointLimits(limits))
+		if err != nil {
+			t.Errorf("expected %s to be legal, got %v", loadout.Code, err)
+		}
+	}
+
+	// The first loadout spends every class point, including granted nodes.
+	loadout := data.Loadouts[0]
+	limits.Class--
+	expectViolation(t, Check(&loadout.Loadout, data.Tree(loadout.SpecName), WithPointLimits(limits)), KindPoints)
+}
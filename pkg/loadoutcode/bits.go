@@ -0,0 +1,72 @@
+package loadoutcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+const bitsPerChar = 6
+
+var errEndOfCode = errors.New("unexpected end of loadout code")
+
+// bitReader reads values from a loadout code. Each character holds 6 bits,
+// which are consumed least significant bit first.
+type bitReader struct {
+	values []byte
+	offset int
+}
+
+func newBitReader(code string) (*bitReader, error) {
+	values := make([]byte, len(code))
+	for i := range code {
+		value := strings.IndexByte(alphabet, code[i])
+		if value < 0 {
+			return nil, fmt.Errorf("invalid character in loadout code: %q", code[i])
+		}
+		values[i] = byte(value)
+	}
+	return &bitReader{values: values}, nil
+}
+
+func (r *bitReader) read(width int) (int, error) {
+	if r.offset+width > len(r.values)*bitsPerChar {
+		return 0, errEndOfCode
+	}
+
+	value := 0
+	for i := 0; i < width; i++ {
+		char := r.values[r.offset/bitsPerChar]
+		bit := (char >> (r.offset % bitsPerChar)) & 1
+		value |= int(bit) << i
+		r.offset++
+	}
+	return value, nil
+}
+
+type bitWriter struct {
+	values []byte
+	offset int
+}
+
+func (w *bitWriter) write(value int, width int) {
+	for i := 0; i < width; i++ {
+		if w.offset%bitsPerChar == 0 {
+			w.values = append(w.values, 0)
+		}
+		bit := byte((value >> i) & 1)
+		w.values[len(w.values)-1] |= bit << (w.offset % bitsPerChar)
+		w.offset++
+	}
+}
+
+func (w *bitWriter) String() string {
+	var builder strings.Builder
+	builder.Grow(len(w.values))
+	for _, value := range w.values {
+		builder.WriteByte(alphabet[value])
+	}
+	return builder.String()
+}
@@ -0,0 +1,394 @@
+// Package loadoutcode reads and writes the talent loadout strings used by the
+// in-game import and export dialogs.
+//
+// A loadout code is a header, followed by a few bits for every node in the
+// class's talent tree in ascending node id order. The in-game tree includes
+// nodes for every spec of the class, so decoding codes exported from the game
+// requires the full node order, supplied with WithNodeOrder. Without it, only
+// the nodes of the given wow.TalentTree are walked.
+package loadoutcode
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+// SerializationVersion is the loadout code format version written by Encode.
+const SerializationVersion = 2
+
+const (
+	versionBits      = 8
+	specIdBits       = 16
+	treeHashBits     = 128
+	ranksBits        = 6
+	choiceIndexBits  = 2
+	maxChoiceOptions = 1 << choiceIndexBits
+)
+
+var ErrSpecMismatch = errors.New("loadout code is for a different spec")
+
+type codeOptions struct {
+	NodeOrder []int
+}
+
+type Option interface {
+	apply(*codeOptions)
+}
+
+type nodeOrderOption []int
+
+func (o nodeOrderOption) apply(options *codeOptions) {
+	options.NodeOrder = []int(o)
+}
+
+// WithNodeOrder sets the node ids walked by the code, which must be every node
+// in the class's in-game talent tree. Nodes which aren't part of the
+// wow.TalentTree are read but ignored when decoding, and left unselected when
+// encoding.
+func WithNodeOrder(nodeIds []int) Option {
+	ids := slices.Clone(nodeIds)
+	slices.Sort(ids)
+	return nodeOrderOption(ids)
+}
+
+type nodeSection int
+
+const (
+	sectionClass nodeSection = iota
+	sectionSpec
+	sectionHero
+)
+
+type treeNode struct {
+	Node    *wow.TalentNode
+	Section nodeSection
+}
+
+// Decode reads a loadout code into a loadout for the given tree.
+func Decode(code string, tree *wow.TalentTree, opts ...Option) (wow.Loadout, error) {
+	nodes, options := prepare(tree, opts)
+
+	reader, err := newBitReader(code)
+	if err != nil {
+		return wow.Loadout{}, err
+	}
+
+	version, err := reader.read(versionBits)
+	if err != nil {
+		return wow.Loadout{}, err
+	}
+	if version < 1 || version > SerializationVersion {
+		return wow.Loadout{}, fmt.Errorf("unsupported loadout code version: %d", version)
+	}
+
+	specId, err := reader.read(specIdBits)
+	if err != nil {
+		return wow.Loadout{}, err
+	}
+	if specId != tree.SpecId {
+		return wow.Loadout{}, fmt.Errorf("%w: expected %d, got %d", ErrSpecMismatch, tree.SpecId, specId)
+	}
+
+	// The tree hash is only used by the game to detect outdated codes.
+	for i := 0; i < treeHashBits/8; i++ {
+		_, err = reader.read(8)
+		if err != nil {
+			return wow.Loadout{}, err
+		}
+	}
+
+	loadout := wow.Loadout{
+		ClassName:  tree.ClassName,
+		SpecName:   tree.SpecName,
+		ClassNodes: make([]wow.LoadoutNode, 0),
+		SpecNodes:  make([]wow.LoadoutNode, 0),
+		HeroNodes:  make([]wow.LoadoutNode, 0),
+		Code:       code,
+	}
+	for _, nodeId := range options.NodeOrder {
+		selection, err := readNode(reader, version)
+		if err != nil {
+			return wow.Loadout{}, fmt.Errorf("failed to read node %d: %w", nodeId, err)
+		}
+		if !selection.Selected {
+			continue
+		}
+
+		entry, ok := nodes[nodeId]
+		if !ok {
+			continue
+		}
+
+		loadoutNode, err := selection.toLoadoutNode(entry.Node)
+		if err != nil {
+			return wow.Loadout{}, err
+		}
+		switch entry.Section {
+		case sectionClass:
+			loadout.ClassNodes = append(loadout.ClassNodes, loadoutNode)
+		case sectionSpec:
+			loadout.SpecNodes = append(loadout.SpecNodes, loadoutNode)
+		case sectionHero:
+			loadout.HeroNodes = append(loadout.HeroNodes, loadoutNode)
+		}
+	}
+	return loadout, nil
+}
+
+// Encode writes a loadout as a code which can be imported in game.
+// The tree doesn't record which nodes the game grants for free, so every
+// selected node is written as purchased. The tree's hero selection node is
+// written from the hero tree of the loadout's hero nodes.
+func Encode(loadout *wow.Loadout, tree *wow.TalentTree, opts ...Option) (string, error) {
+	nodes, options := prepare(tree, opts)
+
+	selected := make(map[int]wow.LoadoutNode)
+	for _, section := range [][]wow.LoadoutNode{loadout.ClassNodes, loadout.SpecNodes, loadout.HeroNodes} {
+		for _, node := range section {
+			if _, ok := nodes[node.NodeId]; !ok {
+				return "", fmt.Errorf("node %d is not part of the %s %s tree", node.NodeId, tree.SpecName, tree.ClassName)
+			}
+			selected[node.NodeId] = node
+		}
+	}
+
+	writer := &bitWriter{}
+	writer.write(SerializationVersion, versionBits)
+	writer.write(tree.SpecId, specIdBits)
+	for i := 0; i < treeHashBits/8; i++ {
+		writer.write(0, 8)
+	}
+
+	heroChoice, err := heroTreeChoice(loadout, tree)
+	if err != nil {
+		return "", err
+	}
+
+	for _, nodeId := range options.NodeOrder {
+		if nodeId == tree.HeroSelectionNodeId && heroChoice >= 0 {
+			// Selected, purchased and fully ranked, with the hero tree as
+			// the choice.
+			writer.write(1, 1)
+			writer.write(1, 1)
+			writer.write(0, 1)
+			writer.write(1, 1)
+			writer.write(heroChoice, choiceIndexBits)
+			continue
+		}
+
+		loadoutNode, ok := selected[nodeId]
+		if !ok {
+			writer.write(0, 1)
+			continue
+		}
+
+		node := nodes[nodeId].Node
+		choiceIndex := slices.IndexFunc(node.Talents, func(talent wow.Talent) bool {
+			return talent.Id == loadoutNode.TalentId
+		})
+		if choiceIndex < 0 {
+			return "", fmt.Errorf("talent %d is not part of node %d", loadoutNode.TalentId, nodeId)
+		}
+		if choiceIndex >= maxChoiceOptions {
+			return "", fmt.Errorf("node %d has too many choices to encode", nodeId)
+		}
+
+		maxRank := nodeMaxRank(node)
+		if loadoutNode.Rank < 1 || loadoutNode.Rank >= 1<<ranksBits {
+			return "", fmt.Errorf("invalid rank %d for node %d", loadoutNode.Rank, nodeId)
+		}
+
+		// Selected and purchased.
+		writer.write(1, 1)
+		writer.write(1, 1)
+		if loadoutNode.Rank != maxRank {
+			writer.write(1, 1)
+			writer.write(loadoutNode.Rank, ranksBits)
+		} else {
+			writer.write(0, 1)
+		}
+		if len(node.Talents) > 1 {
+			writer.write(1, 1)
+			writer.write(choiceIndex, choiceIndexBits)
+		} else {
+			writer.write(0, 1)
+		}
+	}
+	return writer.String(), nil
+}
+
+// heroTreeChoice returns the choice index of the hero selection node for the
+// loadout's hero tree, or -1 if nothing should be written for it. The game
+// orders the choices by the hero trees' lowest node ids, rather than by their
+// ids.
+func heroTreeChoice(loadout *wow.Loadout, tree *wow.TalentTree) (int, error) {
+	if tree.HeroSelectionNodeId == 0 || len(loadout.HeroNodes) == 0 {
+		return -1, nil
+	}
+
+	heroTrees := make([]*wow.HeroTree, 0, len(tree.HeroTrees))
+	for i := range tree.HeroTrees {
+		if len(tree.HeroTrees[i].Nodes) > 0 {
+			heroTrees = append(heroTrees, &tree.HeroTrees[i])
+		}
+	}
+	slices.SortFunc(heroTrees, func(a, b *wow.HeroTree) int {
+		return cmp.Compare(lowestNodeId(a), lowestNodeId(b))
+	})
+
+	choice := -1
+	for _, loadoutNode := range loadout.HeroNodes {
+		index := slices.IndexFunc(heroTrees, func(heroTree *wow.HeroTree) bool {
+			return slices.ContainsFunc(heroTree.Nodes, func(node wow.TalentNode) bool {
+				return node.Id == loadoutNode.NodeId
+			})
+		})
+		if choice >= 0 && index != choice {
+			return -1, errors.New("hero nodes of the loadout span more than one hero tree")
+		}
+		choice = index
+	}
+	if choice >= maxChoiceOptions {
+		return -1, fmt.Errorf("node %d has too many choices to encode", tree.HeroSelectionNodeId)
+	}
+	return choice, nil
+}
+
+func lowestNodeId(heroTree *wow.HeroTree) int {
+	lowest := heroTree.Nodes[0].Id
+	for _, node := range heroTree.Nodes[1:] {
+		lowest = min(lowest, node.Id)
+	}
+	return lowest
+}
+
+// NodeOrder returns the ids of every node in the trees, including their hero
+// selection nodes, sorted in the order walked by loadout codes. Passing the
+// trees of every spec of a class gives a close approximation of the in-game
+// node order.
+func NodeOrder(trees ...*wow.TalentTree) []int {
+	seen := make(map[int]bool)
+	ids := make([]int, 0)
+	for _, tree := range trees {
+		if tree.HeroSelectionNodeId != 0 && !seen[tree.HeroSelectionNodeId] {
+			seen[tree.HeroSelectionNodeId] = true
+			ids = append(ids, tree.HeroSelectionNodeId)
+		}
+		forEachNode(tree, func(node *wow.TalentNode, _ nodeSection) {
+			if !seen[node.Id] {
+				seen[node.Id] = true
+				ids = append(ids, node.Id)
+			}
+		})
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+type nodeSelection struct {
+	Selected bool
+	// Ranks is the number of ranks purchased, or zero if fully ranked.
+	Ranks       int
+	ChoiceIndex int
+}
+
+func readNode(reader *bitReader, version int) (nodeSelection, error) {
+	selection := nodeSelection{}
+	selected, err := reader.read(1)
+	if err != nil || selected == 0 {
+		return selection, err
+	}
+	selection.Selected = true
+
+	// Version 1 codes didn't distinguish granted nodes from purchased ones.
+	// Granted nodes carry no further data, and are treated as fully ranked.
+	if version >= 2 {
+		purchased, err := reader.read(1)
+		if err != nil || purchased == 0 {
+			return selection, err
+		}
+	}
+
+	partial, err := reader.read(1)
+	if err != nil {
+		return selection, err
+	}
+	if partial == 1 {
+		selection.Ranks, err = reader.read(ranksBits)
+		if err != nil {
+			return selection, err
+		}
+	}
+
+	choice, err := reader.read(1)
+	if err != nil {
+		return selection, err
+	}
+	if choice == 1 {
+		selection.ChoiceIndex, err = reader.read(choiceIndexBits)
+		if err != nil {
+			return selection, err
+		}
+	}
+	return selection, nil
+}
+
+func (s *nodeSelection) toLoadoutNode(node *wow.TalentNode) (wow.LoadoutNode, error) {
+	if s.ChoiceIndex >= len(node.Talents) {
+		return wow.LoadoutNode{}, fmt.Errorf("choice %d is out of range for node %d", s.ChoiceIndex, node.Id)
+	}
+	talent := &node.Talents[s.ChoiceIndex]
+
+	rank := s.Ranks
+	if rank == 0 {
+		rank = nodeMaxRank(node)
+	}
+	return wow.LoadoutNode{
+		TalentName: talent.Name,
+		NodeId:     node.Id,
+		TalentId:   talent.Id,
+		Rank:       rank,
+	}, nil
+}
+
+func nodeMaxRank(node *wow.TalentNode) int {
+	if node.MaxRank > 0 {
+		return node.MaxRank
+	}
+	return 1
+}
+
+func prepare(tree *wow.TalentTree, opts []Option) (map[int]treeNode, *codeOptions) {
+	nodes := make(map[int]treeNode)
+	forEachNode(tree, func(node *wow.TalentNode, section nodeSection) {
+		nodes[node.Id] = treeNode{Node: node, Section: section}
+	})
+
+	options := &codeOptions{}
+	for _, opt := range opts {
+		opt.apply(options)
+	}
+	if options.NodeOrder == nil {
+		options.NodeOrder = NodeOrder(tree)
+	}
+	return nodes, options
+}
+
+func forEachNode(tree *wow.TalentTree, callback func(node *wow.TalentNode, section nodeSection)) {
+	for i := range tree.ClassNodes {
+		callback(&tree.ClassNodes[i], sectionClass)
+	}
+	for i := range tree.SpecNodes {
+		callback(&tree.SpecNodes[i], sectionSpec)
+	}
+	for i := range tree.HeroTrees {
+		heroTree := &tree.HeroTrees[i]
+		for j := range heroTree.Nodes {
+			callback(&heroTree.Nodes[j], sectionHero)
+		}
+	}
+}
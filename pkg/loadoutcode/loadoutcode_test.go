@@ -0,0 +1,270 @@
+package loadoutcode
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/testutils"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockNode(id int, maxRank int, talentIds ...int) wow.TalentNode {
+	talents := make([]wow.Talent, len(talentIds))
+	for i, talentId := range talentIds {
+		talents[i] = wow.Talent{Id: talentId, Name: "Talent"}
+	}
+	return wow.TalentNode{
+		Id:      id,
+		MaxRank: maxRank,
+		Talents: talents,
+	}
+}
+
+func mockTree() *wow.TalentTree {
+	return &wow.TalentTree{
+		ClassName: "Druid",
+		SpecName:  "Balance",
+		SpecId:    102,
+		ClassNodes: []wow.TalentNode{
+			mockNode(100, 1, 1000),
+			mockNode(102, 2, 1020),
+			mockNode(104, 1, 1040, 1041),
+		},
+		SpecNodes: []wow.TalentNode{
+			mockNode(101, 1, 1010),
+			mockNode(103, 3, 1030),
+			mockNode(105, 1, 1050, 1051, 1052),
+		},
+		HeroTrees: []wow.HeroTree{
+			{Id: 2, Nodes: []wow.TalentNode{mockNode(200, 1, 2000), mockNode(201, 1, 2010, 2011)}},
+			{Id: 1, Nodes: []wow.TalentNode{mockNode(300, 1, 3000)}},
+		},
+		HeroSelectionNodeId: 150,
+	}
+}
+
+func mockLoadout() *wow.Loadout {
+	return &wow.Loadout{
+		ClassName: "Druid",
+		SpecName:  "Balance",
+		ClassNodes: []wow.LoadoutNode{
+			{TalentName: "Talent", NodeId: 100, TalentId: 1000, Rank: 1},
+			{TalentName: "Talent", NodeId: 102, TalentId: 1020, Rank: 1},
+			{TalentName: "Talent", NodeId: 104, TalentId: 1041, Rank: 1},
+		},
+		SpecNodes: []wow.LoadoutNode{
+			{TalentName: "Talent", NodeId: 103, TalentId: 1030, Rank: 3},
+			{TalentName: "Talent", NodeId: 105, TalentId: 1052, Rank: 1},
+		},
+		HeroNodes: []wow.LoadoutNode{
+			{TalentName: "Talent", NodeId: 200, TalentId: 2000, Rank: 1},
+			{TalentName: "Talent", NodeId: 201, TalentId: 2011, Rank: 1},
+		},
+	}
+}
+
+func TestEncodeWritesHeader(t *testing.T) {
+	code, err := Encode(mockLoadout(), mockTree())
+	require.NoError(t, err)
+
+	// Every version 2 Balance code starts with the same characters in game.
+	assert.True(t, strings.HasPrefix(code, "CYG"), "unexpected header in %s", code)
+}
+
+func TestRoundTrip(t *testing.T) {
+	tree := mockTree()
+	loadout := mockLoadout()
+
+	code, err := Encode(loadout, tree)
+	require.NoError(t, err)
+
+	decoded, err := Decode(code, tree)
+	require.NoError(t, err)
+
+	assert.Equal(t, loadout.ClassNodes, decoded.ClassNodes)
+	assert.Equal(t, loadout.SpecNodes, decoded.SpecNodes)
+	assert.Equal(t, loadout.HeroNodes, decoded.HeroNodes)
+	assert.Equal(t, code, decoded.Code)
+	assert.Equal(t, "Balance", decoded.SpecName)
+}
+
+func TestRoundTripWithNodeOrder(t *testing.T) {
+	tree := mockTree()
+	loadout := mockLoadout()
+
+	// Nodes from other specs are interleaved with the tree's own nodes.
+	order := WithNodeOrder(append(NodeOrder(tree), 99, 150, 250, 400))
+
+	code, err := Encode(loadout, tree, order)
+	require.NoError(t, err)
+
+	decoded, err := Decode(code, tree, order)
+	require.NoError(t, err)
+	assert.Equal(t, loadout.SpecNodes, decoded.SpecNodes)
+	assert.Equal(t, loadout.HeroNodes, decoded.HeroNodes)
+
+	// Decoding without the full node order misaligns every node after the first foreign one.
+	decoded, err = Decode(code, tree)
+	if err == nil {
+		assert.NotEqual(t, loadout.SpecNodes, decoded.SpecNodes)
+	}
+}
+
+func TestDecodeFailsOnSpecMismatch(t *testing.T) {
+	code, err := Encode(mockLoadout(), mockTree())
+	require.NoError(t, err)
+
+	tree := mockTree()
+	tree.SpecId = 105
+	_, err = Decode(code, tree)
+	assert.True(t, errors.Is(err, ErrSpecMismatch), "expected ErrSpecMismatch, got %v", err)
+}
+
+func TestDecodeFailsOnInvalidCode(t *testing.T) {
+	_, err := Decode("C!G", mockTree())
+	assert.Error(t, err)
+
+	_, err = Decode("CYG", mockTree())
+	assert.ErrorIs(t, err, errEndOfCode)
+}
+
+func TestEncodeFailsOnUnknownNode(t *testing.T) {
+	loadout := mockLoadout()
+	loadout.SpecNodes = append(loadout.SpecNodes, wow.LoadoutNode{NodeId: 999, TalentId: 9990, Rank: 1})
+
+	_, err := Encode(loadout, mockTree())
+	assert.Error(t, err)
+}
+
+func TestEncodeWritesHeroSelectionNode(t *testing.T) {
+	tree := mockTree()
+	loadout := mockLoadout()
+
+	code, err := Encode(loadout, tree)
+	require.NoError(t, err)
+	selections := readSelections(t, code, NodeOrder(tree))
+	assert.Equal(t, nodeSelection{Selected: true, ChoiceIndex: 0}, selections[150])
+
+	// Hero trees are ordered by their lowest node id, not by their id.
+	loadout.HeroNodes = []wow.LoadoutNode{{TalentName: "Talent", NodeId: 300, TalentId: 3000, Rank: 1}}
+	code, err = Encode(loadout, tree)
+	require.NoError(t, err)
+	selections = readSelections(t, code, NodeOrder(tree))
+	assert.Equal(t, nodeSelection{Selected: true, ChoiceIndex: 1}, selections[150])
+
+	decoded, err := Decode(code, tree)
+	require.NoError(t, err)
+	assert.Equal(t, loadout.SpecNodes, decoded.SpecNodes)
+	assert.Equal(t, loadout.HeroNodes, decoded.HeroNodes)
+
+	loadout.HeroNodes = nil
+	code, err = Encode(loadout, tree)
+	require.NoError(t, err)
+	selections = readSelections(t, code, NodeOrder(tree))
+	assert.False(t, selections[150].Selected)
+}
+
+func TestEncodeFailsOnMixedHeroTrees(t *testing.T) {
+	loadout := mockLoadout()
+	loadout.HeroNodes = append(loadout.HeroNodes, wow.LoadoutNode{NodeId: 300, TalentId: 3000, Rank: 1})
+
+	_, err := Encode(loadout, mockTree())
+	assert.Error(t, err)
+}
+
+// readSelections reads the raw selection of every node in a code.
+func readSelections(t *testing.T, code string, nodeOrder []int) map[int]nodeSelection {
+	t.Helper()
+	reader, err := newBitReader(code)
+	require.NoError(t, err)
+
+	version, err := reader.read(versionBits)
+	require.NoError(t, err)
+	_, err = reader.read(specIdBits)
+	require.NoError(t, err)
+	for i := 0; i < treeHashBits/8; i++ {
+		_, err = reader.read(8)
+		require.NoError(t, err)
+	}
+
+	selections := make(map[int]nodeSelection)
+	for _, nodeId := range nodeOrder {
+		selection, err := readNode(reader, version)
+		if errors.Is(err, errEndOfCode) {
+			// The game trims unselected nodes from the end of the code.
+			break
+		}
+		require.NoError(t, err)
+		selections[nodeId] = selection
+	}
+	return selections
+}
+
+type selectedNode struct {
+	NodeId   int
+	TalentId int
+	Rank     int
+}
+
+func selectedNodes(nodes []wow.LoadoutNode) []selectedNode {
+	selected := make([]selectedNode, len(nodes))
+	for i, node := range nodes {
+		selected[i] = selectedNode{node.NodeId, node.TalentId, node.Rank}
+	}
+	return selected
+}
+
+// expectedNodes returns the nodes of a saved loadout which should be decoded
+// from its code. Most codes leave out granted nodes.
+func expectedNodes(expected []wow.LoadoutNode, decoded []wow.LoadoutNode, granted []int) []selectedNode {
+	nodes := make([]wow.LoadoutNode, 0, len(expected))
+	for _, node := range expected {
+		isDecoded := slices.ContainsFunc(decoded, func(decodedNode wow.LoadoutNode) bool {
+			return decodedNode.NodeId == node.NodeId
+		})
+		if isDecoded || !slices.Contains(granted, node.NodeId) {
+			nodes = append(nodes, node)
+		}
+	}
+	return selectedNodes(nodes)
+}
+
+func TestDecodeGameLoadouts(t *testing.T) {
+	data, err := testutils.LoadDruidGameData()
+	require.NoError(t, err)
+	require.NotEmpty(t, data.Loadouts)
+
+	order := WithNodeOrder(data.NodeOrder)
+	heroLoadouts := 0
+	for _, expected := range data.Loadouts {
+		tree := data.Tree(expected.SpecName)
+		require.NotNil(t, tree, "no tree for %s", expected.SpecName)
+
+		loadout, err := Decode(expected.Code, tree, order)
+		require.NoError(t, err, "failed to decode %s", expected.Code)
+		granted := expected.GrantedNodeIds
+		assert.ElementsMatch(t, expectedNodes(expected.ClassNodes, loadout.ClassNodes, granted), selectedNodes(loadout.ClassNodes), "class nodes of %s", expected.Code)
+		assert.ElementsMatch(t, expectedNodes(expected.SpecNodes, loadout.SpecNodes, granted), selectedNodes(loadout.SpecNodes), "spec nodes of %s", expected.Code)
+		assert.ElementsMatch(t, expectedNodes(expected.HeroNodes, loadout.HeroNodes, granted), selectedNodes(loadout.HeroNodes), "hero nodes of %s", expected.Code)
+
+		selection := readSelections(t, expected.Code, data.NodeOrder)[tree.HeroSelectionNodeId]
+		assert.Equal(t, expected.HeroTreeId != 0, selection.Selected, "hero selection of %s", expected.Code)
+		if expected.HeroTreeId == 0 {
+			continue
+		}
+		heroLoadouts++
+
+		choice, err := heroTreeChoice(&expected.Loadout, tree)
+		require.NoError(t, err)
+		assert.Equal(t, selection.ChoiceIndex, choice, "hero selection of %s", expected.Code)
+
+		code, err := Encode(&loadout, tree, order)
+		require.NoError(t, err)
+		assert.Equal(t, selection, readSelections(t, code, data.NodeOrder)[tree.HeroSelectionNodeId])
+	}
+	assert.NotZero(t, heroLoadouts)
+}
@@ -0,0 +1,183 @@
+package classes
+
+import (
+	_ "embed"
+	"fmt"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+	"github.com/crbednarz/moonkinmetrics/pkg/scan"
+	"github.com/crbednarz/moonkinmetrics/pkg/validate"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+//go:embed schema/class-index.schema.json
+var classIndexSchema string
+
+//go:embed schema/spec-index.schema.json
+var specIndexSchema string
+
+//go:embed schema/spec.schema.json
+var specSchema string
+
+type linkJson struct {
+	Key struct {
+		Href string `json:"href"`
+	} `json:"key"`
+	Name string `json:"name"`
+	Id   int    `json:"id"`
+}
+
+type classIndexJson struct {
+	Classes []linkJson `json:"classes"`
+}
+
+type specIndexJson struct {
+	CharacterSpecializations []linkJson `json:"character_specializations"`
+}
+
+type specJson struct {
+	PlayableClass struct {
+		Name string `json:"name"`
+		Id   int    `json:"id"`
+	} `json:"playable_class"`
+	Role struct {
+		Type string `json:"type"`
+	} `json:"role"`
+	Name string `json:"name"`
+	Id   int    `json:"id"`
+}
+
+// GetClassRegistry builds a registry of every playable class and
+// specialization from the playable class and specialization indexes.
+func GetClassRegistry(scanner *scan.Scanner) (*wow.ClassRegistry, error) {
+	classIndex, err := getClassIndex(scanner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playable class index: %w", err)
+	}
+
+	specIndex, err := getSpecIndex(scanner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playable specialization index: %w", err)
+	}
+
+	specs, err := getSpecs(scanner, specIndex.CharacterSpecializations)
+	if err != nil {
+		return nil, err
+	}
+
+	classes := make([]wow.Class, len(classIndex.Classes))
+	classIndexById := make(map[int]int, len(classIndex.Classes))
+	for i, classJson := range classIndex.Classes {
+		classes[i] = wow.Class{
+			Name: classJson.Name,
+			Slug: wow.Slugify(classJson.Name),
+			Id:   classJson.Id,
+		}
+		classIndexById[classJson.Id] = i
+	}
+
+	for _, spec := range specs {
+		classIndex, ok := classIndexById[spec.PlayableClass.Id]
+		if !ok {
+			return nil, fmt.Errorf("specialization %s (%d) belongs to unknown class %d", spec.Name, spec.Id, spec.PlayableClass.Id)
+		}
+		class := &classes[classIndex]
+		class.Specs = append(class.Specs, wow.Spec{
+			Name:      spec.Name,
+			Slug:      wow.Slugify(spec.Name),
+			ClassName: class.Name,
+			Role:      spec.Role.Type,
+			Id:        spec.Id,
+			ClassId:   class.Id,
+		})
+	}
+
+	return wow.NewClassRegistry(classes), nil
+}
+
+func getClassIndex(scanner *scan.Scanner) (*classIndexJson, error) {
+	validator, err := validate.NewSchemaValidator[classIndexJson](classIndexSchema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup playable class index validator: %w", err)
+	}
+
+	result := scan.ScanSingle(
+		scanner,
+		&api.BnetRequest{
+			Region:    api.RegionUS,
+			Namespace: api.NamespaceStatic,
+			Path:      "/data/wow/playable-class/index",
+		},
+		&scan.ScanOptions[classIndexJson]{
+			Validator: validator,
+			Lifespan:  time.Hour * 18,
+		},
+	)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &result.Response, nil
+}
+
+func getSpecIndex(scanner *scan.Scanner) (*specIndexJson, error) {
+	validator, err := validate.NewSchemaValidator[specIndexJson](specIndexSchema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup playable specialization index validator: %w", err)
+	}
+
+	result := scan.ScanSingle(
+		scanner,
+		&api.BnetRequest{
+			Region:    api.RegionUS,
+			Namespace: api.NamespaceStatic,
+			Path:      "/data/wow/playable-specialization/index",
+		},
+		&scan.ScanOptions[specIndexJson]{
+			Validator: validator,
+			Lifespan:  time.Hour * 18,
+		},
+	)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &result.Response, nil
+}
+
+func getSpecs(scanner *scan.Scanner, links []linkJson) ([]specJson, error) {
+	validator, err := validate.NewSchemaValidator[specJson](specSchema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup playable specialization validator: %w", err)
+	}
+
+	requests := make(chan api.Request, len(links))
+	results := make(chan scan.ScanResult[specJson], len(links))
+	options := scan.ScanOptions[specJson]{
+		Validator: validator,
+		Lifespan:  time.Hour * 18,
+	}
+
+	scan.Scan(scanner, requests, results, &options)
+	for _, link := range links {
+		requests <- &api.BnetRequest{
+			Region:    api.RegionUS,
+			Namespace: api.NamespaceStatic,
+			Path:      fmt.Sprintf("/data/wow/playable-specialization/%d", link.Id),
+		}
+	}
+	close(requests)
+
+	specs := make([]specJson, len(links))
+	var resultErr error
+	for result := range results {
+		if result.Error != nil {
+			resultErr = fmt.Errorf("failed to retrieve playable specialization [%v]: %w", result.ApiRequest.Id(), result.Error)
+			continue
+		}
+		specs[result.Index] = result.Response
+	}
+	if resultErr != nil {
+		return nil, resultErr
+	}
+	return specs, nil
+}
@@ -0,0 +1,116 @@
+package classes
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/testutils"
+)
+
+var (
+	//go:embed testdata/valid-class-index.json
+	validClassIndex string
+
+	//go:embed testdata/valid-spec-index.json
+	validSpecIndex string
+)
+
+var mockSpecs = map[string]string{
+	"102":  specJsonFromDetails(102, "Balance", "DAMAGE", 11, "Druid"),
+	"103":  specJsonFromDetails(103, "Feral", "DAMAGE", 11, "Druid"),
+	"104":  specJsonFromDetails(104, "Guardian", "TANK", 11, "Druid"),
+	"105":  specJsonFromDetails(105, "Restoration", "HEALER", 11, "Druid"),
+	"577":  specJsonFromDetails(577, "Havoc", "DAMAGE", 12, "Demon Hunter"),
+	"581":  specJsonFromDetails(581, "Vengeance", "TANK", 12, "Demon Hunter"),
+	"1480": specJsonFromDetails(1480, "Devourer", "DAMAGE", 12, "Demon Hunter"),
+}
+
+func specJsonFromDetails(id int, name string, role string, classId int, className string) string {
+	return fmt.Sprintf(`{
+    "id": %d,
+    "name": "%s",
+    "playable_class": {
+      "key": {
+        "href": "https://us.api.blizzard.com/data/wow/playable-class/%d?namespace=static-us"
+      },
+      "name": "%s",
+      "id": %d
+    },
+    "role": {
+      "type": "%s",
+      "name": "%s"
+    }
+  }`, id, name, classId, className, classId, role, role)
+}
+
+func TestGetClassRegistry(t *testing.T) {
+	scanner, err := testutils.NewMockScanner(func(requestPath string) (string, bool) {
+		switch requestPath {
+		case "/data/wow/playable-class/index":
+			return validClassIndex, true
+		case "/data/wow/playable-specialization/index":
+			return validSpecIndex, true
+		}
+		id, found := strings.CutPrefix(requestPath, "/data/wow/playable-specialization/")
+		if !found {
+			return "", false
+		}
+		spec, ok := mockSpecs[id]
+		return spec, ok
+	})
+	if err != nil {
+		t.Fatalf("failed to setup scanner: %v", err)
+	}
+
+	registry, err := GetClassRegistry(scanner)
+	if err != nil {
+		t.Fatalf("failed to get class registry: %v", err)
+	}
+
+	if len(registry.Classes) != 2 {
+		t.Fatalf("expected 2 classes, got %d", len(registry.Classes))
+	}
+
+	demonHunter := registry.Classes[1]
+	if demonHunter.Slug != "demonhunter" {
+		t.Fatalf("expected slug demonhunter, got %s", demonHunter.Slug)
+	}
+	if len(demonHunter.Specs) != 3 {
+		t.Fatalf("expected 3 demon hunter specs, got %d", len(demonHunter.Specs))
+	}
+
+	spec, ok := registry.SpecById(1480)
+	if !ok {
+		t.Fatalf("expected to find spec 1480")
+	}
+	if spec.Name != "Devourer" || spec.ClassName != "Demon Hunter" || spec.ClassId != 12 {
+		t.Fatalf("expected Devourer Demon Hunter (12), got %s %s (%d)", spec.Name, spec.ClassName, spec.ClassId)
+	}
+
+	spec, _ = registry.SpecById(104)
+	if spec.Role != "TANK" {
+		t.Fatalf("expected guardian to be a tank, got %s", spec.Role)
+	}
+}
+
+func TestGetClassRegistryFailsOnMissingSpec(t *testing.T) {
+	scanner, err := testutils.NewMockScanner(func(requestPath string) (string, bool) {
+		switch requestPath {
+		case "/data/wow/playable-class/index":
+			return validClassIndex, true
+		case "/data/wow/playable-specialization/index":
+			return validSpecIndex, true
+		}
+		return "", false
+	})
+	if err != nil {
+		t.Fatalf("failed to setup scanner: %v", err)
+	}
+
+	_, err = GetClassRegistry(scanner)
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
@@ -0,0 +1,111 @@
+package keystones
+
+import (
+	_ "embed"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+	"github.com/crbednarz/moonkinmetrics/pkg/scan"
+	"github.com/crbednarz/moonkinmetrics/pkg/validate"
+)
+
+//go:embed schema/connected-realm-index.schema.json
+var connectedRealmIndexSchema string
+
+//go:embed schema/leaderboard-index.schema.json
+var leaderboardIndexSchema string
+
+type connectedRealmIndexJson struct {
+	ConnectedRealms []keyJson `json:"connected_realms"`
+}
+
+type leaderboardIndexJson struct {
+	CurrentLeaderboards []struct {
+		Key  keyJson `json:"key"`
+		Name string  `json:"name"`
+		Id   int     `json:"id"`
+	} `json:"current_leaderboards"`
+}
+
+type keyJson struct {
+	Href string `json:"href"`
+}
+
+// GetConnectedRealmUrls returns the urls of every connected realm in a region.
+func GetConnectedRealmUrls(scanner *scan.Scanner, region api.Region) ([]string, error) {
+	validator, err := validate.NewSchemaValidator[connectedRealmIndexJson](connectedRealmIndexSchema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup connected realm index validator: %w", err)
+	}
+	result := scan.ScanSingle(
+		scanner,
+		&api.BnetRequest{
+			Region:    region,
+			Namespace: api.NamespaceDynamic,
+			Path:      "/data/wow/connected-realm/index",
+		},
+		&scan.ScanOptions[connectedRealmIndexJson]{
+			Validator: validator,
+			Lifespan:  time.Hour * 18,
+		},
+	)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	urls := make([]string, len(result.Response.ConnectedRealms))
+	for i, link := range result.Response.ConnectedRealms {
+		urls[i] = link.Href
+	}
+	return urls, nil
+}
+
+// GetCurrentLeaderboardUrls returns the urls of each dungeon's keystone
+// leaderboard for the current period, across all of the given connected realms.
+// Connected realms which fail to return an index are logged and skipped.
+func GetCurrentLeaderboardUrls(scanner *scan.Scanner, connectedRealmUrls []string) ([]string, error) {
+	validator, err := validate.NewSchemaValidator[leaderboardIndexJson](leaderboardIndexSchema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup keystone leaderboard index validator: %w", err)
+	}
+
+	requests := make(chan api.Request, len(connectedRealmUrls))
+	results := make(chan scan.ScanResult[leaderboardIndexJson], len(connectedRealmUrls))
+	options := scan.ScanOptions[leaderboardIndexJson]{
+		Validator: validator,
+		Lifespan:  time.Hour * 18,
+	}
+
+	scan.Scan(scanner, requests, results, &options)
+	for _, url := range connectedRealmUrls {
+		request, err := api.RequestFromUrl(url)
+		if err != nil {
+			close(requests)
+			return nil, fmt.Errorf("failed to create request from connected realm link [%v]: %w", url, err)
+		}
+		request.Path = fmt.Sprintf("%s/mythic-leaderboard/index", request.Path)
+		requests <- &request
+	}
+	close(requests)
+
+	urlsByRealm := make([][]string, len(connectedRealmUrls))
+	for result := range results {
+		if result.Error != nil {
+			log.Printf("Failed to retrieve keystone leaderboard index (%s): %v", result.ApiRequest.Id(), result.Error)
+			continue
+		}
+		realmUrls := make([]string, len(result.Response.CurrentLeaderboards))
+		for i, leaderboard := range result.Response.CurrentLeaderboards {
+			realmUrls[i] = leaderboard.Key.Href
+		}
+		urlsByRealm[result.Index] = realmUrls
+	}
+
+	urls := make([]string, 0, len(connectedRealmUrls)*8)
+	for _, realmUrls := range urlsByRealm {
+		urls = append(urls, realmUrls...)
+	}
+	return urls, nil
+}
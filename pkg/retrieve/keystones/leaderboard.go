@@ -0,0 +1,174 @@
+package keystones
+
+import (
+	_ "embed"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+	"github.com/crbednarz/moonkinmetrics/pkg/scan"
+	"github.com/crbednarz/moonkinmetrics/pkg/validate"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+// Bracket is the bracket name given to keystone leaderboards.
+const Bracket = "mythic-plus"
+
+//go:embed schema/leaderboard.schema.json
+var leaderboardSchema string
+
+type leaderboardJson struct {
+	LeadingGroups []struct {
+		Members []struct {
+			Profile struct {
+				Name  string `json:"name"`
+				Realm struct {
+					Key  keyJson `json:"key"`
+					Slug string  `json:"slug"`
+				} `json:"realm"`
+			} `json:"profile"`
+			Faction struct {
+				Type string `json:"type"`
+			} `json:"faction"`
+			Specialization struct {
+				Key keyJson `json:"key"`
+				Id  int     `json:"id"`
+			} `json:"specialization"`
+		} `json:"members"`
+		MythicRating struct {
+			Rating float64 `json:"rating"`
+		} `json:"mythic_rating"`
+		KeystoneLevel uint `json:"keystone_level"`
+	} `json:"leading_groups"`
+}
+
+// GetCurrentLeaderboard combines the current period's keystone leaderboards
+// for every dungeon on every connected realm in a region.
+//
+// Each player appears once per specialization they were ranked with, using
+// the rating of their best run. Entries are sorted by rating, highest first.
+func GetCurrentLeaderboard(scanner *scan.Scanner, region api.Region) (wow.Leaderboard, error) {
+	connectedRealmUrls, err := GetConnectedRealmUrls(scanner, region)
+	if err != nil {
+		return wow.Leaderboard{}, fmt.Errorf("failed to get connected realm index: %w", err)
+	}
+	log.Printf("Connected realms retrieved: %d total", len(connectedRealmUrls))
+
+	leaderboardUrls, err := GetCurrentLeaderboardUrls(scanner, connectedRealmUrls)
+	if err != nil {
+		return wow.Leaderboard{}, fmt.Errorf("failed to get keystone leaderboard urls: %w", err)
+	}
+	log.Printf("Keystone leaderboards found: %d total", len(leaderboardUrls))
+
+	entries, timestamp, err := getLeaderboardEntries(scanner, leaderboardUrls)
+	if err != nil {
+		return wow.Leaderboard{}, err
+	}
+
+	return wow.Leaderboard{
+		Entries:   entries,
+		Bracket:   Bracket,
+		Region:    region,
+		Timestamp: timestamp,
+	}, nil
+}
+
+// getLeaderboardEntries merges the entries of every leaderboard, also returning
+// when the oldest of them was retrieved.
+func getLeaderboardEntries(scanner *scan.Scanner, leaderboardUrls []string) ([]wow.LeaderboardEntry, time.Time, error) {
+	validator, err := validate.NewSchemaValidator[leaderboardJson](leaderboardSchema)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to setup keystone leaderboard validator: %w", err)
+	}
+
+	requests := make(chan api.Request, len(leaderboardUrls))
+	results := make(chan scan.ScanResult[leaderboardJson], len(leaderboardUrls))
+	options := scan.ScanOptions[leaderboardJson]{
+		Validator: validator,
+		Lifespan:  time.Hour * 18,
+	}
+
+	scan.Scan(scanner, requests, results, &options)
+	for _, url := range leaderboardUrls {
+		request, err := api.RequestFromUrl(url)
+		if err != nil {
+			close(requests)
+			return nil, time.Time{}, fmt.Errorf("failed to create request from keystone leaderboard link [%v]: %w", url, err)
+		}
+		requests <- &request
+	}
+	close(requests)
+
+	bestEntries := make(map[string]wow.LeaderboardEntry)
+	var timestamp time.Time
+	for result := range results {
+		if result.Error != nil {
+			log.Printf("Failed to retrieve keystone leaderboard (%s): %v", result.ApiRequest.Id(), result.Error)
+			continue
+		}
+		if timestamp.IsZero() || result.Details.Timestamp.Before(timestamp) {
+			timestamp = result.Details.Timestamp
+		}
+		for _, entry := range parseLeaderboardEntries(&result.Response) {
+			key := fmt.Sprintf(
+				"%s/%s/%d",
+				entry.Player.Realm.Slug,
+				strings.ToLower(entry.Player.Name),
+				entry.SpecId,
+			)
+			if best, ok := bestEntries[key]; !ok || entry.Rating > best.Rating {
+				bestEntries[key] = entry
+			}
+		}
+	}
+
+	entries := make([]wow.LeaderboardEntry, 0, len(bestEntries))
+	for _, entry := range bestEntries {
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b wow.LeaderboardEntry) int {
+		if a.Rating != b.Rating {
+			return int(b.Rating) - int(a.Rating)
+		}
+		if a.Player.Realm.Slug != b.Player.Realm.Slug {
+			return strings.Compare(a.Player.Realm.Slug, b.Player.Realm.Slug)
+		}
+		if a.Player.Name != b.Player.Name {
+			return strings.Compare(a.Player.Name, b.Player.Name)
+		}
+		return a.SpecId - b.SpecId
+	})
+	return entries, timestamp, nil
+}
+
+func parseLeaderboardEntries(inputJson *leaderboardJson) []wow.LeaderboardEntry {
+	entries := make([]wow.LeaderboardEntry, 0, len(inputJson.LeadingGroups)*5)
+	for _, group := range inputJson.LeadingGroups {
+		// Older periods don't report a rating per run, so fall back to the
+		// keystone level to keep runs comparable.
+		rating := uint(math.Round(group.MythicRating.Rating))
+		if rating == 0 {
+			rating = group.KeystoneLevel
+		}
+
+		for _, member := range group.Members {
+			entries = append(entries, wow.LeaderboardEntry{
+				Player: wow.PlayerLink{
+					Name: member.Profile.Name,
+					Realm: wow.RealmLink{
+						Slug: member.Profile.Realm.Slug,
+						Url:  member.Profile.Realm.Key.Href,
+					},
+				},
+				Faction: member.Faction.Type,
+				Rating:  rating,
+				SpecId:  member.Specialization.Id,
+			})
+		}
+	}
+	return entries
+}
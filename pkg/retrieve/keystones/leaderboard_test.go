@@ -0,0 +1,100 @@
+package keystones
+
+import (
+	_ "embed"
+	"strings"
+	"testing"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+	"github.com/crbednarz/moonkinmetrics/pkg/scan"
+	"github.com/crbednarz/moonkinmetrics/pkg/testutils"
+)
+
+var (
+	//go:embed testdata/valid-connected-realm-index.json
+	validConnectedRealmIndex string
+
+	//go:embed testdata/valid-leaderboard-index.json
+	validLeaderboardIndex string
+
+	//go:embed testdata/valid-leaderboard.json
+	validLeaderboard string
+
+	//go:embed testdata/bad-leaderboard.json
+	badLeaderboard string
+)
+
+func newLeaderboardMockScanner(body string) (*scan.Scanner, error) {
+	return testutils.NewMockScanner(
+		func(requestPath string) (string, bool) {
+			if requestPath == "/data/wow/connected-realm/index" {
+				return validConnectedRealmIndex, true
+			}
+			if strings.HasSuffix(requestPath, "/mythic-leaderboard/index") {
+				return validLeaderboardIndex, true
+			}
+			return body, strings.HasSuffix(requestPath, "/period/1001")
+		},
+	)
+}
+
+func TestGetCurrentLeaderboard(t *testing.T) {
+	scanner, err := newLeaderboardMockScanner(validLeaderboard)
+	if err != nil {
+		t.Fatalf("failed to setup scanner: %v", err)
+	}
+
+	leaderboard, err := GetCurrentLeaderboard(scanner, api.RegionUS)
+	if err != nil {
+		t.Fatalf("failed to get leaderboard: %v", err)
+	}
+
+	if leaderboard.Bracket != Bracket {
+		t.Fatalf("expected bracket %s, got %s", Bracket, leaderboard.Bracket)
+	}
+
+	// Every dungeon on every realm returns the same runs, so each player and
+	// spec pair should only be counted once.
+	if len(leaderboard.Entries) != 8 {
+		t.Fatalf("expected 8 entries, got %d", len(leaderboard.Entries))
+	}
+
+	first := leaderboard.Entries[0]
+	if first.Rating != 512 {
+		t.Fatalf("expected highest rating of 512, got %d", first.Rating)
+	}
+
+	for _, entry := range leaderboard.Entries {
+		if entry.Player.Name != "Moonkin" {
+			continue
+		}
+		switch entry.SpecId {
+		case 102:
+			if entry.Rating != 512 {
+				t.Fatalf("expected best balance run of 512, got %d", entry.Rating)
+			}
+		case 105:
+			if entry.Rating != 440 {
+				t.Fatalf("expected best restoration run of 440, got %d", entry.Rating)
+			}
+		default:
+			t.Fatalf("unexpected spec id %d", entry.SpecId)
+		}
+	}
+}
+
+func TestGetCurrentLeaderboardSkipsBadData(t *testing.T) {
+	scanner, err := newLeaderboardMockScanner(badLeaderboard)
+	if err != nil {
+		t.Fatalf("failed to setup scanner: %v", err)
+	}
+
+	leaderboard, err := GetCurrentLeaderboard(scanner, api.RegionUS)
+	if err != nil {
+		t.Fatalf("failed to get leaderboard: %v", err)
+	}
+
+	if len(leaderboard.Entries) != 0 {
+		t.Fatalf("expected 0 entries, got %d", len(leaderboard.Entries))
+	}
+}
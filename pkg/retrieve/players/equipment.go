@@ -0,0 +1,188 @@
+package players
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+	"github.com/crbednarz/moonkinmetrics/pkg/scan"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+type EquipmentResponse struct {
+	Error     error
+	Equipment wow.Equipment
+}
+
+type equipmentJson struct {
+	EquippedItems []equippedItemJson `json:"equipped_items"`
+	Character     characterJson      `json:"character"`
+}
+
+type equippedItemJson struct {
+	Item struct {
+		Id int `json:"id"`
+	} `json:"item"`
+	Slot struct {
+		Type string `json:"type"`
+	} `json:"slot"`
+	Level struct {
+		Value int `json:"value"`
+	} `json:"level"`
+	Enchantments []struct {
+		EnchantmentId int `json:"enchantment_id"`
+	} `json:"enchantments"`
+	Sockets []struct {
+		Item struct {
+			Id int `json:"id"`
+		} `json:"item"`
+	} `json:"sockets"`
+	Set struct {
+		ItemSet struct {
+			Name string `json:"name"`
+			Id   int    `json:"id"`
+		} `json:"item_set"`
+		Items []struct {
+			IsEquipped bool `json:"is_equipped"`
+		} `json:"items"`
+		Effects []struct {
+			RequiredCount int  `json:"required_count"`
+			IsActive      bool `json:"is_active"`
+		} `json:"effects"`
+	} `json:"set"`
+}
+
+// GetPlayerEquipment retrieves the equipped items of each player.
+func GetPlayerEquipment(scanner *scan.Scanner, players []wow.PlayerLink, region api.Region) ([]EquipmentResponse, error) {
+	requests := make(chan api.Request, len(players))
+	results := make(chan scan.ScanResult[equipmentJson], len(players))
+	options := scan.ScanOptions[equipmentJson]{
+		Validator: &equipmentValidator{},
+		Lifespan:  time.Hour * 18,
+		Repairs: []scan.ResultProcessor[equipmentJson]{
+			scan.NewResultProcessor(removeIncompleteItems),
+		},
+	}
+
+	scan.Scan(scanner, requests, results, &options)
+	for _, player := range players {
+		requests <- &api.BnetRequest{
+			Region:    region,
+			Namespace: api.NamespaceProfile,
+			Path:      player.EquipmentUrl(),
+		}
+	}
+	close(requests)
+
+	responses := make([]EquipmentResponse, len(players))
+	for result := range results {
+		if result.Error != nil {
+			responses[result.Index].Error = result.Error
+			log.Printf("Failed to retrieve player equipment (%s): %v", result.ApiRequest.Id(), result.Error)
+			continue
+		}
+		responses[result.Index].Equipment = parseEquipment(&result.Response)
+	}
+	return responses, nil
+}
+
+func parseEquipment(inputJson *equipmentJson) wow.Equipment {
+	items := make([]wow.EquippedItem, len(inputJson.EquippedItems))
+	sets := make([]wow.EquippedSet, 0)
+	setsSeen := make(map[int]bool)
+	for i, itemJson := range inputJson.EquippedItems {
+		enchants := make([]int, 0, len(itemJson.Enchantments))
+		for _, enchantment := range itemJson.Enchantments {
+			enchants = append(enchants, enchantment.EnchantmentId)
+		}
+
+		gems := make([]int, 0, len(itemJson.Sockets))
+		for _, socket := range itemJson.Sockets {
+			// Empty sockets are reported without an item.
+			if socket.Item.Id != 0 {
+				gems = append(gems, socket.Item.Id)
+			}
+		}
+
+		items[i] = wow.EquippedItem{
+			Slot:      itemJson.Slot.Type,
+			Enchants:  enchants,
+			Gems:      gems,
+			Id:        itemJson.Item.Id,
+			ItemLevel: itemJson.Level.Value,
+		}
+
+		// Every piece of a set reports the whole set, so only the first is used.
+		set := &itemJson.Set
+		if set.ItemSet.Id == 0 || setsSeen[set.ItemSet.Id] {
+			continue
+		}
+		setsSeen[set.ItemSet.Id] = true
+
+		equippedSet := wow.EquippedSet{
+			Name: set.ItemSet.Name,
+			Id:   set.ItemSet.Id,
+		}
+		for _, setItem := range set.Items {
+			if setItem.IsEquipped {
+				equippedSet.EquippedCount++
+			}
+		}
+		for _, effect := range set.Effects {
+			if effect.IsActive {
+				equippedSet.ActiveBonuses++
+			}
+		}
+		sets = append(sets, equippedSet)
+	}
+
+	return wow.Equipment{
+		Items: items,
+		Sets:  sets,
+	}
+}
+
+// removeIncompleteItems drops items which are missing an id, slot or item
+// level, rather than failing the whole character.
+func removeIncompleteItems(e *equipmentJson) error {
+	items := make([]equippedItemJson, 0, len(e.EquippedItems))
+	for _, item := range e.EquippedItems {
+		if validateEquippedItemJson(&item) == nil {
+			items = append(items, item)
+		}
+	}
+	e.EquippedItems = items
+	return nil
+}
+
+type equipmentValidator struct{}
+
+func (v *equipmentValidator) IsValid(equipment *equipmentJson) error {
+	if len(equipment.EquippedItems) == 0 {
+		return fmt.Errorf("equipment.EquippedItems cannot be empty or nil")
+	}
+
+	for i := range equipment.EquippedItems {
+		err := validateEquippedItemJson(&equipment.EquippedItems[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateEquippedItemJson(item *equippedItemJson) error {
+	if item.Item.Id == 0 {
+		return fmt.Errorf("item.Item.Id cannot be zero")
+	}
+
+	if len(item.Slot.Type) == 0 {
+		return fmt.Errorf("item.Slot.Type cannot be empty")
+	}
+
+	if item.Level.Value == 0 {
+		return fmt.Errorf("item.Level.Value cannot be zero")
+	}
+	return nil
+}
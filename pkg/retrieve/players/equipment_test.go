@@ -0,0 +1,72 @@
+package players
+
+import (
+	_ "embed"
+	"testing"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+	"github.com/crbednarz/moonkinmetrics/pkg/testutils"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+//go:embed testdata/valid-equipment.json
+var validEquipment string
+
+func TestGetPlayerEquipment(t *testing.T) {
+	scanner, err := testutils.NewSingleResourceMockScanner(
+		"/profile/wow/character/windrunner/chutney/equipment",
+		validEquipment,
+	)
+	if err != nil {
+		t.Fatalf("failed to setup scanner: %v", err)
+	}
+
+	playerLink := wow.PlayerLink{
+		Name:  "Chutney",
+		Realm: wow.RealmLink{Slug: "windrunner"},
+	}
+	responses, err := GetPlayerEquipment(scanner, []wow.PlayerLink{playerLink}, api.RegionUS)
+	if err != nil {
+		t.Fatalf("failed to get player equipment: %v", err)
+	}
+
+	if responses[0].Error != nil {
+		t.Fatalf("expected no error, got %v", responses[0].Error)
+	}
+
+	// The incomplete tabard should have been repaired away.
+	equipment := responses[0].Equipment
+	if len(equipment.Items) != 16 {
+		t.Fatalf("expected 16 items, got %d", len(equipment.Items))
+	}
+
+	chest := equipment.Items[4]
+	if chest.Slot != "CHEST" || chest.Id != 212059 || chest.ItemLevel != 639 {
+		t.Errorf("expected chest 212059 at 639, got %s %d at %d", chest.Slot, chest.Id, chest.ItemLevel)
+	}
+	if len(chest.Enchants) != 1 || chest.Enchants[0] != 7364 {
+		t.Errorf("expected chest enchant 7364, got %v", chest.Enchants)
+	}
+
+	neck := equipment.Items[1]
+	if len(neck.Gems) != 2 {
+		t.Errorf("expected 2 gems in neck, got %d", len(neck.Gems))
+	}
+
+	waist := equipment.Items[5]
+	if len(waist.Gems) != 0 {
+		t.Errorf("expected empty socket to be ignored, got %v", waist.Gems)
+	}
+
+	if len(equipment.Sets) != 1 {
+		t.Fatalf("expected 1 set, got %d", len(equipment.Sets))
+	}
+	set := equipment.Sets[0]
+	if set.Id != 1684 || set.EquippedCount != 4 || set.ActiveBonuses != 2 {
+		t.Errorf("expected set 1684 with 4 pieces and 2 bonuses, got %d with %d and %d", set.Id, set.EquippedCount, set.ActiveBonuses)
+	}
+
+	if itemLevel := equipment.AverageItemLevel(); itemLevel < 633 || itemLevel > 634 {
+		t.Errorf("expected average item level of about 633.9, got %f", itemLevel)
+	}
+}
@@ -0,0 +1,113 @@
+package players
+
+import (
+	_ "embed"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+	"github.com/crbednarz/moonkinmetrics/pkg/scan"
+	"github.com/crbednarz/moonkinmetrics/pkg/validate"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+//go:embed schema/pvp-bracket.schema.json
+var pvpBracketSchema string
+
+type PvpBracketStatisticsResponse struct {
+	Error      error
+	Statistics wow.PvpBracketStatistics
+}
+
+type pvpBracketJson struct {
+	Bracket struct {
+		Type string `json:"type"`
+		Id   int    `json:"id"`
+	} `json:"bracket"`
+	Season struct {
+		Id int `json:"id"`
+	} `json:"season"`
+	Tier struct {
+		Id int `json:"id"`
+	} `json:"tier"`
+	Specialization struct {
+		Name string `json:"name"`
+		Id   int    `json:"id"`
+	} `json:"specialization"`
+	SeasonMatchStatistics matchStatisticsJson `json:"season_match_statistics"`
+	WeeklyMatchStatistics matchStatisticsJson `json:"weekly_match_statistics"`
+	SeasonRoundStatistics matchStatisticsJson `json:"season_round_statistics"`
+	WeeklyRoundStatistics matchStatisticsJson `json:"weekly_round_statistics"`
+	Rating                uint                `json:"rating"`
+}
+
+type matchStatisticsJson struct {
+	Played uint `json:"played"`
+	Won    uint `json:"won"`
+	Lost   uint `json:"lost"`
+}
+
+// GetPvpBracketStatistics retrieves each player's statistics for a single
+// bracket. Querying a per-spec bracket, such as "shuffle-druid-balance",
+// returns the player's rating for that specialization.
+//
+// Players who haven't played the bracket this season will have an error of
+// scan.ErrNotFound.
+func GetPvpBracketStatistics(scanner *scan.Scanner, players []wow.PlayerLink, bracket string, region api.Region) ([]PvpBracketStatisticsResponse, error) {
+	validator, err := validate.NewSchemaValidator[pvpBracketJson](pvpBracketSchema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup pvp bracket validator: %w", err)
+	}
+
+	requests := make(chan api.Request, len(players))
+	results := make(chan scan.ScanResult[pvpBracketJson], len(players))
+	options := scan.ScanOptions[pvpBracketJson]{
+		Validator: validator,
+		Lifespan:  time.Hour * 18,
+	}
+
+	scan.Scan(scanner, requests, results, &options)
+	for _, player := range players {
+		requests <- &api.BnetRequest{
+			Region:    region,
+			Namespace: api.NamespaceProfile,
+			Path:      player.PvpBracketUrl(bracket),
+		}
+	}
+	close(requests)
+
+	responses := make([]PvpBracketStatisticsResponse, len(players))
+	for result := range results {
+		if result.Error != nil {
+			responses[result.Index].Error = result.Error
+			log.Printf("Failed to retrieve pvp bracket statistics (%s): %v", result.ApiRequest.Id(), result.Error)
+			continue
+		}
+		responses[result.Index].Statistics = parsePvpBracketStatistics(&result.Response, bracket)
+	}
+	return responses, nil
+}
+
+func parsePvpBracketStatistics(inputJson *pvpBracketJson, bracket string) wow.PvpBracketStatistics {
+	return wow.PvpBracketStatistics{
+		Bracket:      bracket,
+		SpecName:     inputJson.Specialization.Name,
+		Rating:       inputJson.Rating,
+		SeasonId:     inputJson.Season.Id,
+		TierId:       inputJson.Tier.Id,
+		SpecId:       inputJson.Specialization.Id,
+		Season:       parseMatchStatistics(inputJson.SeasonMatchStatistics),
+		Weekly:       parseMatchStatistics(inputJson.WeeklyMatchStatistics),
+		SeasonRounds: parseMatchStatistics(inputJson.SeasonRoundStatistics),
+		WeeklyRounds: parseMatchStatistics(inputJson.WeeklyRoundStatistics),
+	}
+}
+
+func parseMatchStatistics(inputJson matchStatisticsJson) wow.MatchStatistics {
+	return wow.MatchStatistics{
+		Played: inputJson.Played,
+		Won:    inputJson.Won,
+		Lost:   inputJson.Lost,
+	}
+}
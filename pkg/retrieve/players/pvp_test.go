@@ -0,0 +1,66 @@
+package players
+
+import (
+	_ "embed"
+	"errors"
+	"testing"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+	"github.com/crbednarz/moonkinmetrics/pkg/scan"
+	"github.com/crbednarz/moonkinmetrics/pkg/testutils"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+//go:embed testdata/valid-pvp-bracket.json
+var validPvpBracket string
+
+func TestGetPvpBracketStatistics(t *testing.T) {
+	scanner, err := testutils.NewSingleResourceMockScanner(
+		"/profile/wow/character/windrunner/chutney/pvp-bracket/shuffle-druid-restoration",
+		validPvpBracket,
+	)
+	if err != nil {
+		t.Fatalf("failed to setup scanner: %v", err)
+	}
+
+	players := []wow.PlayerLink{
+		{Name: "Chutney", Realm: wow.RealmLink{Slug: "windrunner"}},
+		{Name: "Missing", Realm: wow.RealmLink{Slug: "windrunner"}},
+	}
+	responses, err := GetPvpBracketStatistics(scanner, players, "shuffle-druid-restoration", api.RegionUS)
+	if err != nil {
+		t.Fatalf("failed to get pvp bracket statistics: %v", err)
+	}
+
+	if len(responses) != 2 {
+		t.Fatalf("expected 2 responses, got %d", len(responses))
+	}
+
+	if responses[0].Error != nil {
+		t.Fatalf("expected no error, got %v", responses[0].Error)
+	}
+
+	stats := responses[0].Statistics
+	if stats.Rating != 2412 {
+		t.Errorf("expected rating 2412, got %d", stats.Rating)
+	}
+	if stats.SpecId != 105 || stats.SpecName != "Restoration" {
+		t.Errorf("expected spec 105 (Restoration), got %d (%s)", stats.SpecId, stats.SpecName)
+	}
+	if stats.SeasonId != 38 {
+		t.Errorf("expected season 38, got %d", stats.SeasonId)
+	}
+	if stats.Season.Played != 84 || stats.Season.Won != 51 {
+		t.Errorf("expected 84 played and 51 won this season, got %d and %d", stats.Season.Played, stats.Season.Won)
+	}
+	if stats.Weekly.Played != 12 || stats.Weekly.Won != 8 {
+		t.Errorf("expected 12 played and 8 won this week, got %d and %d", stats.Weekly.Played, stats.Weekly.Won)
+	}
+	if stats.WeeklyRounds.Played != 72 {
+		t.Errorf("expected 72 rounds played this week, got %d", stats.WeeklyRounds.Played)
+	}
+
+	if !errors.Is(responses[1].Error, scan.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", responses[1].Error)
+	}
+}
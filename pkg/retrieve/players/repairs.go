@@ -2,8 +2,11 @@ package players
 
 import "github.com/crbednarz/moonkinmetrics/pkg/scan"
 
+// unusedRemover drops every spec but the target one, along with its inactive
+// loadouts. With AllLoadouts, inactive loadouts are kept unless incomplete.
 type unusedRemover struct {
 	OverrideSpec string
+	AllLoadouts  bool
 }
 
 func (r *unusedRemover) Process(s *specializationsJson) error {
@@ -30,7 +33,11 @@ func (r *unusedRemover) Process(s *specializationsJson) error {
 			}
 		}
 		if targetLoadout != nil {
-			targetSpec.Loadouts = []loadoutJson{*targetLoadout}
+			loadouts := []loadoutJson{*targetLoadout}
+			if r.AllLoadouts {
+				loadouts = completeLoadouts(targetSpec.Loadouts)
+			}
+			targetSpec.Loadouts = loadouts
 			s.Specializations = []specializationJson{*targetSpec}
 		}
 	}
@@ -38,6 +45,18 @@ func (r *unusedRemover) Process(s *specializationsJson) error {
 	return nil
 }
 
+// completeLoadouts keeps the active loadout, and any inactive loadouts which
+// pass validation.
+func completeLoadouts(loadouts []loadoutJson) []loadoutJson {
+	complete := make([]loadoutJson, 0, len(loadouts))
+	for i := range loadouts {
+		if loadouts[i].IsActive || validateLoadoutJson(&loadouts[i]) == nil {
+			complete = append(complete, loadouts[i])
+		}
+	}
+	return complete
+}
+
 func removePartialTalents(s *specializationsJson) error {
 	for specIndex := range s.Specializations {
 		spec := &s.Specializations[specIndex]
@@ -66,7 +85,7 @@ func removePartialTalents(s *specializationsJson) error {
 
 func getRepairs(config loadoutScanOptions) []scan.ResultProcessor[specializationsJson] {
 	return []scan.ResultProcessor[specializationsJson]{
-		&unusedRemover{OverrideSpec: config.OverrideSpec},
+		&unusedRemover{OverrideSpec: config.OverrideSpec, AllLoadouts: config.AllLoadouts},
 		scan.NewResultProcessor(removePartialTalents),
 	}
 }
@@ -14,6 +14,10 @@ import (
 type LoadoutResponse struct {
 	Error   error
 	Loadout wow.Loadout
+	// Saved is every complete loadout saved for the spec, including the active
+	// one, in the order they're listed in game. It's only populated when
+	// scanning WithAllLoadouts.
+	Saved []wow.Loadout
 }
 
 type specializationsJson struct {
@@ -104,6 +108,7 @@ type keyJson struct {
 type loadoutScanOptions struct {
 	OverrideSpec string
 	Region       api.Region
+	AllLoadouts  bool
 }
 
 type LoadoutScanOption interface {
@@ -122,6 +127,12 @@ func (o overrideSpecOption) apply(options *loadoutScanOptions) {
 	options.OverrideSpec = string(o)
 }
 
+type allLoadoutsOption bool
+
+func (o allLoadoutsOption) apply(options *loadoutScanOptions) {
+	options.AllLoadouts = bool(o)
+}
+
 func WithRegion(region api.Region) LoadoutScanOption {
 	return regionOption(region)
 }
@@ -130,6 +141,13 @@ func WithOverrideSpec(spec string) LoadoutScanOption {
 	return overrideSpecOption(spec)
 }
 
+// WithAllLoadouts keeps every saved loadout for the spec, rather than only the
+// active one. Saved loadouts missing talents, such as those left over from
+// before hero talents, are skipped.
+func WithAllLoadouts() LoadoutScanOption {
+	return allLoadoutsOption(true)
+}
+
 func GetPlayerLoadouts(scanner *scan.Scanner, players []wow.PlayerLink, opts ...LoadoutScanOption) ([]LoadoutResponse, error) {
 	scanOptions := &loadoutScanOptions{
 		OverrideSpec: "",
@@ -177,22 +195,36 @@ func GetPlayerLoadouts(scanner *scan.Scanner, players []wow.PlayerLink, opts ...
 			log.Printf("Failed to parse player loadout json (%s): %v", id, err)
 			continue
 		}
+
+		if scanOptions.AllLoadouts {
+			loadouts[result.Index].Saved = savedLoadoutsFromSpecializationsJson(&result.Response, scanOptions)
+		}
 	}
 
 	return loadouts, nil
 }
 
-func activeLoadoutFromSpecializationsJson(inputJson *specializationsJson, config *loadoutScanOptions) (wow.Loadout, error) {
-	activeSpec := inputJson.ActiveSpecialization.Name
+func targetSpecName(inputJson *specializationsJson, config *loadoutScanOptions) string {
 	if config.OverrideSpec != "" {
-		activeSpec = config.OverrideSpec
+		return config.OverrideSpec
 	}
+	return inputJson.ActiveSpecialization.Name
+}
 
-	for _, specializationJson := range inputJson.Specializations {
-		if specializationJson.Specialization.Name != activeSpec {
-			continue
+func findSpecializationJson(inputJson *specializationsJson, specName string) *specializationJson {
+	for i := range inputJson.Specializations {
+		if inputJson.Specializations[i].Specialization.Name == specName {
+			return &inputJson.Specializations[i]
 		}
+	}
+	return nil
+}
+
+func activeLoadoutFromSpecializationsJson(inputJson *specializationsJson, config *loadoutScanOptions) (wow.Loadout, error) {
+	activeSpec := targetSpecName(inputJson, config)
 
+	specializationJson := findSpecializationJson(inputJson, activeSpec)
+	if specializationJson != nil {
 		for _, loadoutJson := range specializationJson.Loadouts {
 			if loadoutJson.IsActive {
 				loadout := parseLoadout(loadoutJson)
@@ -200,7 +232,6 @@ func activeLoadoutFromSpecializationsJson(inputJson *specializationsJson, config
 				return loadout, nil
 			}
 		}
-		break
 	}
 
 	return wow.Loadout{}, fmt.Errorf(
@@ -211,6 +242,24 @@ func activeLoadoutFromSpecializationsJson(inputJson *specializationsJson, config
 	)
 }
 
+// savedLoadoutsFromSpecializationsJson parses every loadout of the target spec.
+// Incomplete loadouts have already been removed by repairs. PvP talents aren't
+// saved per loadout, so each shares the spec's current selection.
+func savedLoadoutsFromSpecializationsJson(inputJson *specializationsJson, config *loadoutScanOptions) []wow.Loadout {
+	specializationJson := findSpecializationJson(inputJson, targetSpecName(inputJson, config))
+	if specializationJson == nil {
+		return nil
+	}
+
+	loadouts := make([]wow.Loadout, 0, len(specializationJson.Loadouts))
+	for _, loadoutJson := range specializationJson.Loadouts {
+		loadout := parseLoadout(loadoutJson)
+		loadout.PvpTalents = parsePvpTalents(specializationJson.PvpTalentSlots)
+		loadouts = append(loadouts, loadout)
+	}
+	return loadouts
+}
+
 func parseLoadout(inputJson loadoutJson) wow.Loadout {
 	classNodes := make([]wow.LoadoutNode, len(inputJson.SelectedClassTalents))
 	for i, talent := range inputJson.SelectedClassTalents {
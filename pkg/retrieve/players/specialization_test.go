@@ -63,3 +63,52 @@ func TestGetSingeLoadout(t *testing.T) {
 		t.Errorf("expected 3 pvp talents, got %d", len(responses[0].Loadout.PvpTalents))
 	}
 }
+
+func TestGetAllSavedLoadouts(t *testing.T) {
+	scanner, err := testutils.NewSingleResourceMockScanner(
+		"/profile/wow/character/windrunner/chutney/specializations",
+		validPlayer,
+	)
+	if err != nil {
+		t.Fatalf("failed to setup scanner: %v", err)
+	}
+
+	playerLink := wow.PlayerLink{
+		Name: "chutney",
+		Realm: wow.RealmLink{
+			Slug: "windrunner",
+			Url:  "",
+		},
+	}
+	responses, err := GetPlayerLoadouts(
+		scanner,
+		[]wow.PlayerLink{playerLink},
+		WithAllLoadouts(),
+	)
+	if err != nil {
+		t.Fatalf("failed to load player loadout: %v", err)
+	}
+
+	if responses[0].Error != nil {
+		t.Fatalf("expected no error, got %v", responses[0].Error)
+	}
+
+	// Saved loadouts from before hero talents are incomplete, and skipped.
+	saved := responses[0].Saved
+	if len(saved) != 6 {
+		t.Fatalf("expected 6 saved loadouts, got %d", len(saved))
+	}
+
+	for _, loadout := range saved {
+		if loadout.SpecName != "Restoration" {
+			t.Errorf("expected spec name 'Restoration', got %s", loadout.SpecName)
+		}
+		if len(loadout.HeroNodes) == 0 {
+			t.Errorf("expected saved loadouts to include hero nodes")
+		}
+	}
+
+	if saved[0].Code != responses[0].Loadout.Code {
+		t.Errorf("expected first saved loadout to be the active one")
+	}
+}
@@ -0,0 +1,90 @@
+package players
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+	"github.com/crbednarz/moonkinmetrics/pkg/scan"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+type CharacterSummaryResponse struct {
+	Error   error
+	Summary wow.CharacterSummary
+}
+
+type characterSummaryJson struct {
+	Name  string `json:"name"`
+	Guild struct {
+		Name  string        `json:"name"`
+		Realm realmLinkJson `json:"realm"`
+		Id    int           `json:"id"`
+	} `json:"guild"`
+	ActiveTitle struct {
+		Name string `json:"name"`
+		Id   int    `json:"id"`
+	} `json:"active_title"`
+	Level              int   `json:"level"`
+	AverageItemLevel   int   `json:"average_item_level"`
+	EquippedItemLevel  int   `json:"equipped_item_level"`
+	LastLoginTimestamp int64 `json:"last_login_timestamp"`
+}
+
+// GetCharacterSummaries retrieves the profile summary of each player.
+func GetCharacterSummaries(scanner *scan.Scanner, players []wow.PlayerLink, region api.Region) ([]CharacterSummaryResponse, error) {
+	requests := make(chan api.Request, len(players))
+	results := make(chan scan.ScanResult[characterSummaryJson], len(players))
+	options := scan.ScanOptions[characterSummaryJson]{
+		Validator: &characterSummaryValidator{},
+		Lifespan:  time.Hour * 18,
+	}
+
+	scan.Scan(scanner, requests, results, &options)
+	for _, player := range players {
+		requests <- &api.BnetRequest{
+			Region:    region,
+			Namespace: api.NamespaceProfile,
+			Path:      player.SummaryUrl(),
+		}
+	}
+	close(requests)
+
+	responses := make([]CharacterSummaryResponse, len(players))
+	for result := range results {
+		if result.Error != nil {
+			responses[result.Index].Error = result.Error
+			log.Printf("Failed to retrieve character summary (%s): %v", result.ApiRequest.Id(), result.Error)
+			continue
+		}
+		responses[result.Index].Summary = parseCharacterSummary(&result.Response)
+	}
+	return responses, nil
+}
+
+func parseCharacterSummary(inputJson *characterSummaryJson) wow.CharacterSummary {
+	return wow.CharacterSummary{
+		Guild:             inputJson.Guild.Name,
+		GuildRealm:        inputJson.Guild.Realm.Slug,
+		Title:             inputJson.ActiveTitle.Name,
+		LastLogin:         time.UnixMilli(inputJson.LastLoginTimestamp),
+		Level:             inputJson.Level,
+		AverageItemLevel:  inputJson.AverageItemLevel,
+		EquippedItemLevel: inputJson.EquippedItemLevel,
+		TitleId:           inputJson.ActiveTitle.Id,
+	}
+}
+
+type characterSummaryValidator struct{}
+
+func (v *characterSummaryValidator) IsValid(summary *characterSummaryJson) error {
+	if summary.LastLoginTimestamp == 0 {
+		return fmt.Errorf("summary.LastLoginTimestamp cannot be zero")
+	}
+
+	if summary.Level == 0 {
+		return fmt.Errorf("summary.Level cannot be zero")
+	}
+	return nil
+}
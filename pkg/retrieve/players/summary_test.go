@@ -0,0 +1,60 @@
+package players
+
+import (
+	_ "embed"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+	"github.com/crbednarz/moonkinmetrics/pkg/scan"
+	"github.com/crbednarz/moonkinmetrics/pkg/testutils"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+//go:embed testdata/valid-summary.json
+var validSummary string
+
+func TestGetCharacterSummaries(t *testing.T) {
+	scanner, err := testutils.NewSingleResourceMockScanner(
+		"/profile/wow/character/windrunner/chutney",
+		validSummary,
+	)
+	if err != nil {
+		t.Fatalf("failed to setup scanner: %v", err)
+	}
+
+	playerLinks := []wow.PlayerLink{
+		{Name: "Chutney", Realm: wow.RealmLink{Slug: "windrunner"}},
+		{Name: "Missing", Realm: wow.RealmLink{Slug: "windrunner"}},
+	}
+	responses, err := GetCharacterSummaries(scanner, playerLinks, api.RegionUS)
+	if err != nil {
+		t.Fatalf("failed to get character summaries: %v", err)
+	}
+
+	if responses[0].Error != nil {
+		t.Fatalf("expected no error, got %v", responses[0].Error)
+	}
+
+	summary := responses[0].Summary
+	if summary.Guild != "Grove Tenders" || summary.GuildRealm != "windrunner" {
+		t.Errorf("expected guild Grove Tenders on windrunner, got %s on %s", summary.Guild, summary.GuildRealm)
+	}
+	if summary.Title != "Duelist" || summary.TitleId != 480 {
+		t.Errorf("expected title Duelist (480), got %s (%d)", summary.Title, summary.TitleId)
+	}
+	if summary.Level != 80 {
+		t.Errorf("expected level 80, got %d", summary.Level)
+	}
+	if summary.AverageItemLevel != 642 || summary.EquippedItemLevel != 639 {
+		t.Errorf("expected item levels 642/639, got %d/%d", summary.AverageItemLevel, summary.EquippedItemLevel)
+	}
+	if !summary.LastLogin.Equal(time.UnixMilli(1736372553000)) {
+		t.Errorf("expected last login at 1736372553000, got %v", summary.LastLogin)
+	}
+
+	if !errors.Is(responses[1].Error, scan.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for missing character, got %v", responses[1].Error)
+	}
+}
@@ -27,7 +27,16 @@ type leaderboardJson struct {
 				Id   int     `json:"id"`
 			} `json:"realm"`
 		} `json:"character"`
+		Tier struct {
+			Id int `json:"id"`
+		} `json:"tier"`
+		SeasonMatchStatistics struct {
+			Played uint `json:"played"`
+			Won    uint `json:"won"`
+			Lost   uint `json:"lost"`
+		} `json:"season_match_statistics"`
 		Rating uint `json:"rating"`
+		Rank   uint `json:"rank"`
 	} `json:"entries"`
 }
 
@@ -37,6 +46,12 @@ func GetCurrentLeaderboard(scanner *scan.Scanner, bracket string, region api.Reg
 		return wow.Leaderboard{}, fmt.Errorf("failed to get current season id: %w", err)
 	}
 
+	return GetSeasonLeaderboard(scanner, seasonId, bracket, region)
+}
+
+// GetSeasonLeaderboard retrieves a bracket's leaderboard for any season,
+// including those which have already ended.
+func GetSeasonLeaderboard(scanner *scan.Scanner, seasonId int, bracket string, region api.Region) (wow.Leaderboard, error) {
 	validator, err := validate.NewSchemaValidator[leaderboardJson](leaderboardSchema)
 	if err != nil {
 		return wow.Leaderboard{}, fmt.Errorf("failed to setup leaderboard validator: %w", err)
@@ -60,9 +75,11 @@ func GetCurrentLeaderboard(scanner *scan.Scanner, bracket string, region api.Reg
 	}
 
 	return wow.Leaderboard{
-		Entries: parseLeaderboardEntries(&result.Response),
-		Bracket: bracket,
-		Region:  region,
+		Entries:   parseLeaderboardEntries(&result.Response),
+		Bracket:   bracket,
+		Region:    region,
+		Season:    seasonId,
+		Timestamp: result.Details.Timestamp,
 	}, nil
 }
 
@@ -79,6 +96,13 @@ func parseLeaderboardEntries(inputJson *leaderboardJson) []wow.LeaderboardEntry
 			},
 			Faction: entry.Faction.Type,
 			Rating:  entry.Rating,
+			Rank:    entry.Rank,
+			TierId:  entry.Tier.Id,
+			MatchStatistics: wow.MatchStatistics{
+				Played: entry.SeasonMatchStatistics.Played,
+				Won:    entry.SeasonMatchStatistics.Won,
+				Lost:   entry.SeasonMatchStatistics.Lost,
+			},
 		}
 	}
 
@@ -55,3 +55,60 @@ func TestGetCurrentLeaderboardFailsOnBadData(t *testing.T) {
 		t.Fatalf("expected error, got nil")
 	}
 }
+
+func TestGetSeasonLeaderboard(t *testing.T) {
+	scanner, err := testutils.NewSingleResourceMockScanner(
+		"/data/wow/pvp-season/33/pvp-leaderboard/3v3",
+		validLeaderboard,
+	)
+	if err != nil {
+		t.Fatalf("failed to setup scanner: %v", err)
+	}
+
+	leaderboard, err := GetSeasonLeaderboard(scanner, 33, "3v3", api.RegionUS)
+	if err != nil {
+		t.Fatalf("failed to get leaderboard: %v", err)
+	}
+
+	if leaderboard.Season != 33 {
+		t.Fatalf("expected season 33, got %d", leaderboard.Season)
+	}
+
+	if len(leaderboard.Entries) != 5009 {
+		t.Fatalf("expected 5009 entries, got %d", len(leaderboard.Entries))
+	}
+}
+
+func TestLeaderboardParsesMatchStatistics(t *testing.T) {
+	scanner, err := newLeaderboardMockScanner(validLeaderboard)
+	if err != nil {
+		t.Fatalf("failed to setup scanner: %v", err)
+	}
+
+	leaderboard, err := GetCurrentLeaderboard(scanner, "3v3", api.RegionUS)
+	if err != nil {
+		t.Fatalf("failed to get leaderboard: %v", err)
+	}
+
+	entry := leaderboard.Entries[0]
+	if entry.Rank != 1 {
+		t.Fatalf("expected rank 1, got %d", entry.Rank)
+	}
+	if entry.TierId != 14 {
+		t.Fatalf("expected tier 14, got %d", entry.TierId)
+	}
+	stats := entry.MatchStatistics
+	if stats.Played != 552 || stats.Won != 323 || stats.Lost != 229 {
+		t.Fatalf("expected 552/323/229 played/won/lost, got %d/%d/%d", stats.Played, stats.Won, stats.Lost)
+	}
+
+	filtered := leaderboard.FilterByMinGamesPlayed(100)
+	if len(filtered.Entries) != 4740 {
+		t.Fatalf("expected 4740 entries with at least 100 games, got %d", len(filtered.Entries))
+	}
+
+	filtered = leaderboard.FilterByMinWinRate(0.6)
+	if len(filtered.Entries) != 553 {
+		t.Fatalf("expected 553 entries with at least 60%% win rate, got %d", len(filtered.Entries))
+	}
+}
@@ -2,11 +2,22 @@ package talents
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/crbednarz/moonkinmetrics/pkg/scan"
 	"github.com/crbednarz/moonkinmetrics/pkg/wow"
 )
 
+// apexMatch is an apex node along with the talents which hold its later
+// ranks.
+type apexMatch struct {
+	Node *wow.TalentNode
+	// Middle holds ranks 2 & 3 of the apex talent.
+	Middle talentJson
+	// Last holds rank 4 of the apex talent.
+	Last talentJson
+}
+
 // attachApexTalents constructs ApexTalents field on provied trees:
 // tree.ApexTalents = {Rank1Talent, Rank2And3Talent, Rank4Talent}
 // Additionally, these talents are merged into a single 4-rank talent
@@ -22,79 +33,268 @@ func attachApexTalents(scanner *scan.Scanner, trees []wow.TalentTree) error {
 		return fmt.Errorf("apex talent correction failed during talents index construction: %w", err)
 	}
 
-	// Search talents index for anything matching the name of an existing apex talent
-	baseApexNodes := make([]*wow.TalentNode, len(trees))
-	apexNameMap := make(map[string]bool)
+	components, err := getApexComponents(scanner, talentsIndex, trees)
+	if err != nil {
+		return err
+	}
+
 	for i := range trees {
 		tree := &trees[i]
-		apex := findApexNodeFromTree(tree)
-		if apex == nil {
-			return fmt.Errorf("can't find apex talent for %s - %s", tree.ClassName, tree.SpecName)
+		match, err := findApexMatch(tree, components)
+		if err != nil {
+			return err
 		}
+		applyApexMatch(tree, match)
+	}
+
+	return nil
+}
 
-		tree.ApexTalents = make([]wow.Talent, 3)
-		tree.ApexTalents[0] = apex.Talents[0]
-		apexNameMap[apex.Talents[0].Name] = true
-		baseApexNodes[i] = apex
+// apexComponentReach is how far apart, in talent ids, neighbouring apex
+// talents may be.
+const apexComponentReach = 2
+
+// getApexComponents retrieves every talent from the talents index which could
+// hold the later ranks of an apex talent. The index only lists ids and names,
+// and the API doesn't link components to their base talent, but all apex
+// talents were added together as one block of ids. The block is walked
+// outwards from each candidate apex node, through index entries outside of any
+// tree which belong to a spec and have one or two ranks.
+func getApexComponents(scanner *scan.Scanner, talentsIndex *TalentsIndex, trees []wow.TalentTree) ([]talentJson, error) {
+	treeTalentIds := make(map[int]bool)
+	specIds := make(map[int]bool)
+	frontier := make([]int, 0, len(trees))
+	for i := range trees {
+		tree := &trees[i]
+		specIds[tree.SpecId] = true
+		for _, nodes := range treeNodeSections(tree) {
+			for _, node := range nodes {
+				for _, talent := range node.Talents {
+					treeTalentIds[talent.Id] = true
+				}
+			}
+		}
+		for _, node := range findApexCandidates(tree) {
+			frontier = append(frontier, node.Talents[0].Id)
+		}
 	}
 
-	possibleApexTalentIds := make([]int, 0, len(trees)*3)
+	unusedIds := make(map[int]bool)
 	for _, item := range talentsIndex.Talents {
-		_, ok := apexNameMap[item.Name]
-		if ok {
-			possibleApexTalentIds = append(possibleApexTalentIds, item.Id)
+		if !treeTalentIds[item.Id] {
+			unusedIds[item.Id] = true
 		}
 	}
-	possibleApexTalents, err := getTalentsJsonFromIds(scanner, possibleApexTalentIds)
-	if err != nil {
-		return fmt.Errorf("failed to query potential apex talents: %w", err)
-	}
 
-	for i := range trees {
-		tree := &trees[i]
-		apexNode := baseApexNodes[i]
-		baseRank := apexNode.Talents[0].Spell.Ranks[0]
-		apexNode.MaxRank = 4
-		ranks := []wow.Rank{
-			baseRank,
-			baseRank,
-			baseRank,
-			baseRank,
-		}
-		apexNode.Talents[0].Spell.Ranks = ranks
-
-		for _, talent := range possibleApexTalents {
-			if talent.Spell.Name != apexNode.Talents[0].Name {
-				continue
+	visited := make(map[int]bool)
+	components := make([]talentJson, 0, len(trees)*2)
+	for len(frontier) > 0 {
+		talentIds := make([]int, 0)
+		for _, id := range frontier {
+			for neighbour := id - apexComponentReach; neighbour <= id+apexComponentReach; neighbour++ {
+				if unusedIds[neighbour] && !visited[neighbour] {
+					visited[neighbour] = true
+					talentIds = append(talentIds, neighbour)
+				}
 			}
-			if talent.Id == apexNode.Id {
+		}
+
+		talentsJson, err := getTalentsJsonFromIds(scanner, talentIds)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query potential apex talents: %w", err)
+		}
+
+		frontier = make([]int, 0, len(talentsJson))
+		for _, id := range talentIds {
+			talent, ok := talentsJson[id]
+			if !ok || !isApexComponentShape(talent, specIds) {
 				continue
 			}
+			components = append(components, talent)
+			frontier = append(frontier, id)
+		}
+	}
 
-			if len(talent.RankDescriptions) == 2 {
-				tree.ApexTalents[1] = parseTalentJson(talent)
-				ranks[1].Description = talent.RankDescriptions[0].Description
-				ranks[2].Description = talent.RankDescriptions[1].Description
-			} else {
-				tree.ApexTalents[2] = parseTalentJson(talent)
-				ranks[3].Description = talent.RankDescriptions[0].Description
-			}
+	sort.Slice(components, func(i, j int) bool {
+		return components[i].Id < components[j].Id
+	})
+	return components, nil
+}
+
+// isApexComponentShape reports whether a talent could hold later ranks of an
+// apex talent for one of the given specs.
+func isApexComponentShape(talent talentJson, specIds map[int]bool) bool {
+	if talent.PlayableSpecialization == nil || !specIds[talent.PlayableSpecialization.Id] {
+		return false
+	}
+	ranks := len(talent.RankDescriptions)
+	return ranks == 1 || ranks == 2
+}
+
+// findApexMatch identifies the apex node of a tree from the structure of its
+// components. Exactly one candidate node must have a complete set of
+// components, otherwise the tree is rejected rather than guessed at.
+func findApexMatch(tree *wow.TalentTree, components []talentJson) (apexMatch, error) {
+	matches := make([]apexMatch, 0, 1)
+	for _, node := range findApexCandidates(tree) {
+		match, ok, err := matchApexComponents(tree, node, components)
+		if err != nil {
+			return apexMatch{}, err
+		}
+		if ok {
+			matches = append(matches, match)
 		}
 	}
 
-	return nil
+	switch len(matches) {
+	case 0:
+		return apexMatch{}, fmt.Errorf("can't find apex talent for %s - %s", tree.ClassName, tree.SpecName)
+	case 1:
+		return matches[0], nil
+	default:
+		ids := make([]int, len(matches))
+		for i, match := range matches {
+			ids[i] = match.Node.Id
+		}
+		return apexMatch{}, fmt.Errorf("ambiguous apex talent for %s - %s: nodes %v", tree.ClassName, tree.SpecName, ids)
+	}
+}
+
+// findApexCandidates returns spec nodes shaped like the base of an apex talent:
+// a single rank of a single talent, outside of the tree's prerequisites. Apex
+// talents are gated only by points spent, so nothing leads to or from them.
+func findApexCandidates(tree *wow.TalentTree) []*wow.TalentNode {
+	candidates := make([]*wow.TalentNode, 0)
+	for i := range tree.SpecNodes {
+		node := &tree.SpecNodes[i]
+		if node.NodeType == "CHOICE" || len(node.Talents) != 1 {
+			continue
+		}
+		if len(node.LockedBy) != 0 || len(node.Unlocks) != 0 {
+			continue
+		}
+		if node.MaxRank != 1 || len(node.Talents[0].Spell.Ranks) != 1 {
+			continue
+		}
+		candidates = append(candidates, node)
+	}
+	return candidates
 }
 
-func findApexNodeFromTree(tree *wow.TalentTree) *wow.TalentNode {
-	// Annoyingly, I don't see any other way to identify an apex talent without
-	// hardcoding the names.
-	lowestNode := &tree.SpecNodes[0]
+// matchApexComponents finds the talents holding ranks 2 through 4 of a
+// candidate node. Components belong to the tree's spec, descend from the
+// base talent's spell, and are split into one 2-rank and one 1-rank talent
+// with adjacent ids. More than one talent of either shape is an error, as
+// there's no way to tell which is current.
+func matchApexComponents(tree *wow.TalentTree, node *wow.TalentNode, components []talentJson) (apexMatch, bool, error) {
+	base := node.Talents[0]
+	middle := make([]talentJson, 0, 1)
+	last := make([]talentJson, 0, 1)
+	for _, talent := range components {
+		if talent.Id == base.Id {
+			continue
+		}
+		if talent.PlayableSpecialization == nil || talent.PlayableSpecialization.Id != tree.SpecId {
+			continue
+		}
+		if !isSpellRank(base.Spell, talent) {
+			continue
+		}
 
+		switch len(talent.RankDescriptions) {
+		case 2:
+			middle = append(middle, talent)
+		case 1:
+			last = append(last, talent)
+		}
+	}
+
+	if len(middle) == 0 && len(last) == 0 {
+		return apexMatch{}, false, nil
+	}
+	if len(middle) != 1 || len(last) != 1 {
+		return apexMatch{}, false, fmt.Errorf(
+			"unexpected apex components for node %d in %s - %s: %d with 2 ranks, %d with 1 rank",
+			node.Id,
+			tree.ClassName,
+			tree.SpecName,
+			len(middle),
+			len(last),
+		)
+	}
+	if middle[0].Id-last[0].Id != 1 && last[0].Id-middle[0].Id != 1 {
+		return apexMatch{}, false, fmt.Errorf(
+			"apex components %d and %d for node %d in %s - %s aren't adjacent",
+			middle[0].Id,
+			last[0].Id,
+			node.Id,
+			tree.ClassName,
+			tree.SpecName,
+		)
+	}
+
+	return apexMatch{
+		Node:   node,
+		Middle: middle[0],
+		Last:   last[0],
+	}, true, nil
+}
+
+// isSpellRank reports whether a talent holds later ranks of a spell. Each rank
+// of an apex talent is a spell of its own, but keeps the name of the first
+// rank's spell, which is the only link the API exposes between them.
+func isSpellRank(spell wow.Spell, talent talentJson) bool {
+	return talent.Spell.Id == spell.Id || talent.Spell.Name == spell.Name
+}
+
+// applyApexMatch merges an apex talent's components into its node, and
+// records each component on the tree.
+func applyApexMatch(tree *wow.TalentTree, match apexMatch) {
+	apexNode := match.Node
+	baseRank := apexNode.Talents[0].Spell.Ranks[0]
+	ranks := []wow.Rank{
+		baseRank,
+		baseRank,
+		baseRank,
+		baseRank,
+	}
+	ranks[1].Description = match.Middle.RankDescriptions[0].Description
+	ranks[2].Description = match.Middle.RankDescriptions[1].Description
+	ranks[3].Description = match.Last.RankDescriptions[0].Description
+
+	tree.ApexTalents = make([]wow.Talent, 3)
+	tree.ApexTalents[0] = apexNode.Talents[0]
+
+	// The later ranks aren't part of any tree, so they never had their
+	// media attached. They share the base talent's icon in game.
+	tree.ApexTalents[1] = parseTalentJson(match.Middle)
+	tree.ApexTalents[1].Icon = tree.ApexTalents[0].Icon
+	tree.ApexTalents[2] = parseTalentJson(match.Last)
+	tree.ApexTalents[2].Icon = tree.ApexTalents[0].Icon
+
+	apexNode.MaxRank = 4
+	apexNode.Talents[0].Spell.Ranks = ranks
+}
+
+// findApexNode returns the node apex talents were merged into, or nil if the
+// tree has no apex talents attached.
+func findApexNode(tree *wow.TalentTree) *wow.TalentNode {
+	if len(tree.ApexTalents) == 0 {
+		return nil
+	}
 	for i := range tree.SpecNodes {
 		node := &tree.SpecNodes[i]
-		if node.Y > lowestNode.Y {
-			lowestNode = node
+		if len(node.Talents) == 1 && node.Talents[0].Id == tree.ApexTalents[0].Id {
+			return node
 		}
 	}
-	return lowestNode
+	return nil
+}
+
+func treeNodeSections(tree *wow.TalentTree) [][]wow.TalentNode {
+	sections := [][]wow.TalentNode{tree.ClassNodes, tree.SpecNodes}
+	for _, heroTree := range tree.HeroTrees {
+		sections = append(sections, heroTree.Nodes)
+	}
+	return sections
 }
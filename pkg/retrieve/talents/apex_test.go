@@ -0,0 +1,253 @@
+package talents
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/testutils"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+// expectedApexTalents is the base, ranks 2&3, and rank 4 talent ids for each
+// spec in the test data.
+var expectedApexTalents = map[int][3]int{
+	62:   {141791, 141790, 141789}, // Arcane Mage
+	63:   {141800, 141799, 141798}, // Fire Mage
+	64:   {141797, 141796, 141795}, // Frost Mage
+	65:   {141782, 141781, 141780}, // Holy Paladin
+	66:   {141785, 141784, 141783}, // Protection Paladin
+	70:   {141788, 141787, 141786}, // Retribution Paladin
+	71:   {141752, 141751, 141750}, // Arms Warrior
+	72:   {141767, 141766, 141765}, // Fury Warrior
+	73:   {141764, 141763, 141762}, // Protection Warrior
+	102:  {141794, 141793, 141792}, // Balance Druid
+	103:  {141809, 141808, 141807}, // Feral Druid
+	104:  {141824, 141823, 141822}, // Guardian Druid
+	105:  {141803, 141802, 141801}, // Restoration Druid
+	250:  {141680, 141679, 141678}, // Blood Death Knight
+	251:  {141731, 141730, 141729}, // Frost Death Knight
+	252:  {141683, 141682, 141681}, // Unholy Death Knight
+	253:  {141815, 141814, 141813}, // Beast Mastery Hunter
+	254:  {141821, 141820, 141819}, // Marksmanship Hunter
+	255:  {141818, 141817, 141816}, // Survival Hunter
+	256:  {141761, 141760, 141759}, // Discipline Priest
+	257:  {141758, 141757, 141756}, // Holy Priest
+	258:  {141755, 141754, 141753}, // Shadow Priest
+	259:  {141831, 141830, 141829}, // Assassination Rogue
+	260:  {141834, 141833, 141832}, // Outlaw Rogue
+	261:  {141827, 141826, 141825}, // Subtlety Rogue
+	262:  {141737, 141736, 141735}, // Elemental Shaman
+	263:  {141734, 141733, 141732}, // Enhancement Shaman
+	264:  {141740, 141739, 141738}, // Restoration Shaman
+	265:  {141746, 141745, 141744}, // Affliction Warlock
+	266:  {141743, 141742, 141741}, // Demonology Warlock
+	267:  {141749, 141748, 141747}, // Destruction Warlock
+	268:  {141839, 141838, 141837}, // Brewmaster Monk
+	269:  {141840, 141841, 141842}, // Windwalker Monk
+	270:  {140504, 141836, 141835}, // Mistweaver Monk
+	577:  {141812, 141811, 141810}, // Havoc Demon Hunter
+	581:  {141806, 141805, 141804}, // Vengeance Demon Hunter
+	1467: {141773, 141772, 141771}, // Devastation Evoker
+	1468: {141770, 141769, 141768}, // Preservation Evoker
+	1473: {141776, 141775, 141774}, // Augmentation Evoker
+	1480: {141779, 141778, 141777}, // Devourer Demon Hunter
+}
+
+func TestApexTalentsMatchEverySpec(t *testing.T) {
+	scanner, err := testutils.NewMockTalentScanner()
+	if err != nil {
+		t.Fatalf("failed to setup scanner: %v", err)
+	}
+
+	trees, err := GetTalentTrees(scanner)
+	if err != nil {
+		t.Fatalf("failed to get talent trees: %v", err)
+	}
+
+	if len(trees) != len(expectedApexTalents) {
+		t.Fatalf("expected %d trees, got %d", len(expectedApexTalents), len(trees))
+	}
+
+	for _, tree := range trees {
+		expected, ok := expectedApexTalents[tree.SpecId]
+		if !ok {
+			t.Errorf("unexpected spec %d", tree.SpecId)
+			continue
+		}
+
+		actual := [3]int{}
+		for i, talent := range tree.ApexTalents {
+			actual[i] = talent.Id
+		}
+		if actual != expected {
+			t.Errorf("expected apex talents %v for %s - %s, got %v", expected, tree.ClassName, tree.SpecName, actual)
+		}
+
+		apexNode := findApexNode(&tree)
+		if apexNode == nil {
+			t.Errorf("expected apex node for %s - %s", tree.ClassName, tree.SpecName)
+			continue
+		}
+		ranks := apexNode.Talents[0].Spell.Ranks
+		if len(ranks) != 4 {
+			t.Errorf("expected 4 apex ranks for %s - %s, got %d", tree.ClassName, tree.SpecName, len(ranks))
+			continue
+		}
+		for i := 1; i < len(ranks); i++ {
+			if ranks[i].Description == "" || ranks[i].Description == ranks[0].Description {
+				t.Errorf("expected distinct description for rank %d of %s", i+1, apexNode.Talents[0].Name)
+			}
+		}
+	}
+}
+
+func mockApexTree() *wow.TalentTree {
+	node := func(id int, talentId int, spellId int, name string, lockedBy []int, unlocks []int) wow.TalentNode {
+		return wow.TalentNode{
+			Id:       id,
+			NodeType: "ACTIVE",
+			MaxRank:  1,
+			LockedBy: lockedBy,
+			Unlocks:  unlocks,
+			Talents: []wow.Talent{{
+				Id:   talentId,
+				Name: name,
+				Spell: wow.Spell{
+					Id:    spellId,
+					Name:  name,
+					Ranks: []wow.Rank{{Description: name}},
+				},
+			}},
+		}
+	}
+	return &wow.TalentTree{
+		ClassName: "Druid",
+		SpecName:  "Balance",
+		SpecId:    102,
+		SpecNodes: []wow.TalentNode{
+			node(1, 10, 100, "Root", nil, []int{2}),
+			node(2, 20, 200, "Leaf", []int{1}, nil),
+			node(3, 30, 300, "Apex", nil, nil),
+		},
+	}
+}
+
+func mockApexComponent(t *testing.T, id int, specId int, spellId int, name string, ranks int) talentJson {
+	t.Helper()
+	descriptions := make([]string, ranks)
+	for i := range descriptions {
+		descriptions[i] = fmt.Sprintf(`{"rank": %d, "description": "%s %d"}`, i+1, name, i+1)
+	}
+	raw := fmt.Sprintf(
+		`{"id": %d, "rank_descriptions": [%s], "spell": {"id": %d, "name": "%s"}, "playable_specialization": {"id": %d}}`,
+		id,
+		strings.Join(descriptions, ","),
+		spellId,
+		name,
+		specId,
+	)
+	var talent talentJson
+	if err := json.Unmarshal([]byte(raw), &talent); err != nil {
+		t.Fatalf("failed to build mock talent: %v", err)
+	}
+	return talent
+}
+
+func TestFindApexMatchIgnoresPositionAndTalentNames(t *testing.T) {
+	tree := mockApexTree()
+	// Move the apex node above everything else, and rename its talent.
+	tree.SpecNodes[2].Y = -100
+	tree.SpecNodes[2].Talents[0].Name = "Renamed"
+	components := []talentJson{
+		mockApexComponent(t, 31, 102, 301, "Apex", 2),
+		mockApexComponent(t, 32, 102, 302, "Apex", 1),
+		// Another spec's apex.
+		mockApexComponent(t, 33, 103, 303, "Apex", 1),
+	}
+
+	match, err := findApexMatch(tree, components)
+	if err != nil {
+		t.Fatalf("failed to find apex: %v", err)
+	}
+	if match.Node.Id != 3 || match.Middle.Id != 31 || match.Last.Id != 32 {
+		t.Fatalf("expected node 3 with talents 31 and 32, got %d with %d and %d", match.Node.Id, match.Middle.Id, match.Last.Id)
+	}
+}
+
+func TestFindApexMatchFollowsSpellAncestry(t *testing.T) {
+	components := []talentJson{
+		// Talents of the spec shaped like apex components, from other spells.
+		mockApexComponent(t, 28, 102, 298, "Root", 2),
+		mockApexComponent(t, 29, 102, 299, "Leaf", 1),
+		// The apex talent's later ranks. One shares the base talent's spell,
+		// the other is a spell of its own with the same name.
+		mockApexComponent(t, 31, 102, 300, "Apex", 2),
+		mockApexComponent(t, 32, 102, 302, "Apex", 1),
+		mockApexComponent(t, 33, 102, 303, "Unrelated", 1),
+	}
+
+	match, err := findApexMatch(mockApexTree(), components)
+	if err != nil {
+		t.Fatalf("failed to find apex: %v", err)
+	}
+	if match.Middle.Id != 31 || match.Last.Id != 32 {
+		t.Fatalf("expected talents 31 and 32, got %d and %d", match.Middle.Id, match.Last.Id)
+	}
+}
+
+func TestFindApexMatchFailsWithDistantComponents(t *testing.T) {
+	components := []talentJson{
+		mockApexComponent(t, 31, 102, 301, "Apex", 2),
+		mockApexComponent(t, 35, 102, 305, "Apex", 1),
+	}
+
+	_, err := findApexMatch(mockApexTree(), components)
+	if err == nil || !strings.Contains(err.Error(), "adjacent") {
+		t.Fatalf("expected error for components which aren't adjacent, got %v", err)
+	}
+}
+
+func TestFindApexMatchFailsWithoutComponents(t *testing.T) {
+	components := []talentJson{
+		mockApexComponent(t, 31, 103, 301, "Apex", 2),
+		mockApexComponent(t, 32, 103, 302, "Apex", 1),
+	}
+
+	_, err := findApexMatch(mockApexTree(), components)
+	if err == nil {
+		t.Fatalf("expected error for tree without apex components")
+	}
+}
+
+func TestFindApexMatchFailsWithDuplicateComponents(t *testing.T) {
+	components := []talentJson{
+		mockApexComponent(t, 31, 102, 301, "Apex", 2),
+		mockApexComponent(t, 32, 102, 302, "Apex", 1),
+		mockApexComponent(t, 33, 102, 303, "Apex", 1),
+	}
+
+	_, err := findApexMatch(mockApexTree(), components)
+	if err == nil {
+		t.Fatalf("expected error for duplicate apex components")
+	}
+}
+
+func TestFindApexMatchFailsWithMultipleApexNodes(t *testing.T) {
+	tree := mockApexTree()
+	// Detach the leaf and give it the apex spell, so it's shaped like the
+	// apex node too.
+	tree.SpecNodes[0].Unlocks = nil
+	tree.SpecNodes[1].LockedBy = nil
+	tree.SpecNodes[1].Talents[0].Spell.Name = "Apex"
+	components := []talentJson{
+		mockApexComponent(t, 31, 102, 301, "Apex", 2),
+		mockApexComponent(t, 32, 102, 302, "Apex", 1),
+	}
+
+	_, err := findApexMatch(tree, components)
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expected ambiguous apex error, got %v", err)
+	}
+}
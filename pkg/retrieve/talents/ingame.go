@@ -3,6 +3,8 @@ package talents
 import (
 	_ "embed"
 	"fmt"
+	"math"
+	"slices"
 	"time"
 
 	"github.com/crbednarz/moonkinmetrics/pkg/api"
@@ -38,7 +40,15 @@ type talentJson struct {
 	} `json:"playable_specialization"`
 }
 
-func talentTreeFromIngame(scanner *scan.Scanner, ingameTree hack.IngameTree) (wow.TalentTree, error) {
+// Ingame node positions are laid out on a grid of this many units per row and
+// column. Nodes are occasionally nudged a few units off the grid, so positions
+// are snapped before being divided.
+const (
+	ingameGridSize = 600
+	ingameGridSnap = 10
+)
+
+func talentTreeFromIngame(scanner *scan.Scanner, ingameTree hack.IngameTree, knownNodeTypes map[int]string) (wow.TalentTree, error) {
 	talentIds := getAllTalentIds(ingameTree)
 	talentsJson, err := getTalentsJsonFromIds(scanner, talentIds)
 	if err != nil {
@@ -47,36 +57,52 @@ func talentTreeFromIngame(scanner *scan.Scanner, ingameTree hack.IngameTree) (wo
 
 	specNodes := make([]wow.TalentNode, 0, len(ingameTree.Nodes))
 	classNodes := make([]wow.TalentNode, 0, len(ingameTree.Nodes))
+	unlocks := getIngameUnlocks(ingameTree)
+	originRow, originCol := getIngameGridOrigin(ingameTree)
 
 	for _, ingameNode := range ingameTree.Nodes {
 		talents := make([]wow.Talent, 0, len(ingameNode.TalentIds))
 		isSpecNode := false
+		isOtherSpecNode := false
 		for _, talentId := range ingameNode.TalentIds {
 			talent, ok := talentsJson[talentId]
 			if !ok {
 				return wow.TalentTree{}, fmt.Errorf("talent %d not found", talentId)
 			}
 			talents = append(talents, parseTalentJson(talent))
-			isSpecNode = isSpecNode || talent.PlayableSpecialization != nil
+			if talent.PlayableSpecialization != nil {
+				isSpecNode = true
+				isOtherSpecNode = isOtherSpecNode || talent.PlayableSpecialization.Id != ingameTree.SpecId
+			}
+		}
+		// Trees converted from client data include the nodes of every spec
+		// of the class.
+		if isOtherSpecNode {
+			continue
+		}
+		lockedBy := ingameNode.LockedBy
+		if lockedBy == nil {
+			lockedBy = []int{}
 		}
 		node := wow.TalentNode{
 			Id:       ingameNode.Id,
 			X:        ingameNode.PosX,
 			Y:        ingameNode.PosY,
-			Row:      0,
-			Col:      0,
-			Unlocks:  make([]int, 0),
-			LockedBy: ingameNode.LockedBy,
+			Row:      ingameGridIndex(ingameNode.PosY) - originRow + 1,
+			Col:      ingameGridIndex(ingameNode.PosX) - originCol + 1,
+			Unlocks:  unlocks[ingameNode.Id],
+			LockedBy: lockedBy,
 			MaxRank:  len(talents[0].Spell.Ranks),
-			NodeType: "MISSING",
 			Talents:  talents,
 		}
+		node.NodeType = inferIngameNodeType(&node, ingameNode.Choice, knownNodeTypes)
 		if isSpecNode {
 			specNodes = append(specNodes, node)
 		} else {
 			classNodes = append(classNodes, node)
 		}
 	}
+	removeDroppedUnlocks(classNodes, specNodes)
 	return wow.TalentTree{
 		ClassName:  ingameTree.ClassName,
 		SpecName:   ingameTree.SpecName,
@@ -88,6 +114,93 @@ func talentTreeFromIngame(scanner *scan.Scanner, ingameTree hack.IngameTree) (wo
 	}, nil
 }
 
+// getIngameUnlocks inverts each node's LockedBy, as ingame data only records
+// prerequisites. Nodes are listed in tree order.
+func getIngameUnlocks(ingameTree hack.IngameTree) map[int][]int {
+	unlocks := make(map[int][]int, len(ingameTree.Nodes))
+	for _, node := range ingameTree.Nodes {
+		unlocks[node.Id] = []int{}
+	}
+	for _, node := range ingameTree.Nodes {
+		for _, lockedById := range node.LockedBy {
+			if _, ok := unlocks[lockedById]; ok {
+				unlocks[lockedById] = append(unlocks[lockedById], node.Id)
+			}
+		}
+	}
+	return unlocks
+}
+
+// removeDroppedUnlocks removes unlocks for nodes which were dropped from the
+// tree, such as those of other specs.
+func removeDroppedUnlocks(sections ...[]wow.TalentNode) {
+	kept := make(map[int]bool)
+	for _, nodes := range sections {
+		for _, node := range nodes {
+			kept[node.Id] = true
+		}
+	}
+	for _, nodes := range sections {
+		for i := range nodes {
+			nodes[i].Unlocks = slices.DeleteFunc(nodes[i].Unlocks, func(nodeId int) bool {
+				return !kept[nodeId]
+			})
+		}
+	}
+}
+
+// getIngameGridOrigin finds the top left cell of the tree. Class and spec
+// nodes share a single grid, so that the two halves line up as they do in game.
+func getIngameGridOrigin(ingameTree hack.IngameTree) (int, int) {
+	if len(ingameTree.Nodes) == 0 {
+		return 0, 0
+	}
+	originRow := ingameGridIndex(ingameTree.Nodes[0].PosY)
+	originCol := ingameGridIndex(ingameTree.Nodes[0].PosX)
+	for _, node := range ingameTree.Nodes[1:] {
+		originRow = min(originRow, ingameGridIndex(node.PosY))
+		originCol = min(originCol, ingameGridIndex(node.PosX))
+	}
+	return originRow, originCol
+}
+
+func ingameGridIndex(position int) int {
+	return int(math.Floor(float64(position+ingameGridSnap) / ingameGridSize))
+}
+
+// inferIngameNodeType determines a node's type. Choice nodes are marked in the
+// ingame data, but whether a talent is active isn't part of the talent data, so
+// other types are taken from the same talent in trees retrieved from the
+// Battle.net API where possible. Otherwise, the node is assumed to be passive,
+// as most are.
+func inferIngameNodeType(node *wow.TalentNode, choice bool, knownNodeTypes map[int]string) string {
+	if choice {
+		return "CHOICE"
+	}
+	if nodeType, ok := knownNodeTypes[node.Talents[0].Id]; ok {
+		return nodeType
+	}
+	return "PASSIVE"
+}
+
+// getKnownNodeTypes maps each talent in the provided trees to the type of
+// the node it belongs to. Choice nodes are skipped, as their talents may
+// appear on their own elsewhere.
+func getKnownNodeTypes(trees []wow.TalentTree) map[int]string {
+	nodeTypes := make(map[int]string)
+	for i := range trees {
+		for _, nodes := range treeNodeSections(&trees[i]) {
+			for _, node := range nodes {
+				if len(node.Talents) != 1 || node.NodeType == "" {
+					continue
+				}
+				nodeTypes[node.Talents[0].Id] = node.NodeType
+			}
+		}
+	}
+	return nodeTypes
+}
+
 func parseTalentJson(talent talentJson) wow.Talent {
 	ranks := make([]wow.Rank, 0, len(talent.RankDescriptions))
 	for _, rank := range talent.RankDescriptions {
@@ -3,17 +3,20 @@ package talents
 import (
 	_ "embed"
 	"fmt"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/crbednarz/moonkinmetrics/pkg/hack"
+	"github.com/crbednarz/moonkinmetrics/pkg/scan"
 	"github.com/crbednarz/moonkinmetrics/pkg/testutils"
 )
 
 //go:embed testdata/valid-talent.json
 var validTalent string
 
-func TestParseIngameTalentTree(t *testing.T) {
+func newIngameTalentScanner(t *testing.T) *scan.Scanner {
+	t.Helper()
 	scanner, err := testutils.NewMockScanner(func(requestPath string) (string, bool) {
 		if strings.HasPrefix(requestPath, "/data/wow/talent/") {
 			id := strings.TrimPrefix(requestPath, "/data/wow/talent/")
@@ -24,13 +27,18 @@ func TestParseIngameTalentTree(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to setup scanner: %v", err)
 	}
+	return scanner
+}
+
+func TestParseIngameTalentTree(t *testing.T) {
+	scanner := newIngameTalentScanner(t)
 
 	ingameTrees := hack.GetIngameTrees()
 
 	for _, ingameTree := range ingameTrees {
 		name := fmt.Sprintf("%s %s", ingameTree.ClassName, ingameTree.SpecName)
 		t.Run(name, func(t *testing.T) {
-			tree, err := talentTreeFromIngame(scanner, ingameTree)
+			tree, err := talentTreeFromIngame(scanner, ingameTree, nil)
 			if err != nil {
 				t.Fatalf("failed to parse talent tree: %v", err)
 			}
@@ -61,3 +69,93 @@ func TestParseIngameTalentTree(t *testing.T) {
 		})
 	}
 }
+
+func TestIngameTreeLayout(t *testing.T) {
+	scanner := newIngameTalentScanner(t)
+	ingameTree := hack.IngameTree{
+		ClassName: "Druid",
+		ClassId:   11,
+		SpecName:  "Restoration",
+		SpecId:    105,
+		Nodes: []hack.IngameNode{
+			{Id: 1, PosX: 1800, PosY: 1500, TalentIds: []int{10}},
+			{Id: 2, PosX: 1200, PosY: 2100, TalentIds: []int{20}, LockedBy: []int{1}},
+			{Id: 3, PosX: 2400, PosY: 2099, TalentIds: []int{30, 31}, LockedBy: []int{1}, Choice: true},
+			{Id: 4, PosX: 1800, PosY: 2697, TalentIds: []int{40}, LockedBy: []int{2, 3}},
+		},
+	}
+
+	tree, err := talentTreeFromIngame(scanner, ingameTree, map[int]string{10: "ACTIVE"})
+	if err != nil {
+		t.Fatalf("failed to parse talent tree: %v", err)
+	}
+
+	expected := []struct {
+		Row      int
+		Col      int
+		Unlocks  []int
+		NodeType string
+	}{
+		{Row: 1, Col: 2, Unlocks: []int{2, 3}, NodeType: "ACTIVE"},
+		{Row: 2, Col: 1, Unlocks: []int{4}, NodeType: "PASSIVE"},
+		{Row: 2, Col: 3, Unlocks: []int{4}, NodeType: "CHOICE"},
+		{Row: 3, Col: 2, Unlocks: []int{}, NodeType: "PASSIVE"},
+	}
+
+	if len(tree.SpecNodes) != len(expected) {
+		t.Fatalf("expected %d spec nodes, got %d", len(expected), len(tree.SpecNodes))
+	}
+	for i, node := range tree.SpecNodes {
+		if node.Row != expected[i].Row || node.Col != expected[i].Col {
+			t.Errorf("expected node %d at %d,%d, got %d,%d", node.Id, expected[i].Row, expected[i].Col, node.Row, node.Col)
+		}
+		if !slices.Equal(node.Unlocks, expected[i].Unlocks) {
+			t.Errorf("expected node %d to unlock %v, got %v", node.Id, expected[i].Unlocks, node.Unlocks)
+		}
+		if node.NodeType != expected[i].NodeType {
+			t.Errorf("expected node %d to be %s, got %s", node.Id, expected[i].NodeType, node.NodeType)
+		}
+	}
+}
+
+func TestIngameTreeDropsOtherSpecs(t *testing.T) {
+	scanner, err := testutils.NewMockScanner(func(requestPath string) (string, bool) {
+		if !strings.HasPrefix(requestPath, "/data/wow/talent/") {
+			return "", false
+		}
+		id := strings.TrimPrefix(requestPath, "/data/wow/talent/")
+		talent := strings.ReplaceAll(validTalent, "108105", id)
+		// Talents from 100 onward belong to Feral rather than Restoration.
+		if len(id) > 2 {
+			talent = strings.ReplaceAll(talent, `"id": 105`, `"id": 103`)
+		}
+		return talent, true
+	})
+	if err != nil {
+		t.Fatalf("failed to setup scanner: %v", err)
+	}
+
+	ingameTree := hack.IngameTree{
+		ClassName: "Druid",
+		ClassId:   11,
+		SpecName:  "Restoration",
+		SpecId:    105,
+		Nodes: []hack.IngameNode{
+			{Id: 1, PosX: 600, PosY: 600, TalentIds: []int{10}},
+			{Id: 2, PosX: 600, PosY: 1200, TalentIds: []int{20}, LockedBy: []int{1}},
+			{Id: 3, PosX: 1200, PosY: 1200, TalentIds: []int{300}, LockedBy: []int{1}},
+		},
+	}
+
+	tree, err := talentTreeFromIngame(scanner, ingameTree, nil)
+	if err != nil {
+		t.Fatalf("failed to parse talent tree: %v", err)
+	}
+
+	if len(tree.SpecNodes) != 2 {
+		t.Fatalf("expected 2 spec nodes, got %d", len(tree.SpecNodes))
+	}
+	if !slices.Equal(tree.SpecNodes[0].Unlocks, []int{2}) {
+		t.Errorf("expected node 1 to unlock [2], got %v", tree.SpecNodes[0].Unlocks)
+	}
+}
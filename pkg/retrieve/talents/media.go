@@ -98,7 +98,73 @@ func countTalents(trees []wow.TalentTree) int {
 		for _, node := range tree.SpecNodes {
 			count += len(node.Talents)
 		}
+		for _, heroTree := range tree.HeroTrees {
+			for _, node := range heroTree.Nodes {
+				count += len(node.Talents)
+			}
+		}
 		count += len(tree.PvpTalents)
 	}
 	return count
 }
+
+type heroTreeMediaJson struct {
+	Assets []assetJson `json:"assets"`
+}
+
+// GetHeroTreeMedia retrieves the icon of every hero tree, keyed by the hero
+// tree's media url. Hero trees shared between specs are only retrieved once.
+func GetHeroTreeMedia(scanner *scan.Scanner, trees []wow.TalentTree) (map[string]string, error) {
+	mediaUrls := make([]string, 0)
+	urlsSeen := make(map[string]bool)
+	for _, tree := range trees {
+		for _, heroTree := range tree.HeroTrees {
+			if !urlsSeen[heroTree.MediaUrl] {
+				urlsSeen[heroTree.MediaUrl] = true
+				mediaUrls = append(mediaUrls, heroTree.MediaUrl)
+			}
+		}
+	}
+
+	requests := make(chan api.Request, len(mediaUrls))
+	results := make(chan scan.ScanResult[heroTreeMediaJson], len(mediaUrls))
+	options := scan.ScanOptions[heroTreeMediaJson]{
+		Validator: nil,
+		Lifespan:  time.Hour * 24 * 7,
+	}
+	scan.Scan(scanner, requests, results, &options)
+	for _, url := range mediaUrls {
+		request, err := api.RequestFromUrl(url)
+		if err != nil {
+			close(requests)
+			return nil, fmt.Errorf("failed to create request from hero tree media link [%v]: %w", url, err)
+		}
+		requests <- &request
+	}
+	close(requests)
+
+	mediaDict := make(map[string]string, len(mediaUrls))
+	var resultErr error
+	for result := range results {
+		if result.Error != nil {
+			resultErr = result.Error
+			continue
+		}
+
+		icon := ""
+		for _, asset := range result.Response.Assets {
+			if asset.Key == "icon" {
+				icon = asset.Value
+			}
+		}
+		if icon == "" {
+			resultErr = fmt.Errorf("missing icon asset for hero tree media: %s", mediaUrls[result.Index])
+			continue
+		}
+		mediaDict[mediaUrls[result.Index]] = icon
+	}
+	if resultErr != nil {
+		return nil, resultErr
+	}
+	return mediaDict, nil
+}
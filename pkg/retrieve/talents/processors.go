@@ -1,12 +1,15 @@
 package talents
 
 import (
+	"slices"
+
 	"github.com/crbednarz/moonkinmetrics/pkg/scan"
 )
 
 func getTreeRepairs() []scan.ResultProcessor[talentTreeJson] {
 	return []scan.ResultProcessor[talentTreeJson]{
 		scan.NewResultProcessor(func(treeJson *talentTreeJson) error {
+			takeHeroSelectionNode(treeJson)
 			treeJson.ClassTalentNodes = removeNoDescriptionTalents(treeJson.ClassTalentNodes)
 			treeJson.SpecTalentNodes = removeNoDescriptionTalents(treeJson.SpecTalentNodes)
 			return nil
@@ -17,6 +20,8 @@ func getTreeRepairs() []scan.ResultProcessor[talentTreeJson] {
 func getTreeFilters() []scan.ResultProcessor[talentTreeJson] {
 	return []scan.ResultProcessor[talentTreeJson]{
 		scan.NewResultProcessor(func(treeJson *talentTreeJson) error {
+			takeHeroSelectionNode(treeJson)
+
 			treeJson.ClassTalentNodes = removeHeroTalents(treeJson.ClassTalentNodes, treeJson)
 			treeJson.SpecTalentNodes = removeHeroTalents(treeJson.SpecTalentNodes, treeJson)
 
@@ -98,3 +103,28 @@ func removeNoDescriptionTalents(talentNodes []talentNodeJson) []talentNodeJson {
 
 	return results
 }
+
+// takeHeroSelectionNode removes the node used by the game to record the chosen
+// hero tree from the class nodes. It's a choice node with no tooltips, which
+// would otherwise be dropped as a talent without a description.
+func takeHeroSelectionNode(tree *talentTreeJson) {
+	if len(tree.HeroTalentTrees) == 0 {
+		return
+	}
+
+	for i, node := range tree.ClassTalentNodes {
+		if node.NodeType.Type != "CHOICE" || len(node.Ranks) == 0 {
+			continue
+		}
+		hasTooltip := slices.ContainsFunc(node.Ranks, func(rank rankJson) bool {
+			return rank.Tooltip != nil || len(rank.ChoiceOfTooltips) > 0
+		})
+		if hasTooltip {
+			continue
+		}
+
+		tree.HeroSelectionNodeId = node.Id
+		tree.ClassTalentNodes = slices.Delete(tree.ClassTalentNodes, i, i+1)
+		return
+	}
+}
@@ -16,10 +16,37 @@ import (
 //go:embed schema/talent-tree.schema.json
 var talentTreeSchema string
 
+type talentTreeOptions struct {
+	IngameTrees []hack.IngameTree
+}
+
+type TalentTreeOption interface {
+	apply(*talentTreeOptions)
+}
+
+type ingameTreesOption []hack.IngameTree
+
+func (o ingameTreesOption) apply(options *talentTreeOptions) {
+	options.IngameTrees = mergeIngameTrees(options.IngameTrees, o)
+}
+
+// WithIngameTrees adds ingame trees to those built in, such as those loaded
+// with hack.LoadIngameTrees. A tree replaces any built in tree for its spec.
+func WithIngameTrees(trees []hack.IngameTree) TalentTreeOption {
+	return ingameTreesOption(trees)
+}
+
 // GetTalentTreeIndex retrieves the full talent tree of each spec.
 // If the Battle.net API is missing a spec, fallback mechanisms will be
 // used to retrieve the talent tree, though some information may be missing.
-func GetTalentTrees(scanner *scan.Scanner) ([]wow.TalentTree, error) {
+func GetTalentTrees(scanner *scan.Scanner, opts ...TalentTreeOption) ([]wow.TalentTree, error) {
+	options := talentTreeOptions{
+		IngameTrees: hack.GetIngameTrees(),
+	}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+
 	index, err := GetTalentTreeIndex(scanner)
 	if err != nil {
 		return nil, err
@@ -27,7 +54,7 @@ func GetTalentTrees(scanner *scan.Scanner) ([]wow.TalentTree, error) {
 
 	// If we've already retrieved the ingame representation of a spec, we should
 	// not retrieve it again from the Battle.net API.
-	ingameTrees := hack.GetIngameTrees()
+	ingameTrees := options.IngameTrees
 	specLinks := make([]SpecTreeLink, 0, len(index.SpecLinks))
 	for _, specLink := range index.SpecLinks {
 		found := false
@@ -48,9 +75,10 @@ func GetTalentTrees(scanner *scan.Scanner) ([]wow.TalentTree, error) {
 	}
 
 	// If the Battle.net API is missing a spec, fallback to the ingame talent tree.
+	knownNodeTypes := getKnownNodeTypes(trees)
 	for _, ingameTree := range ingameTrees {
 		log.Printf("Retrieving talent tree from ingame data: %v - %v", ingameTree.ClassName, ingameTree.SpecName)
-		tree, err := talentTreeFromIngame(scanner, ingameTree)
+		tree, err := talentTreeFromIngame(scanner, ingameTree, knownNodeTypes)
 		if err != nil {
 			return nil, err
 		}
@@ -69,6 +97,12 @@ func GetTalentTrees(scanner *scan.Scanner) ([]wow.TalentTree, error) {
 		return nil, err
 	}
 
+	log.Printf("Retrieving hero tree media")
+	err = attachHeroTreeMedia(scanner, trees)
+	if err != nil {
+		return nil, err
+	}
+
 	log.Printf("Retrieving apex talents")
 	err = attachApexTalents(scanner, trees)
 	if err != nil {
@@ -78,6 +112,23 @@ func GetTalentTrees(scanner *scan.Scanner) ([]wow.TalentTree, error) {
 	return trees, nil
 }
 
+func mergeIngameTrees(trees []hack.IngameTree, overrides []hack.IngameTree) []hack.IngameTree {
+	merged := make([]hack.IngameTree, 0, len(trees)+len(overrides))
+	for _, tree := range trees {
+		overridden := false
+		for _, override := range overrides {
+			if override.SpecId == tree.SpecId {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			merged = append(merged, tree)
+		}
+	}
+	return append(merged, overrides...)
+}
+
 func attachSpellMedia(scanner *scan.Scanner, trees []wow.TalentTree) error {
 	mediaDict, err := GetSpellMedia(scanner, trees)
 	if err != nil {
@@ -135,6 +186,25 @@ func attachSpellMedia(scanner *scan.Scanner, trees []wow.TalentTree) error {
 	return nil
 }
 
+func attachHeroTreeMedia(scanner *scan.Scanner, trees []wow.TalentTree) error {
+	mediaDict, err := GetHeroTreeMedia(scanner, trees)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve hero tree media: %w", err)
+	}
+
+	for treeIndex := range trees {
+		for heroTreeIndex := range trees[treeIndex].HeroTrees {
+			heroTree := &trees[treeIndex].HeroTrees[heroTreeIndex]
+			media, ok := mediaDict[heroTree.MediaUrl]
+			if !ok {
+				return fmt.Errorf("missing media for hero tree: %s", heroTree.Name)
+			}
+			heroTree.Icon = media
+		}
+	}
+	return nil
+}
+
 func attachPvpTalents(scanner *scan.Scanner, trees []wow.TalentTree) error {
 	pvpTalents, err := GetPvpTalents(scanner)
 	if err != nil {
@@ -3,6 +3,7 @@ package talents
 import (
 	_ "embed"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/crbednarz/moonkinmetrics/pkg/testutils"
@@ -32,10 +33,24 @@ func TestCanGetTalentTrees(t *testing.T) {
 			t.Fatalf("expected at least 2 hero trees, got %d", len(tree.HeroTrees))
 		}
 
+		if tree.HeroSelectionNodeId == 0 {
+			t.Errorf("expected hero selection node for %s - %s", tree.ClassName, tree.SpecName)
+		}
+		for _, node := range tree.ClassNodes {
+			if node.Id == tree.HeroSelectionNodeId {
+				t.Errorf("expected hero selection node %d to be removed from class nodes", node.Id)
+			}
+		}
+
 		for _, heroTree := range tree.HeroTrees {
 			if len(heroTree.Nodes) < 11 {
 				t.Errorf("expected at least 11 hero nodes, got %d", len(heroTree.Nodes))
 			}
+
+			expectedIcon := fmt.Sprintf("/hero-talent/%d", heroTree.Id)
+			if !strings.HasSuffix(heroTree.Icon, expectedIcon) {
+				t.Errorf("expected hero tree icon ending in %s, got %s", expectedIcon, heroTree.Icon)
+			}
 		}
 
 		// Due to the mocking mechanism, all talents will fall into exactly one spec.
@@ -47,7 +62,16 @@ func TestCanGetTalentTrees(t *testing.T) {
 			t.Fatalf("expected 3 apex talents per tree, got %d", len(tree.ApexTalents))
 		}
 
-		apexNode := findApexNodeFromTree(&tree)
+		for _, talent := range tree.ApexTalents {
+			if talent.Icon != tree.ApexTalents[0].Icon {
+				t.Errorf("expected apex talents to share icon %s, got %s", tree.ApexTalents[0].Icon, talent.Icon)
+			}
+		}
+
+		apexNode := findApexNode(&tree)
+		if apexNode == nil {
+			t.Fatalf("expected apex node for %s - %s", tree.ClassName, tree.SpecName)
+		}
 		if apexNode.MaxRank != 4 {
 			t.Fatalf("expected max apex talent rank to be 4, got %d", apexNode.MaxRank)
 		}
@@ -72,5 +96,8 @@ func TestCanGetTalentTrees(t *testing.T) {
 			}
 		}
 
+		if tree.SpecId == 105 && tree.HeroSelectionNodeId != 99806 {
+			t.Errorf("expected Restoration hero selection node 99806, got %d", tree.HeroSelectionNodeId)
+		}
 	}
 }
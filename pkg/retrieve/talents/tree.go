@@ -1,7 +1,10 @@
 package talents
 
 import (
+	"cmp"
 	"errors"
+	"math"
+	"slices"
 
 	"github.com/crbednarz/moonkinmetrics/pkg/wow"
 )
@@ -18,10 +21,20 @@ type talentTreeJson struct {
 		Name string `json:"name"`
 		Id   int    `json:"id"`
 	} `json:"playable_specialization"`
-	ClassTalentNodes []talentNodeJson `json:"class_talent_nodes"`
-	SpecTalentNodes  []talentNodeJson `json:"spec_talent_nodes"`
-	HeroTalentTrees  []heroTreeJson   `json:"hero_talent_trees"`
-	Id               int              `json:"id"`
+	ClassTalentNodes []talentNodeJson      `json:"class_talent_nodes"`
+	SpecTalentNodes  []talentNodeJson      `json:"spec_talent_nodes"`
+	HeroTalentTrees  []heroTreeJson        `json:"hero_talent_trees"`
+	RestrictionLines []restrictionLineJson `json:"restriction_lines"`
+	Id               int                   `json:"id"`
+	// HeroSelectionNodeId is set once the hero selection node has been taken
+	// out of the class nodes.
+	HeroSelectionNodeId int `json:"-"`
+}
+
+type restrictionLineJson struct {
+	RequiredPoints int     `json:"required_points"`
+	RestrictedRow  float64 `json:"restricted_row"`
+	IsForClass     bool    `json:"is_for_class"`
 }
 
 type heroTreeJson struct {
@@ -129,6 +142,24 @@ func parseTalentTreeJson(treeJson *talentTreeJson) (wow.TalentTree, error) {
 		heroTrees = append(heroTrees, tree)
 	}
 
+	classGates := make([]wow.TalentGate, 0, len(treeJson.RestrictionLines))
+	specGates := make([]wow.TalentGate, 0, len(treeJson.RestrictionLines))
+	for _, line := range treeJson.RestrictionLines {
+		// Restriction lines are drawn between rows, so they're reported as
+		// half rows. For example, 5.5 gates row 6 onward.
+		gate := wow.TalentGate{
+			Row:            int(math.Ceil(line.RestrictedRow)),
+			RequiredPoints: line.RequiredPoints,
+		}
+		if line.IsForClass {
+			classGates = append(classGates, gate)
+		} else {
+			specGates = append(specGates, gate)
+		}
+	}
+	slices.SortFunc(classGates, compareGates)
+	slices.SortFunc(specGates, compareGates)
+
 	return wow.TalentTree{
 		ClassName:  treeJson.PlayableClass.Name,
 		ClassId:    treeJson.Id,
@@ -136,7 +167,11 @@ func parseTalentTreeJson(treeJson *talentTreeJson) (wow.TalentTree, error) {
 		SpecId:     treeJson.PlayableSpecialization.Id,
 		ClassNodes: classNodes,
 		SpecNodes:  specNodes,
+		ClassGates: classGates,
+		SpecGates:  specGates,
 		HeroTrees:  heroTrees,
+
+		HeroSelectionNodeId: treeJson.HeroSelectionNodeId,
 	}, nil
 }
 
@@ -150,10 +185,10 @@ func parseHeroTree(heroTreeJson heroTreeJson) (wow.HeroTree, error) {
 		nodes[i] = node
 	}
 	return wow.HeroTree{
-		Id:    heroTreeJson.Id,
-		Name:  heroTreeJson.Name,
-		Icon:  heroTreeJson.Media.Key.Href,
-		Nodes: nodes,
+		Id:       heroTreeJson.Id,
+		Name:     heroTreeJson.Name,
+		MediaUrl: heroTreeJson.Media.Key.Href,
+		Nodes:    nodes,
 	}, nil
 }
 
@@ -236,3 +271,7 @@ func parseTalentNode(nodeJson talentNodeJson) (wow.TalentNode, error) {
 		Y:        nodeJson.RawPositionY,
 	}, nil
 }
+
+func compareGates(a, b wow.TalentGate) int {
+	return cmp.Compare(a.Row, b.Row)
+}
@@ -2,6 +2,7 @@ package talents
 
 import (
 	_ "embed"
+	"slices"
 	"testing"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/crbednarz/moonkinmetrics/pkg/scan"
 	"github.com/crbednarz/moonkinmetrics/pkg/testutils"
 	"github.com/crbednarz/moonkinmetrics/pkg/validate"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
 )
 
 //go:embed testdata/valid-tree.json
@@ -66,6 +68,11 @@ func TestGetTalentTree(t *testing.T) {
 		t.Errorf("expected 43 spec nodes, got %d", len(tree.SpecNodes))
 	}
 
+	expectedGates := []wow.TalentGate{{Row: 6, RequiredPoints: 8}, {Row: 9, RequiredPoints: 20}}
+	if !slices.Equal(tree.ClassGates, expectedGates) || !slices.Equal(tree.SpecGates, expectedGates) {
+		t.Errorf("expected gates %v, got %v and %v", expectedGates, tree.ClassGates, tree.SpecGates)
+	}
+
 	if len(tree.HeroTrees) != 2 {
 		t.Fatalf("expected 2 hero trees, got %d", len(tree.HeroTrees))
 	}
@@ -0,0 +1,91 @@
+package scan
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/validate"
+)
+
+// ErrRetriesExhausted is returned once a request has failed on every attempt.
+// The error from the final attempt is wrapped alongside it, so callers can
+// still inspect it with errors.As.
+var ErrRetriesExhausted = errors.New("retries exhausted")
+
+// APIStatusError is for responses from Blizzard's API with an unexpected status code.
+type APIStatusError struct {
+	RequestId  string
+	StatusCode int
+}
+
+func (e *APIStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code for %s: %d", e.RequestId, e.StatusCode)
+}
+
+// ValidationError is for responses which could not be parsed, repaired, or
+// filtered into a valid result.
+type ValidationError struct {
+	RequestId string
+	// Paths holds the fields which failed schema validation, if known.
+	Paths []string
+	Err   error
+}
+
+func newValidationError(requestId string, err error) *ValidationError {
+	validationError := &ValidationError{
+		RequestId: requestId,
+		Err:       err,
+	}
+	var schemaError *validate.SchemaError
+	if errors.As(err, &schemaError) {
+		validationError.Paths = schemaError.Paths()
+	}
+	return validationError
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("response for %s failed validation: %v", e.RequestId, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// StorageError is for failures to store a valid response in the scanner's storage.
+type StorageError struct {
+	RequestId string
+	Err       error
+}
+
+func (e *StorageError) Error() string {
+	return fmt.Sprintf("failed to store response for %s: %v", e.RequestId, e.Err)
+}
+
+func (e *StorageError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorKind returns a short, stable name for the category of a scan error.
+// This is intended for grouping failures in reports and metrics.
+func ErrorKind(err error) string {
+	var statusError *APIStatusError
+	var validationError *ValidationError
+	var storageError *StorageError
+
+	switch {
+	case err == nil:
+		return "none"
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	case errors.As(err, &validationError):
+		return "validation"
+	case errors.As(err, &storageError):
+		return "storage"
+	case errors.As(err, &statusError):
+		return "api_status"
+	case errors.Is(err, ErrRetriesExhausted):
+		return "transport"
+	default:
+		return "unknown"
+	}
+}
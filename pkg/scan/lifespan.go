@@ -0,0 +1,130 @@
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+)
+
+// LifespanPolicy overrides how long responses are cached, based on the
+// namespace and path of the request. Requests which don't match a rule keep
+// the lifespan from their ScanOptions.
+type LifespanPolicy struct {
+	Rules []LifespanRule
+}
+
+// LifespanRule sets the lifespan of requests in a namespace whose path is
+// PathPrefix or lies beneath it. Only whole path segments match, so
+// "/data/wow/talent" doesn't match "/data/wow/talent-tree". An empty Namespace
+// matches every namespace. When several rules match, the one with the longest
+// PathPrefix wins, and later rules win ties.
+type LifespanRule struct {
+	Namespace  api.Namespace
+	PathPrefix string
+	Lifespan   time.Duration
+}
+
+type lifespanPolicyJson struct {
+	Rules []struct {
+		Namespace  string `json:"namespace"`
+		PathPrefix string `json:"path_prefix"`
+		Lifespan   string `json:"lifespan"`
+	} `json:"rules"`
+}
+
+// LoadLifespanPolicy reads a lifespan policy from a JSON file, e.g.
+//
+//	{"rules": [{"namespace": "dynamic", "path_prefix": "/data/wow/realm", "lifespan": "336h"}]}
+func LoadLifespanPolicy(path string) (*LifespanPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var policyJson lifespanPolicyJson
+	err = json.Unmarshal(data, &policyJson)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lifespan policy: %w", err)
+	}
+
+	policy := &LifespanPolicy{}
+	for _, ruleJson := range policyJson.Rules {
+		rule, err := newLifespanRule(ruleJson.Namespace, ruleJson.PathPrefix, ruleJson.Lifespan)
+		if err != nil {
+			return nil, err
+		}
+		policy.Rules = append(policy.Rules, rule)
+	}
+	return policy, nil
+}
+
+// ParseLifespanRule parses a rule in the form "namespace:/path/prefix=duration".
+// The namespace may be omitted to match any namespace, e.g. ":/data/wow/media=168h".
+func ParseLifespanRule(text string) (LifespanRule, error) {
+	key, lifespan, found := strings.Cut(text, "=")
+	if !found {
+		return LifespanRule{}, fmt.Errorf("invalid lifespan rule, expected namespace:/path=duration: %s", text)
+	}
+	namespace, pathPrefix, found := strings.Cut(key, ":")
+	if !found {
+		return LifespanRule{}, fmt.Errorf("invalid lifespan rule, expected namespace:/path=duration: %s", text)
+	}
+	return newLifespanRule(namespace, pathPrefix, lifespan)
+}
+
+func newLifespanRule(namespace string, pathPrefix string, lifespan string) (LifespanRule, error) {
+	switch api.Namespace(namespace) {
+	case "", api.NamespaceStatic, api.NamespaceDynamic, api.NamespaceProfile:
+	default:
+		return LifespanRule{}, fmt.Errorf("invalid lifespan rule namespace: %s", namespace)
+	}
+
+	duration, err := time.ParseDuration(lifespan)
+	if err != nil {
+		return LifespanRule{}, fmt.Errorf("invalid lifespan rule duration: %w", err)
+	}
+
+	return LifespanRule{
+		Namespace:  api.Namespace(namespace),
+		PathPrefix: pathPrefix,
+		Lifespan:   duration,
+	}, nil
+}
+
+// Lifespan returns the lifespan for the request, or defaultLifespan if no rule matches.
+func (p *LifespanPolicy) Lifespan(request api.Request, defaultLifespan time.Duration) time.Duration {
+	if p == nil {
+		return defaultLifespan
+	}
+
+	bnetRequest, ok := request.(*api.BnetRequest)
+	if !ok {
+		return defaultLifespan
+	}
+
+	lifespan := defaultLifespan
+	matchLength := -1
+	for _, rule := range p.Rules {
+		if rule.Namespace != "" && rule.Namespace != bnetRequest.Namespace {
+			continue
+		}
+		if !matchesPathPrefix(bnetRequest.Path, rule.PathPrefix) {
+			continue
+		}
+		if len(rule.PathPrefix) >= matchLength {
+			lifespan = rule.Lifespan
+			matchLength = len(rule.PathPrefix)
+		}
+	}
+	return lifespan
+}
+
+// matchesPathPrefix reports whether path is prefix or one of its descendants.
+func matchesPathPrefix(path string, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
@@ -0,0 +1,113 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+)
+
+func TestLifespanPolicyPrefersLongestPrefix(t *testing.T) {
+	policy := &LifespanPolicy{
+		Rules: []LifespanRule{
+			{Namespace: "", PathPrefix: "/data/wow", Lifespan: time.Hour},
+			{Namespace: api.NamespaceDynamic, PathPrefix: "/data/wow/pvp-season", Lifespan: time.Minute},
+			{Namespace: api.NamespaceStatic, PathPrefix: "/data/wow/pvp-season/37", Lifespan: time.Second},
+		},
+	}
+
+	request := api.BnetRequest{
+		Region:    api.RegionUS,
+		Namespace: api.NamespaceDynamic,
+		Path:      "/data/wow/pvp-season/37/pvp-leaderboard/3v3",
+	}
+	if lifespan := policy.Lifespan(&request, 18*time.Hour); lifespan != time.Minute {
+		t.Errorf("Expected lifespan to be %s, got %s", time.Minute, lifespan)
+	}
+
+	request.Path = "/data/wow/realm/129"
+	if lifespan := policy.Lifespan(&request, 18*time.Hour); lifespan != time.Hour {
+		t.Errorf("Expected lifespan to be %s, got %s", time.Hour, lifespan)
+	}
+
+	request.Path = "/profile/wow/character/skywall/name/specializations"
+	if lifespan := policy.Lifespan(&request, 18*time.Hour); lifespan != 18*time.Hour {
+		t.Errorf("Expected lifespan to be %s, got %s", 18*time.Hour, lifespan)
+	}
+}
+
+func TestLifespanPolicyMatchesWholeSegments(t *testing.T) {
+	policy := &LifespanPolicy{
+		Rules: []LifespanRule{
+			{Namespace: api.NamespaceStatic, PathPrefix: "/data/wow/talent", Lifespan: time.Hour},
+			{Namespace: api.NamespaceStatic, PathPrefix: "/data/wow/media/", Lifespan: time.Minute},
+		},
+	}
+
+	tests := []struct {
+		path     string
+		lifespan time.Duration
+	}{
+		{"/data/wow/talent", time.Hour},
+		{"/data/wow/talent/index", time.Hour},
+		{"/data/wow/talent-tree/793/playable-specialization/105", 18 * time.Hour},
+		{"/data/wow/media/spell/123", time.Minute},
+		{"/data/wow/media-index", 18 * time.Hour},
+	}
+	for _, test := range tests {
+		request := api.BnetRequest{
+			Region:    api.RegionUS,
+			Namespace: api.NamespaceStatic,
+			Path:      test.path,
+		}
+		if lifespan := policy.Lifespan(&request, 18*time.Hour); lifespan != test.lifespan {
+			t.Errorf("Expected lifespan of %s to be %s, got %s", test.path, test.lifespan, lifespan)
+		}
+	}
+}
+
+func TestParseLifespanRule(t *testing.T) {
+	rule, err := ParseLifespanRule("profile:/profile/wow/character=6h")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if rule.Namespace != api.NamespaceProfile || rule.PathPrefix != "/profile/wow/character" || rule.Lifespan != 6*time.Hour {
+		t.Errorf("Unexpected rule: %+v", rule)
+	}
+
+	_, err = ParseLifespanRule("profile=6h")
+	if err == nil {
+		t.Errorf("Expected error for rule without path")
+	}
+
+	_, err = ParseLifespanRule("unknown:/data=6h")
+	if err == nil {
+		t.Errorf("Expected error for unknown namespace")
+	}
+}
+
+func TestLoadLifespanPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lifespans.json")
+	err := os.WriteFile(path, []byte(`{
+  "rules": [
+    {"namespace": "dynamic", "path_prefix": "/data/wow/realm", "lifespan": "336h"},
+    {"path_prefix": "/data/wow/media", "lifespan": "168h"}
+  ]
+}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := LoadLifespanPolicy(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(policy.Rules) != 2 {
+		t.Fatalf("Expected 2 rules, got %d", len(policy.Rules))
+	}
+	if policy.Rules[0].Lifespan != 336*time.Hour {
+		t.Errorf("Expected lifespan to be %s, got %s", 336*time.Hour, policy.Rules[0].Lifespan)
+	}
+}
@@ -3,6 +3,7 @@ package scan
 import (
 	"context"
 
+	"github.com/crbednarz/moonkinmetrics/pkg/storage"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 )
@@ -19,11 +20,17 @@ type otelMetricsReporter struct {
 
 type emptyScanMetrics struct{}
 
+// cacheStatsProvider is implemented by storage which tracks its own cache hits,
+// such as storage.MemoryCache.
+type cacheStatsProvider interface {
+	Stats() storage.CacheStats
+}
+
 func newEmptyMetricsReporter() metricsReporter {
 	return &emptyScanMetrics{}
 }
 
-func newMetricsReporter(meter metric.Meter) (metricsReporter, error) {
+func newMetricsReporter(meter metric.Meter, responseStorage storage.ResponseStorage) (metricsReporter, error) {
 	requestCounter, err := meter.Int64Counter("scan_requests")
 	if err != nil {
 		return nil, err
@@ -38,6 +45,13 @@ func newMetricsReporter(meter metric.Meter) (metricsReporter, error) {
 	if err != nil {
 		return nil, err
 	}
+	if statsProvider, ok := responseStorage.(cacheStatsProvider); ok {
+		err = registerCacheMetrics(meter, statsProvider)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &otelMetricsReporter{
 		requests:    requestCounter,
 		apiErrors:   apiErrorCounter,
@@ -50,6 +64,7 @@ func (o *otelMetricsReporter) Report(ctx context.Context, resultDetails ScanResu
 		attribute.Bool("success", resultDetails.Success),
 		attribute.Bool("cached", resultDetails.Cached),
 		attribute.Bool("repaired", resultDetails.Repaired),
+		attribute.String("error_kind", resultDetails.ErrorKind),
 	)
 	o.requests.Add(ctx, 1,
 		metric.WithAttributeSet(attributeSet),
@@ -64,3 +79,23 @@ func (o *otelMetricsReporter) Report(ctx context.Context, resultDetails ScanResu
 
 func (e *emptyScanMetrics) Report(ctx context.Context, resultDetails ScanResultDetails) {
 }
+
+func registerCacheMetrics(meter metric.Meter, statsProvider cacheStatsProvider) error {
+	hitCounter, err := meter.Int64ObservableCounter("scan_memory_cache_hits")
+	if err != nil {
+		return err
+	}
+
+	missCounter, err := meter.Int64ObservableCounter("scan_memory_cache_misses")
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(ctx context.Context, observer metric.Observer) error {
+		stats := statsProvider.Stats()
+		observer.ObserveInt64(hitCounter, stats.Hits)
+		observer.ObserveInt64(missCounter, stats.Misses)
+		return nil
+	}, hitCounter, missCounter)
+	return err
+}
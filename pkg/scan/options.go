@@ -5,6 +5,7 @@ import "go.opentelemetry.io/otel/metric"
 type scannerOptions struct {
 	metricsOption
 	maxRetriesOption
+	lifespanPolicyOption
 }
 
 type ScannerOption interface {
@@ -38,3 +39,17 @@ func WithMetrics(meter metric.Meter) ScannerOption {
 		meter: meter,
 	}
 }
+
+type lifespanPolicyOption struct {
+	lifespanPolicy *LifespanPolicy
+}
+
+func (l lifespanPolicyOption) apply(o *scannerOptions) {
+	o.lifespanPolicyOption = l
+}
+
+func WithLifespanPolicy(policy *LifespanPolicy) ScannerOption {
+	return lifespanPolicyOption{
+		lifespanPolicy: policy,
+	}
+}
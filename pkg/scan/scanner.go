@@ -26,6 +26,7 @@ type Scanner struct {
 	storage         storage.ResponseStorage
 	client          *api.Client
 	metricsReporter metricsReporter
+	lifespanPolicy  *LifespanPolicy
 	maxRetries      int
 }
 
@@ -35,6 +36,10 @@ type ScanResultDetails struct {
 	Cached      bool
 	Repaired    bool
 	Success     bool
+	ErrorKind   string
+	// Timestamp is when the response was retrieved from the API, which is
+	// earlier than the scan for cached responses.
+	Timestamp time.Time
 }
 
 type ScanResult[T any] struct {
@@ -67,7 +72,7 @@ func NewScanner(storage storage.ResponseStorage, client *api.Client, opts ...Sca
 
 	metricsReporter := newEmptyMetricsReporter()
 	if options.meter != nil {
-		metrics, err := newMetricsReporter(options.meter)
+		metrics, err := newMetricsReporter(options.meter, storage)
 		if err != nil {
 			return nil, err
 		}
@@ -78,6 +83,7 @@ func NewScanner(storage storage.ResponseStorage, client *api.Client, opts ...Sca
 		storage:         storage,
 		client:          client,
 		maxRetries:      options.maxRetries,
+		lifespanPolicy:  options.lifespanPolicy,
 		metricsReporter: metricsReporter,
 	}, nil
 }
@@ -97,7 +103,7 @@ func Scan[T any](scanner *Scanner, requests <-chan api.Request, results chan<- S
 					Index:      request.Index,
 				}
 				buildFromApi(ctx, scanner, request.ApiRequest, options, &result)
-				scanner.metricsReporter.Report(ctx, result.Details)
+				reportResult(ctx, scanner, &result)
 				results <- result
 			}
 			wg.Done()
@@ -115,7 +121,7 @@ func Scan[T any](scanner *Scanner, requests <-chan api.Request, results chan<- S
 			buildFromCache(ctx, scanner, apiRequest, options, &result)
 
 			if result.Error == nil {
-				scanner.metricsReporter.Report(ctx, result.Details)
+				reportResult(ctx, scanner, &result)
 				results <- result
 			} else {
 				result.Error = nil
@@ -144,16 +150,21 @@ func ScanSingle[T any](scanner *Scanner, request api.Request, options *ScanOptio
 
 	buildFromCache(ctx, scanner, request, options, &result)
 	if result.Error == nil {
-		scanner.metricsReporter.Report(ctx, result.Details)
+		reportResult(ctx, scanner, &result)
 		return result
 	}
 
 	result.Error = nil
 	buildFromApi(ctx, scanner, request, options, &result)
-	scanner.metricsReporter.Report(ctx, result.Details)
+	reportResult(ctx, scanner, &result)
 	return result
 }
 
+func reportResult[T any](ctx context.Context, scanner *Scanner, result *ScanResult[T]) {
+	result.Details.ErrorKind = ErrorKind(result.Error)
+	scanner.metricsReporter.Report(ctx, result.Details)
+}
+
 func buildFromCache[T any](ctx context.Context, scanner *Scanner, request api.Request, options *ScanOptions[T], result *ScanResult[T]) {
 	if scanner.storage == nil {
 		result.Error = ErrNoCache
@@ -176,6 +187,7 @@ func buildFromCache[T any](ctx context.Context, scanner *Scanner, request api.Re
 		result.Details.Repaired = repaired
 		result.Details.Cached = true
 		result.Details.Success = true
+		result.Details.Timestamp = cachedResponse.Timestamp
 	}
 }
 
@@ -190,6 +202,7 @@ func buildFromApi[T any](ctx context.Context, scanner *Scanner, request api.Requ
 		}
 
 		result.Details.ApiAttempts += apiResponse.Attempts
+		result.Details.Timestamp = time.Now()
 
 		if apiResponse.StatusCode == 404 {
 			// 404 errors typically don't resolve over multiple requests, so we can break here.
@@ -199,22 +212,26 @@ func buildFromApi[T any](ctx context.Context, scanner *Scanner, request api.Requ
 		}
 
 		if apiResponse.StatusCode >= 300 {
-			lastError = fmt.Errorf("failed to retrieve response for %s: %d", request.Id(), apiResponse.StatusCode)
+			lastError = &APIStatusError{
+				RequestId:  request.Id(),
+				StatusCode: apiResponse.StatusCode,
+			}
 			result.Details.ApiErrors++
 			continue
 		}
 
 		repaired, err := buildFromJson(apiResponse.Body, options, &result.Response)
 		if err != nil {
-			result.Error = fmt.Errorf("response for %s failed validation: %w", request.Id(), err)
+			result.Error = newValidationError(request.Id(), err)
 			return
 		}
 
-		if scanner.storage != nil && options.Lifespan > 0 {
-			err = scanner.storage.Store(request, apiResponse.Body, options.Lifespan)
+		lifespan := scanner.lifespanPolicy.Lifespan(request, options.Lifespan)
+		if scanner.storage != nil && lifespan > 0 {
+			err = scanner.storage.Store(request, apiResponse.Body, lifespan)
 			if err != nil {
 				// While we can technically continue here, a storage failure is important enough to fail the whole request.
-				result.Error = fmt.Errorf("failed to store response for %s: %w", request, err)
+				result.Error = &StorageError{RequestId: request.Id(), Err: err}
 			} else {
 				result.Details.Repaired = repaired
 				result.Details.Success = true
@@ -222,7 +239,9 @@ func buildFromApi[T any](ctx context.Context, scanner *Scanner, request api.Requ
 		}
 		return
 	}
-	result.Error = lastError
+	if lastError != nil {
+		result.Error = fmt.Errorf("%w after %d attempts: %w", ErrRetriesExhausted, scanner.maxRetries, lastError)
+	}
 }
 
 func buildFromJson[T any](body []byte, options *ScanOptions[T], output *T) (repaired bool, err error) {
@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -16,6 +17,7 @@ import (
 type MockHttpClient struct {
 	FailAfterFirst bool
 	ShouldFail     bool
+	StatusCode     int
 }
 
 type MockResponseObject struct {
@@ -33,9 +35,14 @@ func (m *MockHttpClient) Do(req *http.Request) (*http.Response, error) {
 		return nil, fmt.Errorf("mock http client failed")
 	}
 
+	statusCode := m.StatusCode
+	if statusCode == 0 {
+		statusCode = 200
+	}
+
 	responseBody := fmt.Sprintf(`{"path":"%s"}`, req.URL.Path)
 	response := &http.Response{
-		StatusCode: 200,
+		StatusCode: statusCode,
 		Body:       io.NopCloser(strings.NewReader(responseBody)),
 	}
 
@@ -221,3 +228,96 @@ func TestCachedScan(t *testing.T) {
 		t.Errorf("Expected path to be %s, got %s", "/data/wow/mock/path", result.Response.Path)
 	}
 }
+
+func TestScanStatusErrorExhaustsRetries(t *testing.T) {
+	scanner, err := newMockScanner(&MockHttpClient{
+		StatusCode: 503,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	request := newMockRequest("/data/wow/mock/path")
+	options := newMockOptions[MockResponseObject]()
+
+	result := ScanSingle(scanner, &request, &options)
+
+	if !errors.Is(result.Error, ErrRetriesExhausted) {
+		t.Errorf("Expected ErrRetriesExhausted, got %v", result.Error)
+	}
+
+	var statusError *APIStatusError
+	if !errors.As(result.Error, &statusError) {
+		t.Fatalf("Expected APIStatusError, got %v", result.Error)
+	}
+	if statusError.StatusCode != 503 {
+		t.Errorf("Expected status code 503, got %d", statusError.StatusCode)
+	}
+
+	if result.Details.ErrorKind != "api_status" {
+		t.Errorf("Expected error kind api_status, got %s", result.Details.ErrorKind)
+	}
+}
+
+func TestScanTransportErrorExhaustsRetries(t *testing.T) {
+	scanner, err := newMockScanner(&MockHttpClient{
+		ShouldFail: true,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	request := newMockRequest("/data/wow/mock/path")
+	options := newMockOptions[MockResponseObject]()
+
+	result := ScanSingle(scanner, &request, &options)
+
+	if !errors.Is(result.Error, ErrRetriesExhausted) {
+		t.Errorf("Expected ErrRetriesExhausted, got %v", result.Error)
+	}
+
+	if result.Details.ErrorKind != "transport" {
+		t.Errorf("Expected error kind transport, got %s", result.Details.ErrorKind)
+	}
+}
+
+func TestScanValidationError(t *testing.T) {
+	scanner, err := newMockScanner(nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	request := newMockRequest("/")
+	validator, err := validate.NewSchemaValidator[MockResponseObject](`
+  {
+    "type": "object",
+    "properties": {
+      "path": {
+        "type": "string",
+        "minLength": 5
+      }
+    }
+  }`)
+	if err != nil {
+		t.Errorf("Failed to create schema validator: %v", err)
+	}
+	options := ScanOptions[MockResponseObject]{
+		Validator: validator,
+		Lifespan:  time.Hour,
+	}
+
+	result := ScanSingle(scanner, &request, &options)
+
+	var validationError *ValidationError
+	if !errors.As(result.Error, &validationError) {
+		t.Fatalf("Expected ValidationError, got %v", result.Error)
+	}
+
+	if len(validationError.Paths) != 1 || validationError.Paths[0] != "path" {
+		t.Errorf("Expected failing path to be [path], got %v", validationError.Paths)
+	}
+
+	if result.Details.ErrorKind != "validation" {
+		t.Errorf("Expected error kind validation, got %s", result.Details.ErrorKind)
+	}
+}
@@ -0,0 +1,54 @@
+package serialize
+
+import (
+	"encoding/json"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/talentdiff"
+)
+
+type talentDiffJson struct {
+	Specs []specDiffJson `json:"specs"`
+}
+
+type specDiffJson struct {
+	ClassName string       `json:"class_name"`
+	SpecName  string       `json:"spec_name"`
+	Changes   []changeJson `json:"changes"`
+	SpecId    int          `json:"spec_id"`
+}
+
+type changeJson struct {
+	Kind     string `json:"kind"`
+	Section  string `json:"section,omitempty"`
+	Old      string `json:"old,omitempty"`
+	New      string `json:"new,omitempty"`
+	Message  string `json:"message"`
+	NodeId   int    `json:"node_id,omitempty"`
+	TalentId int    `json:"talent_id,omitempty"`
+}
+
+// ExportTalentDiffToJson serializes a talent diff, such as for patch notes.
+func ExportTalentDiffToJson(diff *talentdiff.Diff) ([]byte, error) {
+	specs := make([]specDiffJson, len(diff.Specs))
+	for i, spec := range diff.Specs {
+		changes := make([]changeJson, len(spec.Changes))
+		for j, change := range spec.Changes {
+			changes[j] = changeJson{
+				Kind:     string(change.Kind),
+				Section:  change.Section,
+				Old:      change.Old,
+				New:      change.New,
+				Message:  change.Message,
+				NodeId:   change.NodeId,
+				TalentId: change.TalentId,
+			}
+		}
+		specs[i] = specDiffJson{
+			ClassName: spec.ClassName,
+			SpecName:  spec.SpecName,
+			SpecId:    spec.SpecId,
+			Changes:   changes,
+		}
+	}
+	return json.MarshalIndent(talentDiffJson{Specs: specs}, "", "  ")
+}
@@ -0,0 +1,57 @@
+package serialize
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/talentdiff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportTalentDiffToJson(t *testing.T) {
+	diff := talentdiff.Diff{
+		Specs: []talentdiff.SpecDiff{{
+			ClassName: "Druid",
+			SpecName:  "Balance",
+			SpecId:    102,
+			Changes: []talentdiff.Change{
+				{
+					Kind:    talentdiff.KindMaxRank,
+					Section: talentdiff.SectionSpec,
+					NodeId:  7,
+					Old:     "1",
+					New:     "2",
+					Message: "Starfall max rank changed from 1 to 2",
+				},
+				{
+					Kind:    talentdiff.KindSpecAdded,
+					Message: "spec added",
+				},
+			},
+		}},
+	}
+
+	serialized, err := ExportTalentDiffToJson(&diff)
+	require.NoError(t, err)
+
+	var output map[string]any
+	require.NoError(t, json.Unmarshal(serialized, &output))
+	specs := output["specs"].([]any)
+	require.Len(t, specs, 1)
+	changes := specs[0].(map[string]any)["changes"].([]any)
+	require.Len(t, changes, 2)
+
+	assert.Equal(t, map[string]any{
+		"kind":    "max_rank",
+		"section": "spec",
+		"node_id": float64(7),
+		"old":     "1",
+		"new":     "2",
+		"message": "Starfall max rank changed from 1 to 2",
+	}, changes[0])
+	assert.Equal(t, map[string]any{
+		"kind":    "spec_added",
+		"message": "spec added",
+	}, changes[1])
+}
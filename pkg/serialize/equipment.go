@@ -0,0 +1,163 @@
+package serialize
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/site"
+)
+
+type equipmentJson struct {
+	ClassName        string              `json:"class"`
+	SpecName         string              `json:"spec"`
+	Bracket          string              `json:"bracket"`
+	Slots            map[string]slotJson `json:"slots"`
+	Sets             []setJson           `json:"sets"`
+	Players          int                 `json:"players"`
+	AverageItemLevel float64             `json:"average_item_level"`
+	Timestamp        int64               `json:"timestamp"`
+}
+
+type slotJson struct {
+	Items    []popularityJson `json:"items"`
+	Enchants []popularityJson `json:"enchants"`
+	Gems     []popularityJson `json:"gems"`
+}
+
+type setJson struct {
+	// ActiveBonuses maps a number of active set bonuses to how many players
+	// have exactly that many active.
+	ActiveBonuses map[int]int `json:"active_bonuses"`
+	Name          string      `json:"name"`
+	Id            int         `json:"id"`
+	Count         int         `json:"count"`
+}
+
+type popularityJson struct {
+	Id    int `json:"id"`
+	Count int `json:"count"`
+}
+
+// pairedSlots maps slots which hold interchangeable items to a shared slot, so
+// an item's popularity doesn't depend on which of the pair it was equipped in.
+var pairedSlots = map[string]string{
+	"FINGER_1":  "FINGER",
+	"FINGER_2":  "FINGER",
+	"TRINKET_1": "TRINKET",
+	"TRINKET_2": "TRINKET",
+}
+
+type popularityCounter map[int]int
+
+func (c popularityCounter) toJson() []popularityJson {
+	popularity := make([]popularityJson, 0, len(c))
+	for id, count := range c {
+		popularity = append(popularity, popularityJson{id, count})
+	}
+	slices.SortFunc(popularity, func(a, b popularityJson) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return a.Id - b.Id
+	})
+	return popularity
+}
+
+// ExportEquipmentToJson aggregates how often each item, enchant, gem and set
+// is used by the leaderboard's players. Entries without equipment are skipped.
+// Rings and trinkets are counted together, regardless of which slot they're in.
+func ExportEquipmentToJson(leaderboard *site.EnrichedLeaderboard) ([]byte, error) {
+	type slotCounters struct {
+		items    popularityCounter
+		enchants popularityCounter
+		gems     popularityCounter
+	}
+
+	slotMap := make(map[string]*slotCounters)
+	setMap := make(map[int]*setJson)
+	players := 0
+	itemLevelTotal := 0.0
+	for _, entry := range leaderboard.Entries {
+		if entry.Equipment == nil {
+			continue
+		}
+		players++
+		itemLevelTotal += entry.Equipment.AverageItemLevel()
+
+		for _, item := range entry.Equipment.Items {
+			slot := item.Slot
+			if pairedSlot, ok := pairedSlots[slot]; ok {
+				slot = pairedSlot
+			}
+
+			counters, ok := slotMap[slot]
+			if !ok {
+				counters = &slotCounters{
+					items:    make(popularityCounter),
+					enchants: make(popularityCounter),
+					gems:     make(popularityCounter),
+				}
+				slotMap[slot] = counters
+			}
+
+			counters.items[item.Id]++
+			for _, enchant := range item.Enchants {
+				counters.enchants[enchant]++
+			}
+			for _, gem := range item.Gems {
+				counters.gems[gem]++
+			}
+		}
+
+		for _, equippedSet := range entry.Equipment.Sets {
+			set, ok := setMap[equippedSet.Id]
+			if !ok {
+				set = &setJson{
+					ActiveBonuses: make(map[int]int),
+					Name:          equippedSet.Name,
+					Id:            equippedSet.Id,
+				}
+				setMap[equippedSet.Id] = set
+			}
+			set.Count++
+			set.ActiveBonuses[equippedSet.ActiveBonuses]++
+		}
+	}
+
+	slots := make(map[string]slotJson, len(slotMap))
+	for slot, counters := range slotMap {
+		slots[slot] = slotJson{
+			Items:    counters.items.toJson(),
+			Enchants: counters.enchants.toJson(),
+			Gems:     counters.gems.toJson(),
+		}
+	}
+
+	sets := make([]setJson, 0, len(setMap))
+	for _, set := range setMap {
+		sets = append(sets, *set)
+	}
+	slices.SortFunc(sets, func(a, b setJson) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return a.Id - b.Id
+	})
+
+	averageItemLevel := 0.0
+	if players > 0 {
+		averageItemLevel = itemLevelTotal / float64(players)
+	}
+
+	output := equipmentJson{
+		ClassName:        leaderboard.ClassName,
+		SpecName:         leaderboard.SpecName,
+		Bracket:          leaderboard.Bracket,
+		Slots:            slots,
+		Sets:             sets,
+		Players:          players,
+		AverageItemLevel: averageItemLevel,
+		Timestamp:        leaderboard.Timestamp.UnixMilli(),
+	}
+	return json.MarshalIndent(output, "", "  ")
+}
@@ -0,0 +1,90 @@
+package serialize
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/site"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockEquipment(weaponId int, enchantId int, activeBonuses int) *wow.Equipment {
+	return &wow.Equipment{
+		Items: []wow.EquippedItem{
+			{Slot: "HEAD", Id: 100, ItemLevel: 640},
+			{Slot: "MAIN_HAND", Id: weaponId, ItemLevel: 620, Enchants: []int{enchantId}},
+		},
+		Sets: []wow.EquippedSet{
+			{Name: "Tier", Id: 1, EquippedCount: activeBonuses * 2, ActiveBonuses: activeBonuses},
+		},
+	}
+}
+
+func TestCanExportEquipment(t *testing.T) {
+	leaderboard := site.EnrichedLeaderboard{
+		ClassName: "Druid",
+		SpecName:  "Balance",
+		Bracket:   "3v3",
+		Entries: []site.EnrichedLeaderboardEntry{
+			{Equipment: mockEquipment(200, 10, 2)},
+			{Equipment: mockEquipment(200, 11, 2)},
+			{Equipment: mockEquipment(201, 10, 1)},
+			{Equipment: nil},
+		},
+	}
+
+	data, err := ExportEquipmentToJson(&leaderboard)
+	assert.NoError(t, err)
+
+	var output equipmentJson
+	err = json.Unmarshal(data, &output)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 3, output.Players)
+	assert.Equal(t, 630.0, output.AverageItemLevel)
+
+	mainHand := output.Slots["MAIN_HAND"]
+	assert.Equal(t, []popularityJson{{200, 2}, {201, 1}}, mainHand.Items)
+	assert.Equal(t, []popularityJson{{10, 2}, {11, 1}}, mainHand.Enchants)
+	assert.Empty(t, mainHand.Gems)
+
+	assert.Len(t, output.Sets, 1)
+	assert.Equal(t, 3, output.Sets[0].Count)
+	assert.Equal(t, map[int]int{1: 1, 2: 2}, output.Sets[0].ActiveBonuses)
+}
+
+func TestExportEquipmentMergesPairedSlots(t *testing.T) {
+	leaderboard := site.EnrichedLeaderboard{
+		ClassName: "Druid",
+		SpecName:  "Balance",
+		Bracket:   "3v3",
+		Timestamp: time.UnixMilli(1700000000000),
+		Entries: []site.EnrichedLeaderboardEntry{
+			{Equipment: &wow.Equipment{Items: []wow.EquippedItem{
+				{Slot: "FINGER_1", Id: 300, ItemLevel: 630},
+				{Slot: "FINGER_2", Id: 301, ItemLevel: 630},
+				{Slot: "TRINKET_1", Id: 400, ItemLevel: 630},
+			}}},
+			{Equipment: &wow.Equipment{Items: []wow.EquippedItem{
+				{Slot: "FINGER_1", Id: 301, ItemLevel: 630},
+				{Slot: "FINGER_2", Id: 302, ItemLevel: 630},
+				{Slot: "TRINKET_2", Id: 400, ItemLevel: 630},
+			}}},
+		},
+	}
+
+	data, err := ExportEquipmentToJson(&leaderboard)
+	assert.NoError(t, err)
+
+	var output equipmentJson
+	err = json.Unmarshal(data, &output)
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(1700000000000), output.Timestamp)
+	assert.NotContains(t, output.Slots, "FINGER_1")
+	assert.NotContains(t, output.Slots, "TRINKET_2")
+	assert.Equal(t, []popularityJson{{301, 2}, {300, 1}, {302, 1}}, output.Slots["FINGER"].Items)
+	assert.Equal(t, []popularityJson{{400, 2}}, output.Slots["TRINKET"].Items)
+}
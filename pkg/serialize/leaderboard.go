@@ -3,15 +3,25 @@ package serialize
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"slices"
 	"strings"
 	"time"
 
+	"github.com/crbednarz/moonkinmetrics/pkg/legality"
+	"github.com/crbednarz/moonkinmetrics/pkg/scan"
 	"github.com/crbednarz/moonkinmetrics/pkg/site"
 	"github.com/crbednarz/moonkinmetrics/pkg/wow"
 )
 
-const EncodingVersion int = 1
+const EncodingVersion int = 3
+
+const (
+	// entryFlagStaleTalents marks entries whose talents may not be what they
+	// played with, as they haven't logged in since well before the snapshot,
+	// or the leaderboard is from a past season.
+	entryFlagStaleTalents byte = 1 << iota
+)
 
 type classSpec struct {
 	ClassName string
@@ -22,6 +32,12 @@ type leaderboardJson struct {
 	Entries   []string     `json:"entries"`
 	Encoding  metadataJson `json:"encoding"`
 	Timestamp int64        `json:"timestamp"`
+	// Historical is set for past seasons, where every entry's talents are as
+	// of Timestamp rather than from the season itself.
+	Historical bool `json:"historical"`
+	// Illegal is the number of entries whose loadout failed the legality
+	// check. They're left out of Entries unless illegal loadouts are kept.
+	Illegal int `json:"illegal"`
 }
 
 type metadataJson struct {
@@ -105,13 +121,10 @@ func ExportLeaderboardToJson(leaderboard *site.EnrichedLeaderboard) ([]byte, err
 		data := make([]byte, 0, 128)
 
 		data = append(data, talentSerializer.Serialize(entry.Loadout)...)
-		rating := entry.Rating
-		data = append(data, byte(rating&0xFF))
-		data = append(data, byte((rating>>8)&0xFF))
+		data = appendUint16(data, entry.Rating)
 
 		realmIndex := realmMap[entry.Player.Realm.Slug]
-		data = append(data, byte(realmIndex&0xFF))
-		data = append(data, byte((realmIndex>>8)&0xFF))
+		data = appendUint16(data, uint(realmIndex))
 
 		if entry.Faction == "HORDE" {
 			data = append(data, 1)
@@ -119,6 +132,24 @@ func ExportLeaderboardToJson(leaderboard *site.EnrichedLeaderboard) ([]byte, err
 			data = append(data, 0)
 		}
 
+		data = appendUint16(data, entry.Rank)
+		data = append(data, byte(entry.TierId))
+		data = appendUint16(data, entry.MatchStatistics.Played)
+		data = appendUint16(data, entry.MatchStatistics.Won)
+		data = appendUint16(data, entry.MatchStatistics.Lost)
+
+		// Entries without a summary report an item level of zero.
+		flags := byte(0)
+		if entry.StaleTalents || leaderboard.Historical {
+			flags |= entryFlagStaleTalents
+		}
+		itemLevel := 0
+		if entry.Summary != nil {
+			itemLevel = entry.Summary.EquippedItemLevel
+		}
+		data = append(data, flags)
+		data = appendUint16(data, uint(max(itemLevel, 0)))
+
 		encodedData := base64.StdEncoding.EncodeToString(data)
 		entryData := strings.Join([]string{encodedData, entry.Player.Name, entry.Loadout.Code}, "|")
 		entries = append(entries, entryData)
@@ -129,13 +160,21 @@ func ExportLeaderboardToJson(leaderboard *site.EnrichedLeaderboard) ([]byte, err
 			Version: EncodingVersion,
 			Realms:  realms,
 		},
-		Entries:   entries,
-		Timestamp: time.Now().UnixMilli(),
+		Entries:    entries,
+		Timestamp:  leaderboard.Timestamp.UnixMilli(),
+		Historical: leaderboard.Historical,
+		Illegal:    leaderboard.IllegalLoadouts,
 	}
 
 	return json.MarshalIndent(output, "", "  ")
 }
 
+// appendUint16 appends the value in little endian order, clamped to 16 bits.
+func appendUint16(data []byte, value uint) []byte {
+	value = min(value, 0xFFFF)
+	return append(data, byte(value&0xFF), byte((value>>8)&0xFF))
+}
+
 func createTalentMap(tree *wow.TalentTree) map[int]int {
 	talentIds := make([]int, 0, len(tree.ClassNodes)+len(tree.SpecNodes))
 	idsSeen := make(map[int]bool, len(tree.ClassNodes)+len(tree.SpecNodes))
@@ -210,3 +249,72 @@ func createRealmMap(realms []realmJson) map[string]int {
 	}
 	return realmMap
 }
+
+type unresolvedJson struct {
+	Entries   []unresolvedEntryJson `json:"entries"`
+	Timestamp int64                 `json:"timestamp"`
+}
+
+type unresolvedEntryJson struct {
+	Name    string `json:"name"`
+	Realm   string `json:"realm"`
+	Faction string `json:"faction"`
+	Reason  string `json:"reason"`
+	Error   string `json:"error"`
+	Rating  uint   `json:"rating"`
+	// SpecId and Violations are only set for illegal loadouts.
+	SpecId     int             `json:"spec_id,omitempty"`
+	Violations []violationJson `json:"violations,omitempty"`
+}
+
+type violationJson struct {
+	Kind    string `json:"kind"`
+	Section string `json:"section"`
+	NodeId  int    `json:"node_id,omitempty"`
+	Message string `json:"message"`
+}
+
+// ExportUnresolvedToJson lists leaderboard entries whose talents couldn't be
+// retrieved or were illegal, along with why. The timestamp is when the
+// leaderboard they came from was retrieved.
+func ExportUnresolvedToJson(unresolved []site.UnresolvedEntry, timestamp time.Time) ([]byte, error) {
+	entries := make([]unresolvedEntryJson, len(unresolved))
+	for i, entry := range unresolved {
+		entries[i] = unresolvedEntryJson{
+			Name:    entry.Entry.Player.Name,
+			Realm:   entry.Entry.Player.Realm.Slug,
+			Faction: entry.Entry.Faction,
+			Reason:  unresolvedReason(entry.Error),
+			Error:   entry.Error.Error(),
+			Rating:  entry.Entry.Rating,
+		}
+
+		var illegal *legality.IllegalLoadoutError
+		if errors.As(entry.Error, &illegal) {
+			entries[i].SpecId = entry.Entry.SpecId
+			entries[i].Violations = make([]violationJson, len(illegal.Violations))
+			for j, violation := range illegal.Violations {
+				entries[i].Violations[j] = violationJson{
+					Kind:    string(violation.Kind),
+					Section: string(violation.Section),
+					NodeId:  violation.NodeId,
+					Message: violation.Message,
+				}
+			}
+		}
+	}
+
+	output := unresolvedJson{
+		Entries:   entries,
+		Timestamp: timestamp.UnixMilli(),
+	}
+	return json.MarshalIndent(output, "", "  ")
+}
+
+func unresolvedReason(err error) string {
+	var illegal *legality.IllegalLoadoutError
+	if errors.As(err, &illegal) {
+		return "illegal_loadout"
+	}
+	return scan.ErrorKind(err)
+}
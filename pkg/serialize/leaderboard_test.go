@@ -0,0 +1,139 @@
+package serialize
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/legality"
+	"github.com/crbednarz/moonkinmetrics/pkg/scan"
+	"github.com/crbednarz/moonkinmetrics/pkg/site"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockLeaderboard(entries ...site.EnrichedLeaderboardEntry) site.EnrichedLeaderboard {
+	tree := wow.TalentTree{
+		ClassName:  "Druid",
+		SpecName:   "Balance",
+		ClassNodes: mockNodes(2),
+	}
+	return site.EnrichedLeaderboard{
+		RealmMap:  map[string]wow.Realm{"tichondrius": {Name: "Tichondrius"}},
+		Entries:   entries,
+		ClassName: "Druid",
+		SpecName:  "Balance",
+		Bracket:   "3v3",
+		Tree:      &tree,
+		Timestamp: time.UnixMilli(1700000000000),
+	}
+}
+
+func mockLeaderboardEntry(name string, stale bool) site.EnrichedLeaderboardEntry {
+	return site.EnrichedLeaderboardEntry{
+		Loadout: &wow.Loadout{
+			ClassNodes: []wow.LoadoutNode{{NodeId: 1, TalentId: 1, Rank: 1}},
+		},
+		Rating: 2400,
+		Player: wow.PlayerLink{
+			Name:  name,
+			Realm: wow.RealmLink{Slug: "tichondrius"},
+		},
+		StaleTalents: stale,
+	}
+}
+
+// exportedEntryFlags returns the flags byte of each exported entry.
+func exportedEntryFlags(t *testing.T, output leaderboardJson) []byte {
+	t.Helper()
+	flags := make([]byte, len(output.Entries))
+	for i, entry := range output.Entries {
+		encoded, _, _ := strings.Cut(entry, "|")
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		require.NoError(t, err)
+		// The flags byte is followed only by the item level.
+		flags[i] = data[len(data)-3]
+	}
+	return flags
+}
+
+func TestExportLeaderboardFlagsStaleTalents(t *testing.T) {
+	leaderboard := mockLeaderboard(
+		mockLeaderboardEntry("Fresh", false),
+		mockLeaderboardEntry("Stale", true),
+	)
+
+	data, err := ExportLeaderboardToJson(&leaderboard)
+	require.NoError(t, err)
+
+	var output leaderboardJson
+	require.NoError(t, json.Unmarshal(data, &output))
+	assert.Equal(t, EncodingVersion, output.Encoding.Version)
+	assert.Equal(t, int64(1700000000000), output.Timestamp)
+	assert.False(t, output.Historical)
+	assert.Equal(t, []byte{0, entryFlagStaleTalents}, exportedEntryFlags(t, output))
+}
+
+func TestExportHistoricalLeaderboardFlagsEveryEntry(t *testing.T) {
+	leaderboard := mockLeaderboard(
+		mockLeaderboardEntry("First", false),
+		mockLeaderboardEntry("Second", false),
+	)
+	leaderboard.Historical = true
+
+	data, err := ExportLeaderboardToJson(&leaderboard)
+	require.NoError(t, err)
+
+	var output leaderboardJson
+	require.NoError(t, json.Unmarshal(data, &output))
+	assert.True(t, output.Historical)
+	assert.Equal(t, []byte{entryFlagStaleTalents, entryFlagStaleTalents}, exportedEntryFlags(t, output))
+}
+
+func TestExportLeaderboardReportsIllegalLoadouts(t *testing.T) {
+	leaderboard := mockLeaderboard(mockLeaderboardEntry("Legal", false))
+	leaderboard.IllegalLoadouts = 2
+
+	data, err := ExportLeaderboardToJson(&leaderboard)
+	require.NoError(t, err)
+
+	var output leaderboardJson
+	require.NoError(t, json.Unmarshal(data, &output))
+	assert.Equal(t, 2, output.Illegal)
+}
+
+func TestExportUnresolvedIncludesViolations(t *testing.T) {
+	illegal := &legality.IllegalLoadoutError{
+		Violations: []legality.Violation{
+			{Kind: legality.KindRank, Section: legality.SectionSpec, NodeId: 12, Message: "too many ranks"},
+		},
+	}
+	unresolved := []site.UnresolvedEntry{
+		{
+			Entry: wow.LeaderboardEntry{Player: wow.PlayerLink{Name: "Illegal"}, SpecId: 102},
+			Error: illegal,
+		},
+		{
+			Entry: wow.LeaderboardEntry{Player: wow.PlayerLink{Name: "Missing"}},
+			Error: scan.ErrNotFound,
+		},
+	}
+
+	data, err := ExportUnresolvedToJson(unresolved, time.UnixMilli(1700000000000))
+	require.NoError(t, err)
+
+	var output unresolvedJson
+	require.NoError(t, json.Unmarshal(data, &output))
+	require.Len(t, output.Entries, 2)
+	assert.Equal(t, int64(1700000000000), output.Timestamp)
+
+	assert.Equal(t, "illegal_loadout", output.Entries[0].Reason)
+	assert.Equal(t, 102, output.Entries[0].SpecId)
+	assert.Equal(t, []violationJson{{"rank", "spec", 12, "too many ranks"}}, output.Entries[0].Violations)
+
+	assert.Equal(t, 0, output.Entries[1].SpecId)
+	assert.Empty(t, output.Entries[1].Violations)
+}
@@ -0,0 +1,87 @@
+package serialize
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/site"
+)
+
+type savedLoadoutsJson struct {
+	ClassName string             `json:"class"`
+	SpecName  string             `json:"spec"`
+	Bracket   string             `json:"bracket"`
+	Loadouts  []savedLoadoutJson `json:"loadouts"`
+	Players   int                `json:"players"`
+	Timestamp int64              `json:"timestamp"`
+}
+
+type savedLoadoutJson struct {
+	Code string `json:"code"`
+	// Weight is the loadout's share of the leaderboard's players. Each player
+	// contributes a total weight of one, split evenly between their loadouts.
+	Weight float64 `json:"weight"`
+	// Players is how many players have the loadout saved.
+	Players int `json:"players"`
+	// Active is how many players have the loadout active.
+	Active int `json:"active"`
+}
+
+// ExportSavedLoadoutsToJson aggregates every loadout the leaderboard's players
+// have saved, weighted so that players with many saved loadouts don't
+// outweigh those with few. Entries without saved loadouts are skipped.
+func ExportSavedLoadoutsToJson(leaderboard *site.EnrichedLeaderboard) ([]byte, error) {
+	loadoutMap := make(map[string]*savedLoadoutJson)
+	players := 0
+	for _, entry := range leaderboard.Entries {
+		if len(entry.SavedLoadouts) == 0 {
+			continue
+		}
+		players++
+
+		// Identical loadouts saved more than once are only counted once.
+		codes := make([]string, 0, len(entry.SavedLoadouts))
+		for _, loadout := range entry.SavedLoadouts {
+			if !slices.Contains(codes, loadout.Code) {
+				codes = append(codes, loadout.Code)
+			}
+		}
+
+		weight := 1 / float64(len(codes))
+		for _, code := range codes {
+			loadout, ok := loadoutMap[code]
+			if !ok {
+				loadout = &savedLoadoutJson{Code: code}
+				loadoutMap[code] = loadout
+			}
+			loadout.Weight += weight
+			loadout.Players++
+			if entry.Loadout != nil && entry.Loadout.Code == code {
+				loadout.Active++
+			}
+		}
+	}
+
+	loadouts := make([]savedLoadoutJson, 0, len(loadoutMap))
+	for _, loadout := range loadoutMap {
+		loadout.Weight /= float64(players)
+		loadouts = append(loadouts, *loadout)
+	}
+	slices.SortFunc(loadouts, func(a, b savedLoadoutJson) int {
+		if a.Weight != b.Weight {
+			return cmp.Compare(b.Weight, a.Weight)
+		}
+		return cmp.Compare(a.Code, b.Code)
+	})
+
+	output := savedLoadoutsJson{
+		ClassName: leaderboard.ClassName,
+		SpecName:  leaderboard.SpecName,
+		Bracket:   leaderboard.Bracket,
+		Loadouts:  loadouts,
+		Players:   players,
+		Timestamp: leaderboard.Timestamp.UnixMilli(),
+	}
+	return json.MarshalIndent(output, "", "  ")
+}
@@ -0,0 +1,65 @@
+package serialize
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/site"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockSavedEntry(active string, codes ...string) site.EnrichedLeaderboardEntry {
+	saved := make([]wow.Loadout, len(codes))
+	for i, code := range codes {
+		saved[i] = wow.Loadout{Code: code}
+	}
+	return site.EnrichedLeaderboardEntry{
+		Loadout:       &wow.Loadout{Code: active},
+		SavedLoadouts: saved,
+	}
+}
+
+func TestCanExportSavedLoadouts(t *testing.T) {
+	leaderboard := site.EnrichedLeaderboard{
+		ClassName: "Druid",
+		SpecName:  "Balance",
+		Bracket:   "3v3",
+		Timestamp: time.UnixMilli(1700000000000),
+		Entries: []site.EnrichedLeaderboardEntry{
+			mockSavedEntry("A", "A", "B"),
+			mockSavedEntry("A", "A", "A"),
+			mockSavedEntry("C", "C", "B", "A", "D"),
+			mockSavedEntry("E"),
+		},
+	}
+
+	data, err := ExportSavedLoadoutsToJson(&leaderboard)
+	assert.NoError(t, err)
+
+	var output savedLoadoutsJson
+	err = json.Unmarshal(data, &output)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 3, output.Players)
+	assert.Equal(t, int64(1700000000000), output.Timestamp)
+	assert.Len(t, output.Loadouts, 4)
+
+	first := output.Loadouts[0]
+	assert.Equal(t, "A", first.Code)
+	assert.Equal(t, 3, first.Players)
+	assert.Equal(t, 2, first.Active)
+	assert.InDelta(t, (0.5+1+0.25)/3, first.Weight, 0.0001)
+
+	second := output.Loadouts[1]
+	assert.Equal(t, "B", second.Code)
+	assert.Equal(t, 0, second.Active)
+	assert.InDelta(t, (0.5+0.25)/3, second.Weight, 0.0001)
+
+	total := 0.0
+	for _, loadout := range output.Loadouts {
+		total += loadout.Weight
+	}
+	assert.InDelta(t, 1, total, 0.0001)
+}
@@ -1,28 +1,53 @@
 package serialize
 
 import (
+	_ "embed"
 	"encoding/json"
+	"fmt"
 
 	"github.com/crbednarz/moonkinmetrics/pkg/wow"
 )
 
+// TalentsSchema is the JSON Schema of the output of ExportTalentsToJson.
+//
+//go:embed schema/talents.schema.json
+var TalentsSchema []byte
+
 type talentTreeJson struct {
-	ClassName  string           `json:"class_name"`
-	SpecName   string           `json:"spec_name"`
-	ClassNodes []talentNodeJson `json:"class_nodes"`
-	SpecNodes  []talentNodeJson `json:"spec_nodes"`
-	PvpTalents []talentJson     `json:"pvp_talents"`
-	HeroTrees  []heroTreeJson   `json:"hero_trees"`
-	ClassId    int              `json:"class_id"`
-	SpecId     int              `json:"spec_id"`
+	ClassName   string           `json:"class_name"`
+	SpecName    string           `json:"spec_name"`
+	ClassNodes  []talentNodeJson `json:"class_nodes"`
+	SpecNodes   []talentNodeJson `json:"spec_nodes"`
+	PvpTalents  []talentJson     `json:"pvp_talents"`
+	HeroTrees   []heroTreeJson   `json:"hero_trees"`
+	ApexTalents []apexTalentJson `json:"apex_talents"`
+	ClassId     int              `json:"class_id"`
+	SpecId      int              `json:"spec_id"`
+	// HeroSelectionNodeId is needed to encode loadouts with a hero tree.
+	HeroSelectionNodeId int `json:"hero_selection_node_id,omitempty"`
 }
 
 type heroTreeJson struct {
 	Name  string           `json:"name"`
+	Icon  string           `json:"icon"`
 	Nodes []talentNodeJson `json:"nodes"`
 	Id    int              `json:"id"`
 }
 
+// apexTalentJson is one of the talents which make up the ranks of a spec's
+// apex talent. The apex node in the spec tree already merges them into a
+// single talent, so these are only needed to match the talents reported in
+// player loadouts.
+type apexTalentJson struct {
+	talentJson
+	FirstRank int `json:"first_rank"`
+	LastRank  int `json:"last_rank"`
+}
+
+// apexTalentRanks is the range of apex ranks provided by each talent in
+// wow.TalentTree.ApexTalents.
+var apexTalentRanks = [][2]int{{1, 1}, {2, 3}, {4, 4}}
+
 type talentNodeJson struct {
 	Unlocks  []int        `json:"unlocks"`
 	LockedBy []int        `json:"locked_by"`
@@ -76,6 +101,7 @@ func ExportTalentsToJson(talents *wow.TalentTree) ([]byte, error) {
 		}
 		heroTrees[i] = heroTreeJson{
 			Name:  heroTree.Name,
+			Icon:  heroTree.Icon,
 			Nodes: nodes,
 			Id:    heroTree.Id,
 		}
@@ -86,15 +112,23 @@ func ExportTalentsToJson(talents *wow.TalentTree) ([]byte, error) {
 		return nil, err
 	}
 
+	apexTalents, err := convertApexTalents(talents.ApexTalents)
+	if err != nil {
+		return nil, err
+	}
+
 	tree := talentTreeJson{
-		ClassName:  talents.ClassName,
-		SpecName:   talents.SpecName,
-		ClassId:    talents.ClassId,
-		SpecId:     talents.SpecId,
-		ClassNodes: classNodes,
-		SpecNodes:  specNodes,
-		PvpTalents: pvpTalents,
-		HeroTrees:  heroTrees,
+		ClassName:   talents.ClassName,
+		SpecName:    talents.SpecName,
+		ClassId:     talents.ClassId,
+		SpecId:      talents.SpecId,
+		ClassNodes:  classNodes,
+		SpecNodes:   specNodes,
+		PvpTalents:  pvpTalents,
+		HeroTrees:   heroTrees,
+		ApexTalents: apexTalents,
+
+		HeroSelectionNodeId: talents.HeroSelectionNodeId,
 	}
 	return json.MarshalIndent(tree, "", "  ")
 }
@@ -139,6 +173,27 @@ func convertTalents(talents []wow.Talent) ([]talentJson, error) {
 	return jsonTalents, nil
 }
 
+func convertApexTalents(talents []wow.Talent) ([]apexTalentJson, error) {
+	if len(talents) > len(apexTalentRanks) {
+		return nil, fmt.Errorf("expected at most %d apex talents, got %d", len(apexTalentRanks), len(talents))
+	}
+
+	jsonTalents, err := convertTalents(talents)
+	if err != nil {
+		return nil, err
+	}
+
+	apexTalents := make([]apexTalentJson, len(jsonTalents))
+	for i, talent := range jsonTalents {
+		apexTalents[i] = apexTalentJson{
+			talentJson: talent,
+			FirstRank:  apexTalentRanks[i][0],
+			LastRank:   apexTalentRanks[i][1],
+		}
+	}
+	return apexTalents, nil
+}
+
 func convertRanks(ranks []wow.Rank) []rankJson {
 	jsonRanks := make([]rankJson, len(ranks))
 	for i, rank := range ranks {
@@ -152,3 +207,93 @@ func convertRanks(ranks []wow.Rank) []rankJson {
 	}
 	return jsonRanks
 }
+
+// ImportTalentsFromJson reads a tree written by ExportTalentsToJson. Talent
+// gates aren't part of the export, so they're left empty.
+func ImportTalentsFromJson(data []byte) (wow.TalentTree, error) {
+	var treeJson talentTreeJson
+	err := json.Unmarshal(data, &treeJson)
+	if err != nil {
+		return wow.TalentTree{}, fmt.Errorf("invalid talent tree: %w", err)
+	}
+
+	heroTrees := make([]wow.HeroTree, len(treeJson.HeroTrees))
+	for i, heroTree := range treeJson.HeroTrees {
+		heroTrees[i] = wow.HeroTree{
+			Id:    heroTree.Id,
+			Name:  heroTree.Name,
+			Icon:  heroTree.Icon,
+			Nodes: importNodes(heroTree.Nodes),
+		}
+	}
+
+	apexTalents := make([]wow.Talent, len(treeJson.ApexTalents))
+	for i, talent := range treeJson.ApexTalents {
+		apexTalents[i] = importTalent(talent.talentJson)
+	}
+
+	pvpTalents := make([]wow.Talent, len(treeJson.PvpTalents))
+	for i, talent := range treeJson.PvpTalents {
+		pvpTalents[i] = importTalent(talent)
+	}
+
+	return wow.TalentTree{
+		ClassName:   treeJson.ClassName,
+		SpecName:    treeJson.SpecName,
+		ClassId:     treeJson.ClassId,
+		SpecId:      treeJson.SpecId,
+		ClassNodes:  importNodes(treeJson.ClassNodes),
+		SpecNodes:   importNodes(treeJson.SpecNodes),
+		HeroTrees:   heroTrees,
+		ApexTalents: apexTalents,
+		PvpTalents:  pvpTalents,
+
+		HeroSelectionNodeId: treeJson.HeroSelectionNodeId,
+	}, nil
+}
+
+func importNodes(jsonNodes []talentNodeJson) []wow.TalentNode {
+	nodes := make([]wow.TalentNode, len(jsonNodes))
+	for i, node := range jsonNodes {
+		talents := make([]wow.Talent, len(node.Talents))
+		for j, talent := range node.Talents {
+			talents[j] = importTalent(talent)
+		}
+		nodes[i] = wow.TalentNode{
+			Id:       node.Id,
+			X:        node.X,
+			Y:        node.Y,
+			Row:      node.Row,
+			Col:      node.Col,
+			LockedBy: node.LockedBy,
+			Unlocks:  node.Unlocks,
+			MaxRank:  node.MaxRank,
+			NodeType: node.NodeType,
+			Talents:  talents,
+		}
+	}
+	return nodes
+}
+
+func importTalent(talent talentJson) wow.Talent {
+	ranks := make([]wow.Rank, len(talent.Spell.Ranks))
+	for i, rank := range talent.Spell.Ranks {
+		ranks[i] = wow.Rank{
+			Description: rank.Description,
+			CastTime:    rank.CastTime,
+			PowerCost:   rank.PowerCost,
+			Range:       rank.Range,
+			Cooldown:    rank.Cooldown,
+		}
+	}
+	return wow.Talent{
+		Id:   talent.Id,
+		Name: talent.Name,
+		Icon: talent.Icon,
+		Spell: wow.Spell{
+			Id:    talent.Spell.Id,
+			Name:  talent.Spell.Name,
+			Ranks: ranks,
+		},
+	}
+}
@@ -2,10 +2,13 @@ package serialize
 
 import (
 	_ "embed"
+	"encoding/json"
 	"testing"
 
 	"github.com/crbednarz/moonkinmetrics/pkg/wow"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
 )
 
 //go:embed testdata/mock-tree.json
@@ -67,3 +70,70 @@ func TestCanExportTalents(t *testing.T) {
 
 	assert.JSONEq(t, string(mockTree), string(serializedTalents))
 }
+
+func TestExportedTalentsMatchSchema(t *testing.T) {
+	tree := wow.TalentTree{
+		ClassName:  "Druid",
+		SpecName:   "Balance",
+		ClassNodes: mockNodes(30),
+		SpecNodes:  mockNodes(31),
+		PvpTalents: mockTalents(4),
+		HeroTrees: []wow.HeroTree{
+			{Name: "Elune's Chosen", Icon: "Hero Icon", Nodes: mockNodes(11), Id: 24},
+		},
+		ApexTalents: mockTalents(3),
+
+		HeroSelectionNodeId: 99808,
+	}
+
+	serializedTalents, err := ExportTalentsToJson(&tree)
+	require.NoError(t, err)
+
+	result, err := gojsonschema.Validate(
+		gojsonschema.NewBytesLoader(TalentsSchema),
+		gojsonschema.NewBytesLoader(serializedTalents),
+	)
+	require.NoError(t, err)
+	assert.True(t, result.Valid(), "%v", result.Errors())
+
+	var output talentTreeJson
+	err = json.Unmarshal(serializedTalents, &output)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Hero Icon", output.HeroTrees[0].Icon)
+	assert.Len(t, output.ApexTalents, 3)
+	assert.Equal(t, 2, output.ApexTalents[1].FirstRank)
+	assert.Equal(t, 3, output.ApexTalents[1].LastRank)
+}
+
+func TestImportedTalentsRoundTrip(t *testing.T) {
+	tree := wow.TalentTree{
+		ClassName:  "Druid",
+		SpecName:   "Balance",
+		ClassId:    11,
+		SpecId:     102,
+		ClassNodes: mockNodes(30),
+		SpecNodes:  mockNodes(31),
+		PvpTalents: mockTalents(4),
+		HeroTrees: []wow.HeroTree{
+			{Name: "Elune's Chosen", Icon: "Hero Icon", Nodes: mockNodes(11), Id: 24},
+		},
+		ApexTalents: mockTalents(3),
+
+		HeroSelectionNodeId: 99808,
+	}
+
+	serializedTalents, err := ExportTalentsToJson(&tree)
+	require.NoError(t, err)
+
+	imported, err := ImportTalentsFromJson(serializedTalents)
+	require.NoError(t, err)
+	assert.Equal(t, 102, imported.SpecId)
+	assert.Len(t, imported.HeroTrees[0].Nodes, 11)
+	assert.Len(t, imported.ApexTalents, 3)
+	assert.Equal(t, 99808, imported.HeroSelectionNodeId)
+
+	reserializedTalents, err := ExportTalentsToJson(&imported)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(serializedTalents), string(reserializedTalents))
+}
@@ -0,0 +1,29 @@
+package site
+
+import (
+	"github.com/crbednarz/moonkinmetrics/pkg/retrieve/players"
+	"github.com/crbednarz/moonkinmetrics/pkg/scan"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+// EnrichEquipment retrieves the equipment of each entry in the leaderboard.
+// Entries whose equipment can't be retrieved are left without any.
+func EnrichEquipment(scanner *scan.Scanner, leaderboard *EnrichedLeaderboard) error {
+	playerLinks := make([]wow.PlayerLink, len(leaderboard.Entries))
+	for i, entry := range leaderboard.Entries {
+		playerLinks[i] = entry.Player
+	}
+
+	responses, err := players.GetPlayerEquipment(scanner, playerLinks, leaderboard.Region)
+	if err != nil {
+		return err
+	}
+
+	for i := range responses {
+		if responses[i].Error != nil {
+			continue
+		}
+		leaderboard.Entries[i].Equipment = &responses[i].Equipment
+	}
+	return nil
+}
@@ -3,8 +3,10 @@ package site
 import (
 	"fmt"
 	"log"
-	"strings"
+	"time"
 
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+	"github.com/crbednarz/moonkinmetrics/pkg/legality"
 	"github.com/crbednarz/moonkinmetrics/pkg/retrieve/players"
 	"github.com/crbednarz/moonkinmetrics/pkg/scan"
 	"github.com/crbednarz/moonkinmetrics/pkg/wow"
@@ -16,14 +18,60 @@ type EnrichedLeaderboard struct {
 	ClassName string
 	SpecName  string
 	Bracket   string
+	Region    api.Region
 	Tree      *wow.TalentTree
+	// IllegalLoadouts is the number of entries whose loadout failed the
+	// legality check, whether or not they were kept.
+	IllegalLoadouts int
+	// Historical is set for leaderboards of past seasons. Their loadouts are
+	// whatever each character has today, not what was played during the season.
+	Historical bool
+	// Timestamp is when the underlying leaderboard was retrieved.
+	Timestamp time.Time
 }
 
 type EnrichedLeaderboardEntry struct {
-	Loadout *wow.Loadout
-	Rating  uint
-	Faction string
-	Player  wow.PlayerLink
+	Loadout         *wow.Loadout
+	Rating          uint
+	Rank            uint
+	TierId          int
+	MatchStatistics wow.MatchStatistics
+	Faction         string
+	Player          wow.PlayerLink
+	// Equipment is only populated once EnrichEquipment has been called.
+	Equipment *wow.Equipment
+	// SavedLoadouts is only populated when enriching with SavedLoadouts.
+	SavedLoadouts []wow.Loadout
+	// Summary and StaleTalents are only populated once EnrichSummaries has
+	// been called.
+	Summary      *wow.CharacterSummary
+	StaleTalents bool
+}
+
+// UnresolvedEntry is a leaderboard entry whose loadout couldn't be retrieved,
+// or wasn't legal. The former is common for past seasons, as characters which
+// have since been renamed, transferred, or deleted no longer have a profile to
+// fetch.
+type UnresolvedEntry struct {
+	Entry wow.LeaderboardEntry
+	Error error
+}
+
+// LegalityPolicy controls how EnrichLeaderboard treats loadouts which aren't
+// legal for their talent tree.
+type LegalityPolicy struct {
+	Options []legality.Option
+	// KeepIllegal reports illegal loadouts without dropping them.
+	KeepIllegal bool
+}
+
+type EnrichOptions struct {
+	Legality LegalityPolicy
+	// SavedLoadouts also retrieves every loadout each player has saved for
+	// their spec, rather than only the active one.
+	SavedLoadouts bool
+	// Historical marks the leaderboard as belonging to a past season.
+	Historical bool
 }
 
 type entryGroup struct {
@@ -37,33 +85,22 @@ type bracketMetadata struct {
 	OverrideSpec string
 }
 
-var bracketMetadataMap map[string]bracketMetadata = createBracketMetadata()
-
-func createBracketMetadata() map[string]bracketMetadata {
+func createBracketMetadata(registry *wow.ClassRegistry) map[string]bracketMetadata {
 	metadataMap := map[string]bracketMetadata{
 		"2v2": {},
 		"3v3": {},
 		"rbg": {},
 	}
 
-	for class, specs := range wow.SpecByClass {
-		for _, spec := range specs {
-			slug := fmt.Sprintf("shuffle-%s-%s", class, spec)
-			slug = strings.ToLower(strings.ReplaceAll(slug, " ", ""))
-			metadataMap[slug] = bracketMetadata{
-				Class:        class,
-				Spec:         spec,
-				OverrideSpec: spec,
-			}
-
-			slug = fmt.Sprintf("blitz-%s-%s", class, spec)
-			slug = strings.ToLower(strings.ReplaceAll(slug, " ", ""))
-			metadataMap[slug] = bracketMetadata{
-				Class:        class,
-				Spec:         spec,
-				OverrideSpec: spec,
-			}
+	for _, spec := range registry.Specs() {
+		metadata := bracketMetadata{
+			Class:        spec.ClassName,
+			Spec:         spec.Name,
+			OverrideSpec: spec.Name,
 		}
+		classSlug := wow.Slugify(spec.ClassName)
+		metadataMap[fmt.Sprintf("shuffle-%s-%s", classSlug, spec.Slug)] = metadata
+		metadataMap[fmt.Sprintf("blitz-%s-%s", classSlug, spec.Slug)] = metadata
 	}
 	return metadataMap
 }
@@ -108,78 +145,171 @@ func mergeApexTalents(loadout *wow.Loadout, tree *wow.TalentTree) error {
 	return nil
 }
 
-func EnrichLeaderboard(scanner *scan.Scanner, leaderboard *wow.Leaderboard, trees []wow.TalentTree) ([]EnrichedLeaderboard, error) {
-	loadouts, err := getLoadouts(scanner, leaderboard)
+// EnrichLeaderboard resolves the loadout of each leaderboard entry and groups
+// the entries by specialization. Entries whose loadout couldn't be resolved
+// are returned separately, as are those dropped for being illegal under the
+// legality policy. The registry is used to recognize per-spec brackets, such
+// as shuffle.
+func EnrichLeaderboard(
+	scanner *scan.Scanner,
+	leaderboard *wow.Leaderboard,
+	trees []wow.TalentTree,
+	registry *wow.ClassRegistry,
+	options EnrichOptions,
+) ([]EnrichedLeaderboard, []UnresolvedEntry, error) {
+	metadata := createBracketMetadata(registry)[leaderboard.Bracket]
+	loadouts, err := getLoadouts(scanner, leaderboard, trees, metadata, options.SavedLoadouts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	entries := make([]EnrichedLeaderboardEntry, 0, len(leaderboard.Entries))
+	unresolved := make([]UnresolvedEntry, 0)
 	for i := range leaderboard.Entries {
 		entry := &leaderboard.Entries[i]
 		loadout := loadouts[i]
 		if loadout.Error != nil {
+			unresolved = append(unresolved, UnresolvedEntry{
+				Entry: *entry,
+				Error: loadout.Error,
+			})
 			continue
 		}
 		entries = append(entries, EnrichedLeaderboardEntry{
-			Player:  entry.Player,
-			Rating:  entry.Rating,
-			Faction: entry.Faction,
-			Loadout: &loadout.Loadout,
+			Player:          entry.Player,
+			Rating:          entry.Rating,
+			Rank:            entry.Rank,
+			TierId:          entry.TierId,
+			MatchStatistics: entry.MatchStatistics,
+			Faction:         entry.Faction,
+			Loadout:         &loadout.Loadout,
+			SavedLoadouts:   loadout.Saved,
 		})
 	}
 
 	realmMap, err := getRealmMap(scanner, leaderboard)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	entriesGroups := groupEntriesBySpec(leaderboard.Bracket, entries, trees)
+	entriesGroups := groupEntriesBySpec(metadata, entries, trees)
 
 	leaderboards := make([]EnrichedLeaderboard, 0, len(entriesGroups))
 	for _, group := range entriesGroups {
 
 		err := applyTalentFixes(group.Entries, group.Tree)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+
+		legalEntries, illegal := checkLegality(group.Entries, group.Tree, options.Legality)
+		if !options.Legality.KeepIllegal {
+			group.Entries = legalEntries
+			unresolved = append(unresolved, illegal...)
 		}
 
 		leaderboard := EnrichedLeaderboard{
-			RealmMap:  filteredRealmMap(realmMap, group.Entries),
-			Entries:   group.Entries,
-			ClassName: group.Tree.ClassName,
-			SpecName:  group.Tree.SpecName,
-			Bracket:   leaderboard.Bracket,
-			Tree:      group.Tree,
+			RealmMap:        filteredRealmMap(realmMap, group.Entries),
+			Entries:         group.Entries,
+			ClassName:       group.Tree.ClassName,
+			SpecName:        group.Tree.SpecName,
+			Bracket:         leaderboard.Bracket,
+			Region:          leaderboard.Region,
+			Tree:            group.Tree,
+			IllegalLoadouts: len(illegal),
+			Historical:      options.Historical,
+			Timestamp:       leaderboard.Timestamp,
 		}
 		leaderboards = append(leaderboards, leaderboard)
 		log.Printf(
-			"Eriched leaderboard [Class: %s, Spec: %s, Bracket: %s]",
+			"Eriched leaderboard [Class: %s, Spec: %s, Bracket: %s, Illegal: %d]",
 			leaderboard.ClassName,
 			leaderboard.SpecName,
 			leaderboard.Bracket,
+			leaderboard.IllegalLoadouts,
 		)
 	}
 
-	return leaderboards, nil
+	return leaderboards, unresolved, nil
 }
 
 // applyTalentFixes attempts to correct known issues with talent reporting.
 // For example, apex talents are reported as 3 separate talents which need to
 // be merged into one.
 func applyTalentFixes(entries []EnrichedLeaderboardEntry, tree *wow.TalentTree) error {
+	loadouts := make([]*wow.Loadout, 0, len(entries))
 	for i := range entries {
-		err := mergeApexTalents(entries[i].Loadout, tree)
+		entry := &entries[i]
+		err := mergeApexTalents(entry.Loadout, tree)
 		if err != nil {
 			return err
 		}
+		loadouts = append(loadouts, entry.Loadout)
+
+		// Saved loadouts may be left over from older versions of the tree, so
+		// any which can't be fixed are dropped rather than failing the scan.
+		saved := make([]wow.Loadout, 0, len(entry.SavedLoadouts))
+		for _, loadout := range entry.SavedLoadouts {
+			err := mergeApexTalents(&loadout, tree)
+			if err != nil {
+				log.Printf("Dropping saved loadout for %s: %v", entry.Player.Name, err)
+				continue
+			}
+			saved = append(saved, loadout)
+		}
+		entry.SavedLoadouts = saved
+		for j := range entry.SavedLoadouts {
+			loadouts = append(loadouts, &entry.SavedLoadouts[j])
+		}
 	}
 
-	err := fixMissingHeroTreeTalents(entries, tree)
+	err := fixMissingHeroTreeTalents(loadouts, tree)
 	return err
 }
 
-func fixMissingHeroTreeTalents(entries []EnrichedLeaderboardEntry, tree *wow.TalentTree) error {
+// checkLegality splits entries into those with legal loadouts, and unresolved
+// entries for those without. Illegal saved loadouts are dropped from otherwise
+// legal entries unless the policy keeps them.
+func checkLegality(entries []EnrichedLeaderboardEntry, tree *wow.TalentTree, policy LegalityPolicy) ([]EnrichedLeaderboardEntry, []UnresolvedEntry) {
+	legal := make([]EnrichedLeaderboardEntry, 0, len(entries))
+	illegal := make([]UnresolvedEntry, 0)
+	for _, entry := range entries {
+		err := legality.Check(entry.Loadout, tree, policy.Options...)
+		if err == nil {
+			if !policy.KeepIllegal {
+				entry.SavedLoadouts = legalLoadouts(entry.SavedLoadouts, tree, policy)
+			}
+			legal = append(legal, entry)
+			continue
+		}
+
+		illegal = append(illegal, UnresolvedEntry{
+			Entry: wow.LeaderboardEntry{
+				Player:          entry.Player,
+				Rating:          entry.Rating,
+				Rank:            entry.Rank,
+				TierId:          entry.TierId,
+				MatchStatistics: entry.MatchStatistics,
+				Faction:         entry.Faction,
+				SpecId:          tree.SpecId,
+			},
+			Error: err,
+		})
+	}
+	return legal, illegal
+}
+
+func legalLoadouts(loadouts []wow.Loadout, tree *wow.TalentTree, policy LegalityPolicy) []wow.Loadout {
+	legal := make([]wow.Loadout, 0, len(loadouts))
+	for i := range loadouts {
+		if legality.Check(&loadouts[i], tree, policy.Options...) == nil {
+			legal = append(legal, loadouts[i])
+		}
+	}
+	return legal
+}
+
+func fixMissingHeroTreeTalents(loadouts []*wow.Loadout, tree *wow.TalentTree) error {
 	roots := make([]*wow.TalentNode, len(tree.HeroTrees))
 	nodeIdToTreeIndex := make(map[int]int)
 	for treeIndex := range tree.HeroTrees {
@@ -204,12 +334,10 @@ func fixMissingHeroTreeTalents(entries []EnrichedLeaderboardEntry, tree *wow.Tal
 		}
 	}
 
-	for entryIndex := range entries {
-		entry := &entries[entryIndex]
-
+	for _, loadout := range loadouts {
 		heroTreeIndex := -1
 		hasRoot := false
-		for _, talent := range entry.Loadout.HeroNodes {
+		for _, talent := range loadout.HeroNodes {
 			if !hasRoot {
 				for _, root := range roots {
 					if root.Id == talent.NodeId {
@@ -231,7 +359,7 @@ func fixMissingHeroTreeTalents(entries []EnrichedLeaderboardEntry, tree *wow.Tal
 
 		if !hasRoot && heroTreeIndex != -1 {
 			root := roots[heroTreeIndex]
-			entry.Loadout.HeroNodes = append(entry.Loadout.HeroNodes, wow.LoadoutNode{
+			loadout.HeroNodes = append(loadout.HeroNodes, wow.LoadoutNode{
 				TalentName: root.Talents[0].Name,
 				TalentId:   root.Talents[0].Id,
 				NodeId:     root.Id,
@@ -242,9 +370,8 @@ func fixMissingHeroTreeTalents(entries []EnrichedLeaderboardEntry, tree *wow.Tal
 	return nil
 }
 
-func groupEntriesBySpec(bracket string, entries []EnrichedLeaderboardEntry, trees []wow.TalentTree) []entryGroup {
+func groupEntriesBySpec(metadata bracketMetadata, entries []EnrichedLeaderboardEntry, trees []wow.TalentTree) []entryGroup {
 	groups := make([]entryGroup, 0, len(trees))
-	metadata := bracketMetadataMap[bracket]
 	for i := range trees {
 		tree := &trees[i]
 		if tree.ClassName != metadata.Class && metadata.Class != "" {
@@ -266,20 +393,58 @@ func groupEntriesBySpec(bracket string, entries []EnrichedLeaderboardEntry, tree
 	return groups
 }
 
-func getLoadouts(scanner *scan.Scanner, leaderboard *wow.Leaderboard) ([]players.LoadoutResponse, error) {
-	playerLinks := make([]wow.PlayerLink, len(leaderboard.Entries))
+// getLoadouts resolves the loadout of each leaderboard entry. Entries ranked
+// as a specific specialization are resolved using that specialization's
+// loadout rather than the player's active one.
+func getLoadouts(
+	scanner *scan.Scanner,
+	leaderboard *wow.Leaderboard,
+	trees []wow.TalentTree,
+	metadata bracketMetadata,
+	savedLoadouts bool,
+) ([]players.LoadoutResponse, error) {
+	specNames := make(map[int]string, len(trees))
+	for i := range trees {
+		specNames[trees[i].SpecId] = trees[i].SpecName
+	}
+
+	loadouts := make([]players.LoadoutResponse, len(leaderboard.Entries))
+	entriesBySpec := make(map[string][]int)
 	for i, entry := range leaderboard.Entries {
-		playerLinks[i] = entry.Player
+		spec := metadata.OverrideSpec
+		if entry.SpecId != 0 {
+			var ok bool
+			spec, ok = specNames[entry.SpecId]
+			if !ok {
+				loadouts[i].Error = fmt.Errorf("unknown specialization id: %d", entry.SpecId)
+				continue
+			}
+		}
+		entriesBySpec[spec] = append(entriesBySpec[spec], i)
 	}
 
-	loadouts, err := players.GetPlayerLoadouts(
-		scanner,
-		playerLinks,
-		players.WithRegion(leaderboard.Region),
-		players.WithOverrideSpec(bracketMetadataMap[leaderboard.Bracket].OverrideSpec),
-	)
-	if err != nil {
-		return nil, err
+	for spec, entryIndices := range entriesBySpec {
+		playerLinks := make([]wow.PlayerLink, len(entryIndices))
+		for i, entryIndex := range entryIndices {
+			playerLinks[i] = leaderboard.Entries[entryIndex].Player
+		}
+
+		opts := []players.LoadoutScanOption{
+			players.WithRegion(leaderboard.Region),
+			players.WithOverrideSpec(spec),
+		}
+		if savedLoadouts {
+			opts = append(opts, players.WithAllLoadouts())
+		}
+
+		specLoadouts, err := players.GetPlayerLoadouts(scanner, playerLinks, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, entryIndex := range entryIndices {
+			loadouts[entryIndex] = specLoadouts[i]
+		}
 	}
 	return loadouts, nil
 }
@@ -0,0 +1,41 @@
+package site
+
+import (
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/retrieve/players"
+	"github.com/crbednarz/moonkinmetrics/pkg/scan"
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+// EnrichSummaries retrieves the profile summary of each entry in the
+// leaderboard. Entries whose summary can't be retrieved are left without one.
+//
+// Talents are read from the character's profile as of their last login, so
+// entries whose last login is more than staleAfter before the leaderboard was
+// retrieved are marked as having stale talents.
+func EnrichSummaries(scanner *scan.Scanner, leaderboard *EnrichedLeaderboard, staleAfter time.Duration) (stale int, err error) {
+	playerLinks := make([]wow.PlayerLink, len(leaderboard.Entries))
+	for i, entry := range leaderboard.Entries {
+		playerLinks[i] = entry.Player
+	}
+
+	responses, err := players.GetCharacterSummaries(scanner, playerLinks, leaderboard.Region)
+	if err != nil {
+		return 0, err
+	}
+
+	staleBefore := leaderboard.Timestamp.Add(-staleAfter)
+	for i := range responses {
+		if responses[i].Error != nil {
+			continue
+		}
+		entry := &leaderboard.Entries[i]
+		entry.Summary = &responses[i].Summary
+		entry.StaleTalents = entry.Summary.LastLogin.Before(staleBefore)
+		if entry.StaleTalents {
+			stale++
+		}
+	}
+	return stale, nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"bytes"
+	"compress/flate"
+	_ "embed"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// A bodyEncoding identifies how a response body is stored.
+// These values are persisted, so existing values must never be changed.
+// If the dictionary is ever retrained, it must be added as a new encoding.
+type bodyEncoding int
+
+const (
+	encodingRaw bodyEncoding = iota
+	encodingFlateResponsesV1
+)
+
+// responsesDictionary holds substrings common to stored responses, such as keys
+// and URLs. Priming the compressor with it greatly improves compression of
+// smaller bodies. It was trained by cmd/dictgen from the players and testutils
+// testdata fixtures, which cover both profile and static data responses.
+//
+//go:embed dictionaries/responses-v1.dict
+var responsesDictionary []byte
+
+// Creating a flate writer is expensive relative to compressing a small body,
+// so writers and readers are pooled and reset between uses.
+var (
+	flateWriterPool = sync.Pool{
+		New: func() any {
+			writer, err := flate.NewWriterDict(io.Discard, flate.DefaultCompression, responsesDictionary)
+			if err != nil {
+				panic(err)
+			}
+			return writer
+		},
+	}
+	flateReaderPool = sync.Pool{
+		New: func() any {
+			return flate.NewReaderDict(bytes.NewReader(nil), responsesDictionary)
+		},
+	}
+)
+
+func compressBody(body []byte) ([]byte, bodyEncoding, error) {
+	var buffer bytes.Buffer
+	writer := flateWriterPool.Get().(*flate.Writer)
+	defer flateWriterPool.Put(writer)
+	writer.Reset(&buffer)
+
+	_, err := writer.Write(body)
+	if err != nil {
+		return nil, encodingRaw, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, encodingRaw, err
+	}
+
+	// Compression can't always help tiny bodies, so keep whichever is smaller.
+	if buffer.Len() >= len(body) {
+		return body, encodingRaw, nil
+	}
+	return buffer.Bytes(), encodingFlateResponsesV1, nil
+}
+
+func decompressBody(data []byte, encoding bodyEncoding) ([]byte, error) {
+	switch encoding {
+	case encodingRaw:
+		return data, nil
+	case encodingFlateResponsesV1:
+		reader := flateReaderPool.Get().(io.ReadCloser)
+		defer flateReaderPool.Put(reader)
+		err := reader.(flate.Resetter).Reset(bytes.NewReader(data), responsesDictionary)
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(reader)
+	default:
+		return nil, fmt.Errorf("storage: unknown body encoding %d", encoding)
+	}
+}
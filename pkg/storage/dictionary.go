@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+const (
+	// dictionaryKmerSize is the length of the substrings counted across samples.
+	dictionaryKmerSize = 8
+	// dictionarySegmentSize is the length of each piece copied into a dictionary.
+	dictionarySegmentSize = 256
+)
+
+type dictionarySegment struct {
+	data  []byte
+	score int
+}
+
+// TrainDictionary builds a flate dictionary of at most size bytes from sample
+// response bodies.
+//
+// This is a simplified form of the COVER algorithm used by zstd. Every 8 byte
+// substring is scored by the number of samples containing it, the samples are
+// split into one epoch per segment, and the segment from each epoch covering
+// the highest scoring unused substrings is kept. Segments are ordered with the
+// best last, as flate encodes closer matches more cheaply.
+func TrainDictionary(samples [][]byte, size int) []byte {
+	data := make([]byte, 0)
+	kmers := make([]int, 0)
+	frequencies := make([]int, 0)
+	lastSample := make([]int, 0)
+	kmerIds := make(map[uint64]int)
+
+	for sampleIndex, sample := range samples {
+		data = append(data, sample...)
+		for i := range sample {
+			if i+dictionaryKmerSize > len(sample) {
+				kmers = append(kmers, -1)
+				continue
+			}
+
+			key := binary.LittleEndian.Uint64(sample[i:])
+			id, ok := kmerIds[key]
+			if !ok {
+				id = len(frequencies)
+				kmerIds[key] = id
+				frequencies = append(frequencies, 0)
+				lastSample = append(lastSample, -1)
+			}
+			if lastSample[id] != sampleIndex {
+				lastSample[id] = sampleIndex
+				frequencies[id]++
+			}
+			kmers = append(kmers, id)
+		}
+	}
+
+	if len(data) <= size {
+		return data
+	}
+
+	// Substrings found in a single sample are unlikely to help any other body.
+	for id, frequency := range frequencies {
+		if frequency < 2 {
+			frequencies[id] = 0
+		}
+	}
+
+	segmentCount := size / dictionarySegmentSize
+	epochSize := max(len(data)/max(segmentCount, 1), dictionarySegmentSize)
+	active := make([]int, len(frequencies))
+	segments := make([]dictionarySegment, 0, segmentCount)
+	for epochStart := 0; epochStart+dictionarySegmentSize <= len(data); epochStart += epochSize {
+		epochEnd := min(epochStart+epochSize, len(data))
+		start, score := bestDictionarySegment(kmers[epochStart:epochEnd], frequencies, active)
+		if score == 0 {
+			continue
+		}
+
+		start += epochStart
+		for _, id := range kmers[start : start+dictionarySegmentSize] {
+			if id != -1 {
+				frequencies[id] = 0
+			}
+		}
+		segments = append(segments, dictionarySegment{
+			data:  data[start : start+dictionarySegmentSize],
+			score: score,
+		})
+	}
+
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].score < segments[j].score
+	})
+	dictionary := make([]byte, 0, size)
+	for _, segment := range segments {
+		dictionary = append(dictionary, segment.data...)
+	}
+	if len(dictionary) > size {
+		dictionary = dictionary[len(dictionary)-size:]
+	}
+	return dictionary
+}
+
+// bestDictionarySegment finds the segment of kmers whose distinct substrings
+// have the highest total frequency. active must be all zeroes, and is left that way.
+func bestDictionarySegment(kmers []int, frequencies []int, active []int) (int, int) {
+	add := func(id int) int {
+		if id == -1 {
+			return 0
+		}
+		active[id]++
+		if active[id] == 1 {
+			return frequencies[id]
+		}
+		return 0
+	}
+	remove := func(id int) int {
+		if id == -1 {
+			return 0
+		}
+		active[id]--
+		if active[id] == 0 {
+			return frequencies[id]
+		}
+		return 0
+	}
+
+	if len(kmers) < dictionarySegmentSize {
+		return 0, 0
+	}
+
+	score := 0
+	for _, id := range kmers[:dictionarySegmentSize] {
+		score += add(id)
+	}
+	bestStart, bestScore := 0, score
+	for start := 1; start+dictionarySegmentSize <= len(kmers); start++ {
+		score -= remove(kmers[start-1])
+		score += add(kmers[start+dictionarySegmentSize-1])
+		if score > bestScore {
+			bestStart, bestScore = start, score
+		}
+	}
+
+	for _, id := range kmers[len(kmers)-dictionarySegmentSize:] {
+		remove(id)
+	}
+	return bestStart, bestScore
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"testing"
+)
+
+func flateSize(t *testing.T, dictionary []byte, body []byte) int {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer, err := flate.NewWriterDict(&buffer, flate.DefaultCompression, dictionary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = writer.Write(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Len()
+}
+
+func TestTrainDictionary(t *testing.T) {
+	samples := make([][]byte, 0)
+	for i := 0; i < 200; i++ {
+		sample := fmt.Sprintf(
+			`{"_links":{"self":{"href":"https://us.api.blizzard.com/data/wow/talent/%d?namespace=static-us"}},"id":%d,"rank":%d,"name":"Talent %d"}`,
+			i*7919, i*7919, i%3, i*104729,
+		)
+		samples = append(samples, []byte(sample))
+	}
+
+	dictionary := TrainDictionary(samples[:100], 1024)
+	if len(dictionary) == 0 || len(dictionary) > 1024 {
+		t.Fatalf("expected dictionary of at most 1024 bytes, got %d", len(dictionary))
+	}
+	if !bytes.Contains(dictionary, []byte("https://us.api.blizzard.com/data/wow/talent/")) {
+		t.Fatalf("expected dictionary to contain the shared URL, got %q", dictionary)
+	}
+
+	// Samples which weren't used for training should still benefit.
+	for _, sample := range samples[100:110] {
+		withDictionary := flateSize(t, dictionary, sample)
+		withoutDictionary := flateSize(t, nil, sample)
+		if withDictionary >= withoutDictionary {
+			t.Fatalf("expected dictionary to improve compression, got %d >= %d", withDictionary, withoutDictionary)
+		}
+	}
+}
+
+func TestTrainDictionaryFromSmallSamples(t *testing.T) {
+	samples := [][]byte{[]byte(`{"id":1}`), []byte(`{"id":2}`)}
+	dictionary := TrainDictionary(samples, 1024)
+	if string(dictionary) != `{"id":1}{"id":2}` {
+		t.Fatalf("expected samples to be used directly, got %q", dictionary)
+	}
+}
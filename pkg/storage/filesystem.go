@@ -0,0 +1,274 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+)
+
+const (
+	filesystemBodySuffix = ".json"
+	filesystemMetaSuffix = ".meta.json"
+)
+
+// Filesystem stores each response as a file under a directory tree of the
+// request's region and namespace, followed by its path, e.g.
+// <root>/us/static-us/data/wow/talent/123.json.
+// The request id and expiry information are kept alongside it in a ".meta.json"
+// sidecar file. Unlike Sqlite, this does not require cgo.
+//
+// Bodies have a suffix, as many requests share their path with the directory
+// of another request (e.g. /data/wow/pvp-season/37 and
+// /data/wow/pvp-season/37/pvp-leaderboard/3v3). Files without a suffix or
+// sidecar are read as responses which never expire. These are also looked up
+// by path alone, which is the layout of the fixtures written by cmd/testgen.
+//
+// Requests which differ only by other query parameters, such as locale, share
+// a file, so only the most recently stored of them is kept.
+type Filesystem struct {
+	root    string
+	options FilesystemOptions
+}
+
+// filesystemFixtureExpiry is reported as the expiry of responses without metadata.
+var filesystemFixtureExpiry = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+type FilesystemOptions struct {
+	NoExpire bool
+}
+
+type filesystemMetaJson struct {
+	Id        string `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+	Expires   int64  `json:"expires"`
+}
+
+func NewFilesystem(root string, options FilesystemOptions) (*Filesystem, error) {
+	err := os.MkdirAll(root, 0o755)
+	if err != nil {
+		return nil, err
+	}
+	return &Filesystem{root: root, options: options}, nil
+}
+
+func (f *Filesystem) Store(request api.Request, response []byte, lifespan time.Duration) error {
+	basePath, err := f.basePath(request)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(basePath), 0o755)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	meta, err := json.Marshal(filesystemMetaJson{
+		Id:        request.Id(),
+		Timestamp: now.Unix(),
+		Expires:   now.Add(lifespan).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// The body is written before the metadata, so a partially written entry
+	// will never appear valid.
+	err = writeFileAtomic(basePath+filesystemBodySuffix, response)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(basePath+filesystemMetaSuffix, meta)
+}
+
+func (f *Filesystem) Get(request api.Request) (StoredResponse, error) {
+	basePath, err := f.basePath(request)
+	if err != nil {
+		return StoredResponse{}, err
+	}
+
+	meta, err := readFilesystemMeta(basePath + filesystemMetaSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f.getFixture(request, basePath)
+	}
+	if err != nil {
+		return StoredResponse{}, err
+	}
+
+	if meta.Id != request.Id() {
+		return StoredResponse{}, ErrNotFound
+	}
+	if !f.options.NoExpire && meta.Expires < time.Now().Unix() {
+		return StoredResponse{}, ErrNotFound
+	}
+
+	body, err := os.ReadFile(basePath + filesystemBodySuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return StoredResponse{}, ErrNotFound
+	}
+	if err != nil {
+		return StoredResponse{}, err
+	}
+
+	return StoredResponse{
+		Body:      body,
+		Timestamp: time.Unix(meta.Timestamp, 0),
+		Expires:   time.Unix(meta.Expires, 0),
+	}, nil
+}
+
+func (f *Filesystem) Clean() (CleanResult, error) {
+	if f.options.NoExpire {
+		return CleanResult{}, nil
+	}
+
+	now := time.Now().Unix()
+	result := CleanResult{}
+	err := filepath.WalkDir(f.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(path, filesystemMetaSuffix) {
+			return nil
+		}
+
+		meta, err := readFilesystemMeta(path)
+		if err != nil {
+			return err
+		}
+		if meta.Expires >= now {
+			return nil
+		}
+
+		bodyPath := strings.TrimSuffix(path, filesystemMetaSuffix) + filesystemBodySuffix
+		info, err := os.Stat(bodyPath)
+		if err == nil {
+			result.ReclaimedBytes += info.Size()
+		}
+
+		err = os.Remove(path)
+		if err != nil {
+			return err
+		}
+		err = os.Remove(bodyPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		result.Deleted++
+		return nil
+	})
+	return result, err
+}
+
+// basePath returns the path of the response, without a file suffix.
+func (f *Filesystem) basePath(request api.Request) (string, error) {
+	requestUrl, relativePath, err := parseRequestPath(request)
+	if err != nil {
+		return "", err
+	}
+
+	region, _, _ := strings.Cut(requestUrl.Hostname(), ".")
+	namespace := requestUrl.Query().Get("namespace")
+	for _, segment := range []string{region, namespace} {
+		if segment == "" || segment == "." || segment == ".." || strings.ContainsAny(segment, `/\`) {
+			return "", fmt.Errorf("storage: unable to build path for request: %s", request.Id())
+		}
+	}
+	return filepath.Join(f.root, region, namespace, relativePath), nil
+}
+
+// getFixture reads a response without metadata, first from the request's
+// own path, then from its path alone.
+func (f *Filesystem) getFixture(request api.Request, basePath string) (StoredResponse, error) {
+	response, err := readFilesystemFixture(basePath)
+	if !errors.Is(err, ErrNotFound) {
+		return response, err
+	}
+
+	_, relativePath, err := parseRequestPath(request)
+	if err != nil {
+		return StoredResponse{}, err
+	}
+	return readFilesystemFixture(filepath.Join(f.root, relativePath))
+}
+
+// parseRequestPath returns the request's url, and its path relative to the
+// root.
+func parseRequestPath(request api.Request) (*url.URL, string, error) {
+	requestUrl, err := url.Parse(request.Id())
+	if err != nil {
+		return nil, "", fmt.Errorf("storage: request id is not a url: %w", err)
+	}
+
+	// Clean against the root to prevent a request from escaping the cache directory.
+	relativePath := filepath.FromSlash(filepath.Clean("/" + requestUrl.Path))
+	if relativePath == string(filepath.Separator) {
+		return nil, "", fmt.Errorf("storage: unable to build path for request: %s", request.Id())
+	}
+	return requestUrl, relativePath, nil
+}
+
+// readFilesystemFixture reads a response written without metadata, as done by
+// cmd/testgen.
+func readFilesystemFixture(path string) (StoredResponse, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.Mode().IsRegular()) {
+		return StoredResponse{}, ErrNotFound
+	}
+	if err != nil {
+		return StoredResponse{}, err
+	}
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return StoredResponse{}, err
+	}
+	return StoredResponse{
+		Body:      body,
+		Timestamp: info.ModTime(),
+		Expires:   filesystemFixtureExpiry,
+	}, nil
+}
+
+func readFilesystemMeta(path string) (filesystemMetaJson, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return filesystemMetaJson{}, err
+	}
+
+	var meta filesystemMetaJson
+	err = json.Unmarshal(data, &meta)
+	if err != nil {
+		return filesystemMetaJson{}, fmt.Errorf("storage: invalid metadata in %s: %w", path, err)
+	}
+	return meta, nil
+}
+
+// writeFileAtomic writes to a temporary file before renaming it into place,
+// so readers never observe a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+)
+
+func TestFilesystem(t *testing.T) {
+	runStorageSuite(t, func(t *testing.T) ResponseStorage {
+		fs, err := NewFilesystem(t.TempDir(), FilesystemOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fs
+	})
+}
+
+func TestFilesystemMirrorsRequestPath(t *testing.T) {
+	root := t.TempDir()
+	fs, err := NewFilesystem(root, FilesystemOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := api.BnetRequest{
+		Region:    api.RegionUS,
+		Namespace: api.NamespaceStatic,
+		Path:      "/data/wow/talent/123",
+	}
+	response := []byte("{\"id\": 123}")
+
+	err = fs.Store(&request, response, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := os.ReadFile(filepath.Join(root, "us", "static-us", "data", "wow", "talent", "123.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != string(response) {
+		t.Fatalf("expected %s, got %s", response, body)
+	}
+}
+
+func TestFilesystemStoresNestedRequests(t *testing.T) {
+	fs, err := NewFilesystem(t.TempDir(), FilesystemOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	season := api.BnetRequest{
+		Region:    api.RegionUS,
+		Namespace: api.NamespaceDynamic,
+		Path:      "/data/wow/pvp-season/37",
+	}
+	leaderboard := api.BnetRequest{
+		Region:    api.RegionUS,
+		Namespace: api.NamespaceDynamic,
+		Path:      "/data/wow/pvp-season/37/pvp-leaderboard/3v3",
+	}
+	for _, request := range []*api.BnetRequest{&season, &leaderboard} {
+		err = fs.Store(request, []byte(request.Path), time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, request := range []*api.BnetRequest{&season, &leaderboard} {
+		response, err := fs.Get(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(response.Body) != request.Path {
+			t.Fatalf("expected %s, got %s", request.Path, response.Body)
+		}
+	}
+}
+
+func TestFilesystemSeparatesRegionsAndNamespaces(t *testing.T) {
+	fs, err := NewFilesystem(t.TempDir(), FilesystemOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := []api.BnetRequest{
+		{Region: api.RegionUS, Namespace: api.NamespaceStatic, Path: "/data/wow/talent/123"},
+		{Region: api.RegionEU, Namespace: api.NamespaceStatic, Path: "/data/wow/talent/123"},
+		{Region: api.RegionUS, Namespace: api.NamespaceDynamic, Path: "/data/wow/talent/123"},
+	}
+	for i := range requests {
+		err = fs.Store(&requests[i], []byte(requests[i].Id()), time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range requests {
+		response, err := fs.Get(&requests[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(response.Body) != requests[i].Id() {
+			t.Fatalf("expected %s, got %s", requests[i].Id(), response.Body)
+		}
+	}
+}
+
+func TestFilesystemIgnoresOtherRegions(t *testing.T) {
+	fs, err := NewFilesystem(t.TempDir(), FilesystemOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := api.BnetRequest{
+		Region:    api.RegionUS,
+		Namespace: api.NamespaceStatic,
+		Path:      "/data/wow/talent/123",
+	}
+	err = fs.Store(&request, []byte("{\"id\": 123}"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request.Region = api.RegionEU
+	_, err = fs.Get(&request)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestFilesystemReadsTestgenFixtures(t *testing.T) {
+	fs, err := NewFilesystem("../testutils/testdata", FilesystemOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := api.BnetRequest{
+		Region:    api.RegionUS,
+		Namespace: api.NamespaceStatic,
+		Path:      "/data/wow/talent/106507",
+	}
+	response, err := fs.Get(&request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := os.ReadFile("../testutils/testdata/data/wow/talent/106507")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(response.Body) != string(expected) {
+		t.Fatalf("expected %s, got %s", expected, response.Body)
+	}
+	if !response.Expires.After(time.Now()) {
+		t.Fatalf("expected fixture to never expire, got %s", response.Expires)
+	}
+
+	request.Path = "/data/wow/talent"
+	_, err = fs.Get(&request)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a directory, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+)
+
+// storeHistory records a new version of a response, unless it is identical to
+// the most recent version. Versions beyond historySize are pruned, oldest first.
+func storeHistory(tx *sql.Tx, id string, response []byte, data []byte, encoding bodyEncoding, now time.Time, historySize int) error {
+	hashBytes := sha256.Sum256(response)
+	hash := hex.EncodeToString(hashBytes[:])
+
+	var latestHash string
+	err := tx.QueryRow(
+		"SELECT hash FROM ApiResponseHistory WHERE id = ? ORDER BY version DESC LIMIT 1",
+		id,
+	).Scan(&latestHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if latestHash == hash {
+		return nil
+	}
+
+	// Versions are keyed by insertion order, so versions stored within the same
+	// millisecond are all kept.
+	_, err = tx.Exec(
+		"INSERT INTO ApiResponseHistory (id, timestamp, hash, data, encoding) VALUES (?, ?, ?, ?, ?)",
+		id,
+		now.UnixMilli(),
+		hash,
+		data,
+		encoding,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`DELETE FROM ApiResponseHistory WHERE id = ? AND version NOT IN (
+			SELECT version FROM ApiResponseHistory WHERE id = ? ORDER BY version DESC LIMIT ?
+		)`,
+		id,
+		id,
+		historySize,
+	)
+	return err
+}
+
+func (s *Sqlite) GetAt(request api.Request, at time.Time) (StoredResponse, error) {
+	row := s.db.QueryRow(
+		"SELECT data, encoding, timestamp FROM ApiResponseHistory WHERE id = ? AND timestamp <= ? ORDER BY timestamp DESC, version DESC LIMIT 1",
+		request.Id(),
+		at.UnixMilli(),
+	)
+	var data []byte
+	var encoding bodyEncoding
+	var timestamp int64
+	err := row.Scan(&data, &encoding, &timestamp)
+	if errors.Is(err, sql.ErrNoRows) {
+		return StoredResponse{}, ErrNotFound
+	}
+	if err != nil {
+		return StoredResponse{}, err
+	}
+
+	body, err := decompressBody(data, encoding)
+	if err != nil {
+		return StoredResponse{}, err
+	}
+	return StoredResponse{
+		Body:      body,
+		Timestamp: time.UnixMilli(timestamp),
+	}, nil
+}
+
+func (s *Sqlite) History(request api.Request) ([]StoredResponse, error) {
+	rows, err := s.db.Query(
+		"SELECT data, encoding, timestamp FROM ApiResponseHistory WHERE id = ? ORDER BY version ASC",
+		request.Id(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]StoredResponse, 0)
+	for rows.Next() {
+		var data []byte
+		var encoding bodyEncoding
+		var timestamp int64
+		err = rows.Scan(&data, &encoding, &timestamp)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := decompressBody(data, encoding)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, StoredResponse{
+			Body:      body,
+			Timestamp: time.UnixMilli(timestamp),
+		})
+	}
+	return history, rows.Err()
+}
@@ -0,0 +1,229 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+)
+
+// newClock returns a clock which starts at a fixed time and only moves when
+// advanced.
+func newClock() (func() time.Time, func(time.Duration)) {
+	now := time.Date(2024, time.July, 1, 12, 0, 0, 0, time.UTC)
+	return func() time.Time { return now }, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestHistoryKeepsDistinctVersions(t *testing.T) {
+	clock, advance := newClock()
+	db, err := NewSqlite(":memory:", SqliteOptions{HistorySize: 2, Now: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := api.BnetRequest{
+		Region:    api.RegionUS,
+		Namespace: api.NamespaceProfile,
+		Path:      "/data/wow/character/tichondrius/charactername",
+	}
+	versions := []string{
+		"{\"value\": \"1\"}",
+		"{\"value\": \"1\"}",
+		"{\"value\": \"2\"}",
+		"{\"value\": \"3\"}",
+	}
+
+	timestamps := make([]time.Time, len(versions))
+	for i, version := range versions {
+		advance(time.Second)
+		timestamps[i] = clock()
+		err = db.Store(&request, []byte(version), time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := db.History(&request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 versions, got %d", len(history))
+	}
+	if string(history[0].Body) != versions[2] || string(history[1].Body) != versions[3] {
+		t.Fatalf("expected versions %s and %s, got %s and %s", versions[2], versions[3], history[0].Body, history[1].Body)
+	}
+
+	storedResponse, err := db.GetAt(&request, timestamps[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(storedResponse.Body) != versions[2] {
+		t.Fatalf("expected %s, got %s", versions[2], storedResponse.Body)
+	}
+
+	_, err = db.GetAt(&request, timestamps[0].Add(-time.Hour))
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestHistoryKeepsVersionsStoredAtOnce(t *testing.T) {
+	clock, _ := newClock()
+	db, err := NewSqlite(":memory:", SqliteOptions{HistorySize: 3, Now: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := api.BnetRequest{
+		Region:    api.RegionUS,
+		Namespace: api.NamespaceProfile,
+		Path:      "/data/wow/character/tichondrius/charactername",
+	}
+	versions := []string{
+		"{\"value\": \"1\"}",
+		"{\"value\": \"2\"}",
+		"{\"value\": \"3\"}",
+	}
+	for _, version := range versions {
+		err = db.Store(&request, []byte(version), time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := db.History(&request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != len(versions) {
+		t.Fatalf("expected %d versions, got %d", len(versions), len(history))
+	}
+	for i, version := range versions {
+		if string(history[i].Body) != version {
+			t.Fatalf("expected version %d to be %s, got %s", i, version, history[i].Body)
+		}
+	}
+
+	storedResponse, err := db.GetAt(&request, clock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(storedResponse.Body) != versions[2] {
+		t.Fatalf("expected %s, got %s", versions[2], storedResponse.Body)
+	}
+}
+
+func TestHistoryDisabledByDefault(t *testing.T) {
+	db, err := NewSqlite(":memory:", SqliteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := api.BnetRequest{
+		Region:    api.RegionUS,
+		Namespace: api.NamespaceProfile,
+		Path:      "/data/wow/character/tichondrius/charactername",
+	}
+	err = db.Store(&request, []byte("{\"value\": \"1\"}"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := db.History(&request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 0 {
+		t.Fatalf("expected no history, got %d versions", len(history))
+	}
+}
+
+func TestCleanEvictsHistoryByAge(t *testing.T) {
+	clock, advance := newClock()
+	db, err := NewSqlite(":memory:", SqliteOptions{NoExpire: true, HistorySize: 3, MaxAge: time.Hour, Now: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	request := api.BnetRequest{
+		Region:    api.RegionUS,
+		Namespace: api.NamespaceProfile,
+		Path:      "/data/wow/character/tichondrius/charactername",
+	}
+	for _, version := range []string{"{\"value\": \"1\"}", "{\"value\": \"2\"}"} {
+		err = db.Store(&request, []byte(version), time.Hour*24)
+		if err != nil {
+			t.Fatal(err)
+		}
+		advance(time.Hour * 2)
+	}
+
+	result, err := db.Clean()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.EvictedByAge != 1 || result.EvictedHistory != 2 {
+		t.Fatalf("expected 1 response and 2 history versions evicted, got %d and %d", result.EvictedByAge, result.EvictedHistory)
+	}
+
+	history, err := db.History(&request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 0 {
+		t.Fatalf("expected no history, got %d versions", len(history))
+	}
+}
+
+func TestCleanCountsHistoryTowardSize(t *testing.T) {
+	clock, advance := newClock()
+	db, err := NewSqlite(":memory:", SqliteOptions{NoExpire: true, HistorySize: 3, Now: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	request := api.BnetRequest{
+		Region:    api.RegionUS,
+		Namespace: api.NamespaceProfile,
+		Path:      "/data/wow/character/tichondrius/charactername",
+	}
+	for _, version := range []string{"{\"value\": \"1\"}", "{\"value\": \"2\"}"} {
+		err = db.Store(&request, []byte(version), time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		advance(time.Minute)
+	}
+
+	var responseSize int64
+	err = db.db.QueryRow("SELECT LENGTH(data) FROM ApiResponses").Scan(&responseSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The response alone fits, so only history can push the total over.
+	db.options.MaxSize = responseSize * 2
+	result, err := db.Clean()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.EvictedBySize != 0 || result.EvictedHistory != 1 {
+		t.Fatalf("expected only the oldest history version evicted, got %d responses and %d versions", result.EvictedBySize, result.EvictedHistory)
+	}
+
+	history, err := db.History(&request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || string(history[0].Body) != "{\"value\": \"2\"}" {
+		t.Fatalf("expected the latest version to be kept, got %v", history)
+	}
+	_, err = db.Get(&request)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
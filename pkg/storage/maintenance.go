@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// FamilyStats summarizes the stored responses of a single namespace and endpoint family.
+type FamilyStats struct {
+	Namespace string
+	// Family is the leading segments of the request path, e.g. "/data/wow/talent".
+	Family  string
+	Rows    int64
+	Bytes   int64
+	Expired int64
+}
+
+// VacuumResult holds the size of the database file before and after a vacuum.
+type VacuumResult struct {
+	BytesBefore int64
+	BytesAfter  int64
+}
+
+// archiveEntryJson is a single line of an exported cache archive.
+// Archives are gzipped JSON lines, so they're portable between storage
+// encodings and sqlite versions.
+type archiveEntryJson struct {
+	Id        string `json:"id"`
+	Body      []byte `json:"body"`
+	Timestamp int64  `json:"timestamp"`
+	Expires   int64  `json:"expires"`
+}
+
+// requestFamily splits a request id into its namespace and endpoint family.
+func requestFamily(id string) (namespace string, family string) {
+	requestUrl, err := url.Parse(id)
+	if err != nil {
+		return "unknown", "unknown"
+	}
+
+	segments := strings.Split(strings.Trim(requestUrl.Path, "/"), "/")
+	segments = segments[:min(len(segments), 3)]
+	return requestUrl.Query().Get("namespace"), "/" + strings.Join(segments, "/")
+}
+
+// Stats summarizes stored responses by namespace and endpoint family.
+func (s *Sqlite) Stats() ([]FamilyStats, error) {
+	rows, err := s.db.Query("SELECT id, LENGTH(data), expires FROM ApiResponses")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := s.options.Now().Unix()
+	statsMap := make(map[[2]string]*FamilyStats)
+	for rows.Next() {
+		var id string
+		var size int64
+		var expires int64
+		err = rows.Scan(&id, &size, &expires)
+		if err != nil {
+			return nil, err
+		}
+
+		namespace, family := requestFamily(id)
+		key := [2]string{namespace, family}
+		stats, ok := statsMap[key]
+		if !ok {
+			stats = &FamilyStats{Namespace: namespace, Family: family}
+			statsMap[key] = stats
+		}
+		stats.Rows++
+		stats.Bytes += size
+		if expires < now {
+			stats.Expired++
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make([]FamilyStats, 0, len(statsMap))
+	for _, stats := range statsMap {
+		results = append(results, *stats)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Namespace != results[j].Namespace {
+			return results[i].Namespace < results[j].Namespace
+		}
+		return results[i].Family < results[j].Family
+	})
+	return results, nil
+}
+
+// DeletePrefix removes every response, including history, whose request id
+// starts with the given prefix.
+func (s *Sqlite) DeletePrefix(prefix string) (int64, error) {
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	pattern := escaper.Replace(prefix) + "%"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM ApiResponses WHERE id LIKE ? ESCAPE '\'`, pattern)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`DELETE FROM ApiResponseHistory WHERE id LIKE ? ESCAPE '\'`, pattern)
+	if err != nil {
+		return 0, err
+	}
+	return deleted, tx.Commit()
+}
+
+// Vacuum rebuilds the database file, returning unused pages to the filesystem.
+func (s *Sqlite) Vacuum() (VacuumResult, error) {
+	before, err := s.databaseSize()
+	if err != nil {
+		return VacuumResult{}, err
+	}
+
+	_, err = s.db.Exec("VACUUM")
+	if err != nil {
+		return VacuumResult{}, err
+	}
+
+	after, err := s.databaseSize()
+	if err != nil {
+		return VacuumResult{}, err
+	}
+	return VacuumResult{BytesBefore: before, BytesAfter: after}, nil
+}
+
+func (s *Sqlite) databaseSize() (int64, error) {
+	var size int64
+	err := s.db.QueryRow("SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()").Scan(&size)
+	return size, err
+}
+
+// Export writes every non-expired response to w as a portable archive.
+// It returns the number of responses written.
+func (s *Sqlite) Export(w io.Writer) (int64, error) {
+	currentTime := s.options.Now().Unix()
+	if s.options.NoExpire {
+		currentTime = 0
+	}
+
+	rows, err := s.db.Query(
+		"SELECT id, data, encoding, timestamp, expires FROM ApiResponses WHERE expires >= ?",
+		currentTime,
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	gzipWriter := gzip.NewWriter(w)
+	encoder := json.NewEncoder(gzipWriter)
+	var count int64
+	for rows.Next() {
+		var entry archiveEntryJson
+		var data []byte
+		var encoding bodyEncoding
+		err = rows.Scan(&entry.Id, &data, &encoding, &entry.Timestamp, &entry.Expires)
+		if err != nil {
+			return count, err
+		}
+
+		entry.Body, err = decompressBody(data, encoding)
+		if err != nil {
+			return count, err
+		}
+
+		err = encoder.Encode(&entry)
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err = rows.Err(); err != nil {
+		return count, err
+	}
+	return count, gzipWriter.Close()
+}
+
+// Import reads an archive written by Export, replacing any existing responses
+// with the same request id. It returns the number of responses read.
+func (s *Sqlite) Import(r io.Reader) (int64, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, err
+	}
+	defer gzipReader.Close()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	decoder := json.NewDecoder(bufio.NewReader(gzipReader))
+	var count int64
+	for {
+		var entry archiveEntryJson
+		err = decoder.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		data, encoding, err := compressBody(entry.Body)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(
+			"INSERT OR REPLACE INTO ApiResponses (id, data, encoding, timestamp, expires) VALUES (?, ?, ?, ?, ?)",
+			entry.Id,
+			data,
+			encoding,
+			entry.Timestamp,
+			entry.Expires,
+		)
+		if err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return count, tx.Commit()
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+)
+
+func TestStatsGroupsByFamily(t *testing.T) {
+	db, err := NewSqlite(":memory:", SqliteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mockResponses := createMockResponses(3)
+	for i := range mockResponses {
+		err = db.Store(&mockResponses[i].Request, mockResponses[i].Body, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	talentRequest := api.BnetRequest{
+		Region:    api.RegionUS,
+		Namespace: api.NamespaceStatic,
+		Path:      "/data/wow/talent/123",
+	}
+	err = db.Store(&talentRequest, []byte("{}"), -1*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := db.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 families, got %d", len(stats))
+	}
+
+	if stats[0].Namespace != "profile-us" || stats[0].Family != "/data/wow/character" || stats[0].Rows != 3 {
+		t.Errorf("unexpected character stats: %+v", stats[0])
+	}
+	if stats[1].Namespace != "static-us" || stats[1].Family != "/data/wow/talent" || stats[1].Expired != 1 {
+		t.Errorf("unexpected talent stats: %+v", stats[1])
+	}
+}
+
+func TestDeletePrefix(t *testing.T) {
+	db, err := NewSqlite(":memory:", SqliteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mockResponses := createMockResponses(12)
+	for i := range mockResponses {
+		err = db.Store(&mockResponses[i].Request, mockResponses[i].Body, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Matches char1, char10 and char11.
+	deleted, err := db.DeletePrefix("https://us.api.blizzard.com/data/wow/character/tichondrius/char1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 3 {
+		t.Fatalf("expected 3 deleted, got %d", deleted)
+	}
+
+	_, err = db.Get(&mockResponses[1].Request)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	_, err = db.Get(&mockResponses[2].Request)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	source, err := NewSqlite(":memory:", SqliteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mockResponses := createMockResponses(10)
+	for i := range mockResponses {
+		err = source.Store(&mockResponses[i].Request, mockResponses[i].Body, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var archive bytes.Buffer
+	exported, err := source.Export(&archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exported != 10 {
+		t.Fatalf("expected 10 exported, got %d", exported)
+	}
+
+	destination, err := NewSqlite(":memory:", SqliteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := destination.Import(&archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 10 {
+		t.Fatalf("expected 10 imported, got %d", imported)
+	}
+
+	for i := range mockResponses {
+		storedResponse, err := destination.Get(&mockResponses[i].Request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(storedResponse.Body) != string(mockResponses[i].Body) {
+			t.Fatalf("expected %s, got %s", mockResponses[i].Body, storedResponse.Body)
+		}
+	}
+}
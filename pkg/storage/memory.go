@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+)
+
+// MemoryCache is a size-bounded, in-memory LRU cache of response bodies.
+// It wraps another ResponseStorage, which remains the source of truth: stores
+// are written through, and misses fall back to the wrapped storage. Hits are
+// reported to the wrapped storage if it is an AccessTracker.
+type MemoryCache struct {
+	backend  ResponseStorage
+	maxBytes int64
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	size    int64
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// CacheStats holds the hit and miss counts of a MemoryCache.
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+}
+
+type memoryCacheEntry struct {
+	id       string
+	response StoredResponse
+}
+
+func NewMemoryCache(backend ResponseStorage, maxBytes int64) *MemoryCache {
+	return &MemoryCache{
+		backend:  backend,
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (m *MemoryCache) Store(request api.Request, response []byte, lifespan time.Duration) error {
+	err := m.backend.Store(request, response, lifespan)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	m.add(request.Id(), StoredResponse{
+		Body:      response,
+		Timestamp: time.Unix(now.Unix(), 0),
+		Expires:   time.Unix(now.Add(lifespan).Unix(), 0),
+	})
+	return nil
+}
+
+func (m *MemoryCache) Get(request api.Request) (StoredResponse, error) {
+	id := request.Id()
+	response, ok := m.get(id)
+	if ok {
+		m.hits.Add(1)
+		if tracker, ok := m.backend.(AccessTracker); ok {
+			tracker.RecordAccess(request)
+		}
+		return response, nil
+	}
+	m.misses.Add(1)
+
+	response, err := m.backend.Get(request)
+	if err != nil {
+		return response, err
+	}
+	m.add(id, response)
+	return response, nil
+}
+
+func (m *MemoryCache) Clean() (CleanResult, error) {
+	m.lock.Lock()
+	now := time.Now()
+	for element := m.order.Front(); element != nil; {
+		next := element.Next()
+		if element.Value.(*memoryCacheEntry).response.Expires.Before(now) {
+			m.remove(element)
+		}
+		element = next
+	}
+	m.lock.Unlock()
+
+	return m.backend.Clean()
+}
+
+// Stats returns the number of requests served from, and missed by, memory.
+func (m *MemoryCache) Stats() CacheStats {
+	return CacheStats{
+		Hits:   m.hits.Load(),
+		Misses: m.misses.Load(),
+	}
+}
+
+func (m *MemoryCache) get(id string) (StoredResponse, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	element, ok := m.entries[id]
+	if !ok {
+		return StoredResponse{}, false
+	}
+
+	// Expired entries are left to the backend, which may choose to ignore expiry.
+	entry := element.Value.(*memoryCacheEntry)
+	if entry.response.Expires.Before(time.Now()) {
+		m.remove(element)
+		return StoredResponse{}, false
+	}
+
+	m.order.MoveToFront(element)
+	return entry.response, true
+}
+
+func (m *MemoryCache) add(id string, response StoredResponse) {
+	size := int64(len(response.Body))
+	if size > m.maxBytes || response.Expires.Before(time.Now()) {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if element, ok := m.entries[id]; ok {
+		m.remove(element)
+	}
+
+	m.entries[id] = m.order.PushFront(&memoryCacheEntry{
+		id:       id,
+		response: response,
+	})
+	m.size += size
+
+	for m.size > m.maxBytes {
+		m.remove(m.order.Back())
+	}
+}
+
+func (m *MemoryCache) remove(element *list.Element) {
+	entry := m.order.Remove(element).(*memoryCacheEntry)
+	delete(m.entries, entry.id)
+	m.size -= int64(len(entry.response.Body))
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	runStorageSuite(t, func(t *testing.T) ResponseStorage {
+		db, err := NewSqlite(":memory:", SqliteOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewMemoryCache(db, 1024*1024)
+	})
+}
+
+func TestMemoryCacheCountsHits(t *testing.T) {
+	db, err := NewSqlite(":memory:", SqliteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockResponses := createMockResponses(2)
+	err = db.Store(&mockResponses[0].Request, mockResponses[0].Body, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewMemoryCache(db, 1024*1024)
+	for i := 0; i < 3; i++ {
+		_, err = cache.Get(&mockResponses[0].Request)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = cache.Get(&mockResponses[1].Request)
+	if err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 2 {
+		t.Errorf("expected 2 hits, got %d", stats.Hits)
+	}
+	if stats.Misses != 2 {
+		t.Errorf("expected 2 misses, got %d", stats.Misses)
+	}
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	db, err := NewSqlite(":memory:", SqliteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockResponses := createMockResponses(3)
+	bodySize := int64(len(mockResponses[0].Body))
+
+	// Only two of the three responses fit in memory.
+	cache := NewMemoryCache(db, bodySize*2)
+	for i := range mockResponses {
+		err = cache.Store(&mockResponses[i].Request, mockResponses[i].Body, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = cache.Get(&mockResponses[0].Request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cache.Get(&mockResponses[2].Request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("expected 1 hit and 1 miss, got %d hits and %d misses", stats.Hits, stats.Misses)
+	}
+}
+
+func TestMemoryCacheRecordsHitsInBackend(t *testing.T) {
+	clock, advance := newClock()
+	db, err := NewSqlite(":memory:", SqliteOptions{Now: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	mockResponses := createMockResponses(1)
+
+	cache := NewMemoryCache(db, 1024*1024)
+	err = cache.Store(&mockResponses[0].Request, mockResponses[0].Body, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	advance(time.Minute)
+	_, err = cache.Get(&mockResponses[0].Request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats := cache.Stats(); stats.Hits != 1 {
+		t.Fatalf("expected the read to be served from memory, got %d hits", stats.Hits)
+	}
+
+	db.flushAccesses()
+	var accessed int64
+	err = db.db.QueryRow("SELECT accessed FROM ApiResponses").Scan(&accessed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accessed != clock().Unix() {
+		t.Errorf("expected access at %d, got %d", clock().Unix(), accessed)
+	}
+}
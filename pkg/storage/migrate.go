@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// ErrSchemaTooNew is returned when opening a database which was migrated by a
+// newer version of this binary.
+var ErrSchemaTooNew = errors.New("storage: database schema is newer than supported")
+
+type migration struct {
+	Version int
+	Name    string
+	Sql     string
+}
+
+// loadMigrations reads the embedded migrations, ordered by version.
+// Migration files are named "<version>_<description>.sql".
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]migration, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		versionText, _, found := strings.Cut(name, "_")
+		if !found {
+			return nil, fmt.Errorf("invalid migration name: %s", name)
+		}
+		version, err := strconv.Atoi(versionText)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version for %s: %w", name, err)
+		}
+
+		data, err := migrationFiles.ReadFile(path.Join("migrations", name))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration{
+			Version: version,
+			Name:    name,
+			Sql:     string(data),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i := range migrations {
+		if migrations[i].Version != i+1 {
+			return nil, fmt.Errorf("migration %s is out of sequence, expected version %d", migrations[i].Name, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// schemaVersion returns the schema version recorded in the database.
+func schemaVersion(db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRow("PRAGMA user_version").Scan(&version)
+	return version, err
+}
+
+// migrate applies all pending migrations to the database.
+// The schema version is stored in sqlite's user_version pragma, and each
+// migration is applied in its own transaction alongside the version update.
+func migrate(db *sql.DB) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	version, err := schemaVersion(db)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	if version > len(migrations) {
+		return fmt.Errorf("%w: database is at version %d, latest known is %d", ErrSchemaTooNew, version, len(migrations))
+	}
+
+	for _, m := range migrations[version:] {
+		err = applyMigration(db, m)
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(m.Sql)
+	if err != nil {
+		return err
+	}
+
+	// PRAGMA statements can't take bound parameters.
+	_, err = tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", m.Version))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+)
+
+func TestMigratesNewDatabase(t *testing.T) {
+	db, err := NewSqlite(":memory:", SqliteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version, err := schemaVersion(db.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != len(migrations) {
+		t.Fatalf("expected schema version %d, got %d", len(migrations), version)
+	}
+}
+
+func TestMigratesLegacyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wow.db")
+	legacyDb, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = legacyDb.Exec(`CREATE TABLE IF NOT EXISTS ApiResponses(
+    id TEXT NOT NULL,
+    data BLOB NOT NULL,
+    timestamp INTEGER NOT NULL,
+    expires INTEGER NOT NULL,
+    PRIMARY KEY(id)
+);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := api.BnetRequest{
+		Region:    api.RegionUS,
+		Namespace: api.NamespaceProfile,
+		Path:      "/data/wow/character/tichondrius/charactername",
+	}
+	now := time.Now()
+	_, err = legacyDb.Exec(
+		"INSERT INTO ApiResponses (id, data, timestamp, expires) VALUES (?, ?, ?, ?)",
+		request.Id(),
+		[]byte("{\"hello\": \"world\"}"),
+		now.Unix(),
+		now.Add(time.Hour).Unix(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyDb.Close()
+
+	db, err := NewSqlite(path, SqliteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storedResponse, err := db.Get(&request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(storedResponse.Body) != "{\"hello\": \"world\"}" {
+		t.Fatalf("expected legacy response to be readable, got %s", storedResponse.Body)
+	}
+}
+
+func TestRefusesNewerDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wow.db")
+	db, err := NewSqlite(path, SqliteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.db.Exec("PRAGMA user_version = 9999")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	_, err = NewSqlite(path, SqliteOptions{})
+	if !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("expected ErrSchemaTooNew, got %v", err)
+	}
+}
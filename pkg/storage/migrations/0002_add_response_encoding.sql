@@ -0,0 +1 @@
+ALTER TABLE ApiResponses ADD COLUMN encoding INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS ApiResponseHistory(
+    version INTEGER PRIMARY KEY AUTOINCREMENT,
+    id TEXT NOT NULL,
+    timestamp INTEGER NOT NULL,
+    hash TEXT NOT NULL,
+    data BLOB NOT NULL,
+    encoding INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS ApiResponseHistoryById ON ApiResponseHistory(id, timestamp);
@@ -0,0 +1,2 @@
+ALTER TABLE ApiResponses ADD COLUMN accessed INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS ApiResponsesRecency ON ApiResponses(MAX(accessed, timestamp));
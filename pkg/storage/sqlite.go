@@ -2,8 +2,9 @@ package storage
 
 import (
 	"database/sql"
-	_ "embed"
 	"errors"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,86 +12,301 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-//go:embed sqlite-init.sql
-var sqliteInitSql string
-
+// Sqlite stores responses in a sqlite database.
+// The database is opened in WAL mode so reads never wait on writes, and all
+// writes are funneled through a single writer goroutine which batches
+// concurrent stores into shared transactions.
 type Sqlite struct {
-	db      *sql.DB
-	options SqliteOptions
-	lock    sync.RWMutex
+	db         *sql.DB
+	options    SqliteOptions
+	writes     chan *pendingWrite
+	writerDone chan struct{}
+
+	accessLock sync.Mutex
+	accesses   map[string]int64
 }
 
 type SqliteOptions struct {
 	NoExpire bool
+	// HistorySize is the number of versions of each response to retain.
+	// Consecutive versions with identical bodies are only stored once.
+	// Zero disables history.
+	HistorySize int
+	// MaxSize is the total size of stored bodies which Clean will evict
+	// the least recently used responses down to. Zero disables the limit.
+	MaxSize int64
+	// MaxAge is the age beyond which Clean will evict responses, regardless
+	// of their expiry. Zero disables the limit.
+	MaxAge time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
 }
 
 func NewSqlite(path string, options SqliteOptions) (*Sqlite, error) {
-	db, err := sql.Open("sqlite3", path)
+	inMemory := path == ":memory:"
+	dsn := path
+	if !inMemory {
+		separator := "?"
+		if strings.Contains(path, "?") {
+			separator = "&"
+		}
+		dsn = fmt.Sprintf("%s%s_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=10000&_txlock=immediate", path, separator)
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = db.Exec(sqliteInitSql)
+	// Each connection to ":memory:" is its own database, so only one may exist.
+	if inMemory {
+		db.SetMaxOpenConns(1)
+	}
+
+	err = migrate(db)
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
-	return &Sqlite{db: db, options: options}, nil
+
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+
+	s := &Sqlite{
+		db:         db,
+		options:    options,
+		writes:     make(chan *pendingWrite, maxWriteBatch),
+		writerDone: make(chan struct{}),
+		accesses:   make(map[string]int64),
+	}
+	go s.runWriter()
+	return s, nil
+}
+
+// Close waits for pending writes to finish and closes the database.
+// The storage must not be used after it has been closed.
+func (s *Sqlite) Close() error {
+	close(s.writes)
+	<-s.writerDone
+	s.flushAccesses()
+	return s.db.Close()
 }
 
 func (s *Sqlite) Store(request api.Request, response []byte, lifespan time.Duration) error {
-	s.lock.Lock()
-	defer s.lock.Unlock()
-	now := time.Now()
-	_, err := s.db.Exec(
-		"INSERT OR REPLACE INTO ApiResponses (id, data, timestamp, expires) VALUES (?, ?, ?, ?)",
-		request.Id(),
-		response,
-		now.Unix(),
-		now.Add(lifespan).Unix(),
-	)
-	return err
+	data, encoding, err := compressBody(response)
+	if err != nil {
+		return err
+	}
+
+	now := s.options.Now()
+	write := &pendingWrite{
+		id:        request.Id(),
+		response:  response,
+		data:      data,
+		encoding:  encoding,
+		timestamp: now,
+		expires:   now.Add(lifespan),
+		done:      make(chan error, 1),
+	}
+	s.writes <- write
+	return <-write.done
 }
 
 func (s *Sqlite) Get(request api.Request) (StoredResponse, error) {
-	s.lock.RLock()
-	defer s.lock.RUnlock()
-
-	currentTime := time.Now().Unix()
+	currentTime := s.options.Now().Unix()
 	if s.options.NoExpire {
 		currentTime = 0
 	}
 
 	row := s.db.QueryRow(
-		"SELECT data, timestamp FROM ApiResponses WHERE id = ? AND expires >= ?",
+		"SELECT data, encoding, timestamp, expires FROM ApiResponses WHERE id = ? AND expires >= ?",
 		request.Id(),
 		currentTime,
 	)
 	var response StoredResponse
+	var data []byte
+	var encoding bodyEncoding
 	var timestamp int64
-	err := row.Scan(&response.Body, &timestamp)
-	if err == nil {
-		response.Timestamp = time.Unix(timestamp, 0)
-	}
+	var expires int64
+	err := row.Scan(&data, &encoding, &timestamp, &expires)
 	if errors.Is(err, sql.ErrNoRows) {
 		return response, ErrNotFound
 	}
-	return response, err
+	if err != nil {
+		return response, err
+	}
+
+	response.Body, err = decompressBody(data, encoding)
+	if err != nil {
+		return StoredResponse{}, err
+	}
+	response.Timestamp = time.Unix(timestamp, 0)
+	response.Expires = time.Unix(expires, 0)
+	s.recordAccess(request.Id())
+	return response, nil
 }
 
+// Clean deletes expired responses, then evicts responses and their history
+// according to the MaxAge and MaxSize options. Expired responses are kept when
+// NoExpire is set, and history is kept after its response expires.
 func (s *Sqlite) Clean() (CleanResult, error) {
-	if s.options.NoExpire {
-		return CleanResult{}, nil
-	}
-	s.lock.Lock()
-	defer s.lock.Unlock()
-	result, err := s.db.Exec("DELETE FROM ApiResponses WHERE expires < ?", time.Now().Unix())
+	s.flushAccesses()
+
+	tx, err := s.db.Begin()
 	if err != nil {
 		return CleanResult{}, err
 	}
+	defer tx.Rollback()
+
+	now := s.options.Now()
+	result := CleanResult{}
+	if !s.options.NoExpire {
+		deleted, reclaimed, err := deleteResponsesWhere(tx, "expires < ?", now.Unix())
+		if err != nil {
+			return CleanResult{}, err
+		}
+		result.Deleted = deleted
+		result.ReclaimedBytes += reclaimed
+	}
 
-	rowsAffected, err := result.RowsAffected()
+	if s.options.MaxAge > 0 {
+		cutoff := now.Add(-s.options.MaxAge)
+		evicted, reclaimed, err := deleteResponsesWhere(tx, "timestamp < ?", cutoff.Unix())
+		if err != nil {
+			return CleanResult{}, err
+		}
+		result.EvictedByAge = evicted
+		result.ReclaimedBytes += reclaimed
+
+		// History timestamps are stored in milliseconds.
+		evicted, reclaimed, err = deleteHistoryWhere(tx, "timestamp < ?", cutoff.UnixMilli())
+		if err != nil {
+			return CleanResult{}, err
+		}
+		result.EvictedHistory += evicted
+		result.ReclaimedBytes += reclaimed
+	}
+
+	if s.options.MaxSize > 0 {
+		evicted, evictedHistory, reclaimed, err := evictToSize(tx, s.options.MaxSize)
+		if err != nil {
+			return CleanResult{}, err
+		}
+		result.EvictedBySize = evicted
+		result.EvictedHistory += evictedHistory
+		result.ReclaimedBytes += reclaimed
+	}
+
+	err = tx.Commit()
 	if err != nil {
 		return CleanResult{}, err
 	}
-	return CleanResult{Deleted: rowsAffected}, nil
+	return result, nil
+}
+
+func deleteResponsesWhere(tx *sql.Tx, condition string, args ...any) (deleted int64, reclaimed int64, err error) {
+	err = tx.QueryRow("SELECT COALESCE(SUM(LENGTH(data)), 0) FROM ApiResponses WHERE "+condition, args...).Scan(&reclaimed)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	result, err := tx.Exec("DELETE FROM ApiResponses WHERE "+condition, args...)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	deleted, err = result.RowsAffected()
+	return deleted, reclaimed, err
+}
+
+func deleteHistoryWhere(tx *sql.Tx, condition string, args ...any) (deleted int64, reclaimed int64, err error) {
+	err = tx.QueryRow("SELECT COALESCE(SUM(LENGTH(data)), 0) FROM ApiResponseHistory WHERE "+condition, args...).Scan(&reclaimed)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	result, err := tx.Exec("DELETE FROM ApiResponseHistory WHERE "+condition, args...)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	deleted, err = result.RowsAffected()
+	return deleted, reclaimed, err
+}
+
+// evictToSize deletes the least recently used responses and history versions
+// until the total size of stored bodies, including history, is no larger than
+// maxSize. History versions are last used when they were stored.
+func evictToSize(tx *sql.Tx, maxSize int64) (evicted int64, evictedHistory int64, reclaimed int64, err error) {
+	var totalSize int64
+	err = tx.QueryRow(
+		`SELECT
+			(SELECT COALESCE(SUM(LENGTH(data)), 0) FROM ApiResponses) +
+			(SELECT COALESCE(SUM(LENGTH(data)), 0) FROM ApiResponseHistory)`,
+	).Scan(&totalSize)
+	if err != nil || totalSize <= maxSize {
+		return 0, 0, 0, err
+	}
+
+	// Responses have a version of zero, which history versions never use.
+	rows, err := tx.Query(
+		`SELECT id, 0, LENGTH(data), MAX(accessed, timestamp) AS used FROM ApiResponses
+		UNION ALL
+		SELECT id, version, LENGTH(data), timestamp / 1000 AS used FROM ApiResponseHistory
+		ORDER BY used ASC`,
+	)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	ids := make([]string, 0)
+	versions := make([]int64, 0)
+	for totalSize-reclaimed > maxSize && rows.Next() {
+		var id string
+		var version int64
+		var size int64
+		var used int64
+		err = rows.Scan(&id, &version, &size, &used)
+		if err != nil {
+			rows.Close()
+			return 0, 0, 0, err
+		}
+		if version == 0 {
+			ids = append(ids, id)
+		} else {
+			versions = append(versions, version)
+		}
+		reclaimed += size
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, 0, 0, err
+	}
+
+	err = deleteEach(tx, "DELETE FROM ApiResponses WHERE id = ?", ids)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	err = deleteEach(tx, "DELETE FROM ApiResponseHistory WHERE version = ?", versions)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return int64(len(ids)), int64(len(versions)), reclaimed, nil
+}
+
+func deleteEach[T any](tx *sql.Tx, query string, keys []T) error {
+	statement, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	for _, key := range keys {
+		_, err = statement.Exec(key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
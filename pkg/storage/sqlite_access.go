@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"log"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+)
+
+// recordAccess notes that a response was read. Access times are only used to
+// decide eviction order, so they're buffered and written alongside the next
+// batch of stores rather than costing a write per read.
+func (s *Sqlite) recordAccess(id string) {
+	s.accessLock.Lock()
+	defer s.accessLock.Unlock()
+	s.accesses[id] = s.options.Now().Unix()
+}
+
+// RecordAccess notes that the response for request was read elsewhere, such as
+// from a MemoryCache in front of the database.
+func (s *Sqlite) RecordAccess(request api.Request) {
+	s.recordAccess(request.Id())
+}
+
+func (s *Sqlite) flushAccesses() {
+	s.accessLock.Lock()
+	accesses := s.accesses
+	s.accesses = make(map[string]int64)
+	s.accessLock.Unlock()
+
+	if len(accesses) == 0 {
+		return
+	}
+
+	err := s.writeAccesses(accesses)
+	if err != nil {
+		log.Printf("failed to record response access times: %v", err)
+	}
+}
+
+func (s *Sqlite) writeAccesses(accesses map[string]int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statement, err := tx.Prepare("UPDATE ApiResponses SET accessed = ? WHERE id = ? AND accessed < ?")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	for id, accessed := range accesses {
+		_, err = statement.Exec(accessed, id, accessed)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -2,29 +2,26 @@ package storage
 
 import (
 	"errors"
-	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/crbednarz/moonkinmetrics/pkg/api"
 )
 
-func createMockResponses(count int) []Response {
-	responses := make([]Response, count)
-	for i := 0; i < count; i++ {
-		responses[i] = Response{
-			Request: api.BnetRequest{
-				Region:    api.RegionUS,
-				Namespace: api.NamespaceProfile,
-				Path:      fmt.Sprintf("/data/wow/character/tichondrius/char%d", i),
-			},
-			Body: []byte(fmt.Sprintf("{\"value\": %d}}", i)),
+func TestSqlite(t *testing.T) {
+	runStorageSuite(t, func(t *testing.T) ResponseStorage {
+		db, err := NewSqlite(":memory:", SqliteOptions{})
+		if err != nil {
+			t.Fatal(err)
 		}
-	}
-	return responses
+		return db
+	})
 }
 
-func TestCanRetrieve(t *testing.T) {
+func TestStoresCompressed(t *testing.T) {
 	db, err := NewSqlite(":memory:", SqliteOptions{})
 	if err != nil {
 		t.Fatal(err)
@@ -35,139 +32,170 @@ func TestCanRetrieve(t *testing.T) {
 		Namespace: api.NamespaceProfile,
 		Path:      "/data/wow/character/tichondrius/charactername",
 	}
-	response := []byte("{\"hello\": \"world\"}}")
+	response := []byte(strings.Repeat("{\"specializations\": [{\"loadouts\": []}]}", 100))
 
 	err = db.Store(&request, response, time.Hour)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	storedResponse, err := db.Get(&request)
+	var storedSize int
+	err = db.db.QueryRow("SELECT LENGTH(data) FROM ApiResponses WHERE id = ?", request.Id()).Scan(&storedSize)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if storedSize >= len(response) {
+		t.Fatalf("expected stored size to be less than %d, got %d", len(response), storedSize)
+	}
 
+	storedResponse, err := db.Get(&request)
+	if err != nil {
+		t.Fatal(err)
+	}
 	if string(storedResponse.Body) != string(response) {
 		t.Fatalf("expected %s, got %s", response, storedResponse.Body)
 	}
 }
 
-func TestCanExpire(t *testing.T) {
-	db, err := NewSqlite(":memory:", SqliteOptions{})
-	if err != nil {
-		t.Fatal(err)
-	}
+// BenchmarkSqliteBracketScan simulates the storage load of scanning a 7,500
+// player bracket, with up to 100 scan workers storing and reading concurrently.
+func BenchmarkSqliteBracketScan(b *testing.B) {
+	const playerCount = 7500
+	const workerCount = 100
 
-	request := api.BnetRequest{
-		Region:    api.RegionUS,
-		Namespace: api.NamespaceProfile,
-		Path:      "/data/wow/character/tichondrius/charactername",
-	}
-	response := []byte("{\"hello\": \"world\"}}")
+	body := []byte(strings.Repeat("{\"specializations\": [{\"loadouts\": []}]}", 200))
+	mockResponses := createMockResponses(playerCount)
 
-	err = db.Store(&request, response, -1*time.Second)
-	if err != nil {
-		t.Fatal(err)
-	}
+	for i := 0; i < b.N; i++ {
+		db, err := NewSqlite(filepath.Join(b.TempDir(), "wow.db"), SqliteOptions{})
+		if err != nil {
+			b.Fatal(err)
+		}
 
-	_, err = db.Get(&request)
-	if !errors.Is(err, ErrNotFound) {
-		t.Fatalf("expected %s, got %s", ErrNotFound, err)
+		requests := make(chan int, playerCount)
+		for j := range mockResponses {
+			requests <- j
+		}
+		close(requests)
+
+		var wg sync.WaitGroup
+		for w := 0; w < workerCount; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range requests {
+					request := &mockResponses[j].Request
+					_, err := db.Get(request)
+					if !errors.Is(err, ErrNotFound) {
+						b.Errorf("expected ErrNotFound, got %v", err)
+					}
+					err = db.Store(request, body, time.Hour)
+					if err != nil {
+						b.Error(err)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		db.Close()
 	}
 }
 
-func TestCanRetrieveFromMany(t *testing.T) {
-	db, err := NewSqlite(":memory:", SqliteOptions{})
+func TestCleanEvictsLeastRecentlyUsed(t *testing.T) {
+	mockResponses := createMockResponses(3)
+	body := []byte(strings.Repeat("x", 1000))
+
+	db, err := NewSqlite(":memory:", SqliteOptions{NoExpire: true})
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer db.Close()
 
-	mockResponses := createMockResponses(100)
-
-	for i := 0; i < 100; i++ {
-		err = db.Store(&mockResponses[i].Request, mockResponses[i].Body, time.Hour)
+	for i := range mockResponses {
+		err = db.Store(&mockResponses[i].Request, body, time.Hour)
 		if err != nil {
 			t.Fatal(err)
 		}
 	}
 
-	for i := 0; i < 100; i++ {
-		request := mockResponses[i].Request
-		response := mockResponses[i].Body
-
-		storedResponse, err := db.Get(&request)
-		if err != nil {
-			t.Fatal(err)
-		}
-		if string(storedResponse.Body) != string(response) {
-			t.Fatalf("expected %s, got %s", response, storedResponse.Body)
-		}
-	}
-}
-
-func TestCanReplace(t *testing.T) {
-	db, err := NewSqlite(":memory:", SqliteOptions{})
+	var storedSize int64
+	err = db.db.QueryRow("SELECT LENGTH(data) FROM ApiResponses LIMIT 1").Scan(&storedSize)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	request := api.BnetRequest{
-		Region:    api.RegionUS,
-		Namespace: api.NamespaceProfile,
-		Path:      "/data/wow/character/tichondrius/charactername",
+	// Age every entry, then read the first so it becomes the most recently used.
+	_, err = db.db.Exec("UPDATE ApiResponses SET timestamp = timestamp - 3600")
+	if err != nil {
+		t.Fatal(err)
 	}
-	response := []byte("{\"value\": \"1\"}}")
-
-	err = db.Store(&request, response, time.Hour)
+	_, err = db.Get(&mockResponses[0].Request)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	storedResponse, err := db.Get(&request)
+	db.options.MaxSize = storedSize
+	result, err := db.Clean()
 	if err != nil {
 		t.Fatal(err)
 	}
+	if result.EvictedBySize != 2 {
+		t.Fatalf("expected 2 entries evicted by size, got %d", result.EvictedBySize)
+	}
+	if result.ReclaimedBytes != storedSize*2 {
+		t.Fatalf("expected %d bytes reclaimed, got %d", storedSize*2, result.ReclaimedBytes)
+	}
 
-	originalTimestamp := storedResponse.Timestamp
-	time.Sleep(2 * time.Second)
-
-	response = []byte("{\"value\": \"2\"}}")
-
-	err = db.Store(&request, response, time.Hour)
+	_, err = db.Get(&mockResponses[0].Request)
 	if err != nil {
-		t.Fatal(err)
+		t.Fatalf("expected most recently used entry to be kept, got %v", err)
 	}
+	for i := 1; i < len(mockResponses); i++ {
+		_, err = db.Get(&mockResponses[i].Request)
+		if !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+	}
+}
+
+func TestCleanEvictsByAge(t *testing.T) {
+	mockResponses := createMockResponses(2)
 
-	storedResponse, err = db.Get(&request)
+	db, err := NewSqlite(":memory:", SqliteOptions{NoExpire: true, MaxAge: time.Hour})
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer db.Close()
 
-	if string(storedResponse.Body) != string(response) {
-		t.Fatalf("expected %s, got %s", response, storedResponse.Body)
+	for i := range mockResponses {
+		err = db.Store(&mockResponses[i].Request, mockResponses[i].Body, time.Hour*24*365)
+		if err != nil {
+			t.Fatal(err)
+		}
 	}
 
-	if storedResponse.Timestamp.Equal(originalTimestamp) {
-		t.Fatalf("expected timestamps to be different, got %s", storedResponse.Timestamp)
+	_, err = db.db.Exec("UPDATE ApiResponses SET timestamp = timestamp - 7200 WHERE id = ?", mockResponses[0].Request.Id())
+	if err != nil {
+		t.Fatal(err)
 	}
-}
 
-func TestMissing(t *testing.T) {
-	db, err := NewSqlite(":memory:", SqliteOptions{})
+	result, err := db.Clean()
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	request := api.BnetRequest{
-		Region:    api.RegionUS,
-		Namespace: api.NamespaceProfile,
-		Path:      "/data/wow/character/tichondrius/charactername",
+	if result.Deleted != 0 {
+		t.Fatalf("expected no expired entries with NoExpire, got %d", result.Deleted)
+	}
+	if result.EvictedByAge != 1 {
+		t.Fatalf("expected 1 entry evicted by age, got %d", result.EvictedByAge)
 	}
 
-	_, err = db.Get(&request)
-	if err == nil {
-		t.Fatal("expected error, got nil")
-	} else if !errors.Is(err, ErrNotFound) {
+	_, err = db.Get(&mockResponses[0].Request)
+	if !errors.Is(err, ErrNotFound) {
 		t.Fatalf("expected ErrNotFound, got %v", err)
 	}
+	_, err = db.Get(&mockResponses[1].Request)
+	if err != nil {
+		t.Fatal(err)
+	}
 }
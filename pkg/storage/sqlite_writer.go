@@ -0,0 +1,91 @@
+package storage
+
+import "time"
+
+// maxWriteBatch is the largest number of stores committed in one transaction.
+const maxWriteBatch = 256
+
+type pendingWrite struct {
+	id        string
+	response  []byte
+	data      []byte
+	encoding  bodyEncoding
+	timestamp time.Time
+	expires   time.Time
+	done      chan error
+}
+
+// runWriter commits pending writes until the writes channel is closed.
+// Any writes which queue up while a transaction is committing are batched
+// into the next one, so the cost of each fsync is shared between them.
+func (s *Sqlite) runWriter() {
+	defer close(s.writerDone)
+
+	for write := range s.writes {
+		batch := []*pendingWrite{write}
+	collect:
+		for len(batch) < maxWriteBatch {
+			select {
+			case write, ok := <-s.writes:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, write)
+			default:
+				break collect
+			}
+		}
+		s.commitBatch(batch)
+		s.flushAccesses()
+	}
+}
+
+func (s *Sqlite) commitBatch(batch []*pendingWrite) {
+	err := s.writeBatch(batch)
+	if err == nil || len(batch) == 1 {
+		for _, write := range batch {
+			write.done <- err
+		}
+		return
+	}
+
+	// Retry individually so a single bad write doesn't fail the whole batch.
+	for _, write := range batch {
+		write.done <- s.writeBatch([]*pendingWrite{write})
+	}
+}
+
+func (s *Sqlite) writeBatch(batch []*pendingWrite) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statement, err := tx.Prepare("INSERT OR REPLACE INTO ApiResponses (id, data, encoding, timestamp, expires) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	for _, write := range batch {
+		_, err = statement.Exec(
+			write.id,
+			write.data,
+			write.encoding,
+			write.timestamp.Unix(),
+			write.expires.Unix(),
+		)
+		if err != nil {
+			return err
+		}
+
+		if s.options.HistorySize > 0 {
+			err = storeHistory(tx, write.id, write.response, write.data, write.encoding, write.timestamp, s.options.HistorySize)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
@@ -12,6 +12,7 @@ var ErrNotFound = errors.New("storage: not found")
 type StoredResponse struct {
 	Body      []byte
 	Timestamp time.Time
+	Expires   time.Time
 }
 
 type Response struct {
@@ -20,7 +21,17 @@ type Response struct {
 }
 
 type CleanResult struct {
+	// Deleted is the number of expired responses removed.
 	Deleted int64
+	// EvictedByAge is the number of unexpired responses removed for being too old.
+	EvictedByAge int64
+	// EvictedBySize is the number of unexpired responses removed to fit a size limit.
+	EvictedBySize int64
+	// EvictedHistory is the number of earlier versions of responses removed,
+	// whether for age or to fit a size limit.
+	EvictedHistory int64
+	// ReclaimedBytes is the stored size of the removed response bodies.
+	ReclaimedBytes int64
 }
 
 type ResponseStorage interface {
@@ -33,3 +44,20 @@ type ResponseStorage interface {
 	// Cleans up expired responses.
 	Clean() (CleanResult, error)
 }
+
+// AccessTracker is implemented by storage which tracks when responses were
+// last read. Wrappers serving reads on its behalf should report them here.
+type AccessTracker interface {
+	// Notes that the response for the given request was read.
+	RecordAccess(request api.Request)
+}
+
+// HistoryStorage is implemented by storage which can retain earlier versions of
+// a response after it has been replaced.
+type HistoryStorage interface {
+	// Retrieves the most recent version of a response stored at or before the given time.
+	GetAt(request api.Request, at time.Time) (StoredResponse, error)
+
+	// Retrieves every retained version of a response, oldest first.
+	History(request api.Request) ([]StoredResponse, error)
+}
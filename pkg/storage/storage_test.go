@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/api"
+)
+
+type storageFactory func(t *testing.T) ResponseStorage
+
+// runStorageSuite runs the tests which every ResponseStorage implementation must pass.
+func runStorageSuite(t *testing.T, newStorage storageFactory) {
+	t.Run("CanRetrieve", func(t *testing.T) { testCanRetrieve(t, newStorage) })
+	t.Run("CanExpire", func(t *testing.T) { testCanExpire(t, newStorage) })
+	t.Run("CanRetrieveFromMany", func(t *testing.T) { testCanRetrieveFromMany(t, newStorage) })
+	t.Run("CanReplace", func(t *testing.T) { testCanReplace(t, newStorage) })
+	t.Run("Missing", func(t *testing.T) { testMissing(t, newStorage) })
+	t.Run("CleanReportsReclaimed", func(t *testing.T) { testCleanReportsReclaimed(t, newStorage) })
+}
+
+func createMockResponses(count int) []Response {
+	responses := make([]Response, count)
+	for i := 0; i < count; i++ {
+		responses[i] = Response{
+			Request: api.BnetRequest{
+				Region:    api.RegionUS,
+				Namespace: api.NamespaceProfile,
+				Path:      fmt.Sprintf("/data/wow/character/tichondrius/char%d", i),
+			},
+			Body: []byte(fmt.Sprintf("{\"value\": %d}}", i)),
+		}
+	}
+	return responses
+}
+
+func testCanRetrieve(t *testing.T, newStorage storageFactory) {
+	db := newStorage(t)
+
+	request := api.BnetRequest{
+		Region:    api.RegionUS,
+		Namespace: api.NamespaceProfile,
+		Path:      "/data/wow/character/tichondrius/charactername",
+	}
+	response := []byte("{\"hello\": \"world\"}}")
+
+	err := db.Store(&request, response, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storedResponse, err := db.Get(&request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(storedResponse.Body) != string(response) {
+		t.Fatalf("expected %s, got %s", response, storedResponse.Body)
+	}
+}
+
+func testCanExpire(t *testing.T, newStorage storageFactory) {
+	db := newStorage(t)
+
+	request := api.BnetRequest{
+		Region:    api.RegionUS,
+		Namespace: api.NamespaceProfile,
+		Path:      "/data/wow/character/tichondrius/charactername",
+	}
+	response := []byte("{\"hello\": \"world\"}}")
+
+	err := db.Store(&request, response, -1*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Get(&request)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected %s, got %s", ErrNotFound, err)
+	}
+}
+
+func testCanRetrieveFromMany(t *testing.T, newStorage storageFactory) {
+	db := newStorage(t)
+
+	mockResponses := createMockResponses(100)
+
+	for i := 0; i < 100; i++ {
+		err := db.Store(&mockResponses[i].Request, mockResponses[i].Body, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		request := mockResponses[i].Request
+		response := mockResponses[i].Body
+
+		storedResponse, err := db.Get(&request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(storedResponse.Body) != string(response) {
+			t.Fatalf("expected %s, got %s", response, storedResponse.Body)
+		}
+	}
+}
+
+func testCanReplace(t *testing.T, newStorage storageFactory) {
+	db := newStorage(t)
+
+	request := api.BnetRequest{
+		Region:    api.RegionUS,
+		Namespace: api.NamespaceProfile,
+		Path:      "/data/wow/character/tichondrius/charactername",
+	}
+	response := []byte("{\"value\": \"1\"}}")
+
+	err := db.Store(&request, response, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storedResponse, err := db.Get(&request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	originalTimestamp := storedResponse.Timestamp
+	time.Sleep(2 * time.Second)
+
+	response = []byte("{\"value\": \"2\"}}")
+
+	err = db.Store(&request, response, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storedResponse, err = db.Get(&request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(storedResponse.Body) != string(response) {
+		t.Fatalf("expected %s, got %s", response, storedResponse.Body)
+	}
+
+	if storedResponse.Timestamp.Equal(originalTimestamp) {
+		t.Fatalf("expected timestamps to be different, got %s", storedResponse.Timestamp)
+	}
+}
+
+func testMissing(t *testing.T, newStorage storageFactory) {
+	db := newStorage(t)
+
+	request := api.BnetRequest{
+		Region:    api.RegionUS,
+		Namespace: api.NamespaceProfile,
+		Path:      "/data/wow/character/tichondrius/charactername",
+	}
+
+	_, err := db.Get(&request)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	} else if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func testCleanReportsReclaimed(t *testing.T, newStorage storageFactory) {
+	db := newStorage(t)
+
+	mockResponses := createMockResponses(10)
+	for i := range mockResponses {
+		lifespan := time.Hour
+		if i%2 == 0 {
+			lifespan = -1 * time.Second
+		}
+		err := db.Store(&mockResponses[i].Request, mockResponses[i].Body, lifespan)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := db.Clean()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Deleted != 5 {
+		t.Fatalf("expected 5 deleted, got %d", result.Deleted)
+	}
+	if result.ReclaimedBytes <= 0 {
+		t.Fatalf("expected reclaimed bytes to be positive, got %d", result.ReclaimedBytes)
+	}
+}
@@ -0,0 +1,432 @@
+// Package talentdiff compares talent trees between builds, such as before and
+// after a patch, to produce patch notes and catch regressions in the API.
+package talentdiff
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+type ChangeKind string
+
+const (
+	// KindSpecAdded is a spec which only exists in the new trees.
+	KindSpecAdded ChangeKind = "spec_added"
+	// KindSpecRemoved is a spec which only exists in the old trees.
+	KindSpecRemoved ChangeKind = "spec_removed"
+	// KindNodeAdded is a node which only exists in the new tree.
+	KindNodeAdded ChangeKind = "node_added"
+	// KindNodeRemoved is a node which only exists in the old tree.
+	KindNodeRemoved ChangeKind = "node_removed"
+	// KindNodeMoved is a node with a new row or column.
+	KindNodeMoved ChangeKind = "node_moved"
+	// KindMaxRank is a node with a new max rank.
+	KindMaxRank ChangeKind = "max_rank"
+	// KindChoice is a node whose choice of talents changed, including
+	// nodes which became or stopped being choice nodes.
+	KindChoice ChangeKind = "choice"
+	// KindTalentReplaced is a node which holds a different talent.
+	KindTalentReplaced ChangeKind = "talent_replaced"
+	// KindTalentRenamed is a talent with a new name.
+	KindTalentRenamed ChangeKind = "talent_renamed"
+	// KindDescription is a rank of a talent with a new description.
+	KindDescription ChangeKind = "description"
+	// KindPvpTalentAdded is a PvP talent which only exists in the new tree.
+	KindPvpTalentAdded ChangeKind = "pvp_talent_added"
+	// KindPvpTalentRemoved is a PvP talent which only exists in the old tree.
+	KindPvpTalentRemoved ChangeKind = "pvp_talent_removed"
+	// KindHeroTreeAdded is a hero tree which only exists in the new tree.
+	KindHeroTreeAdded ChangeKind = "hero_tree_added"
+	// KindHeroTreeRemoved is a hero tree which only exists in the old tree.
+	KindHeroTreeRemoved ChangeKind = "hero_tree_removed"
+	// KindHeroTreeRenamed is a hero tree with a new name.
+	KindHeroTreeRenamed ChangeKind = "hero_tree_renamed"
+	// KindApexTalentAdded is an apex talent which only exists in the new tree.
+	KindApexTalentAdded ChangeKind = "apex_talent_added"
+	// KindApexTalentRemoved is an apex talent which only exists in the old tree.
+	KindApexTalentRemoved ChangeKind = "apex_talent_removed"
+)
+
+const (
+	SectionClass = "class"
+	SectionSpec  = "spec"
+	SectionApex  = "apex"
+	SectionPvp   = "pvp"
+)
+
+// Change is a single difference between two versions of a spec's tree.
+type Change struct {
+	Kind ChangeKind
+	// Section is the part of the tree changed: class, spec, apex, pvp, or the
+	// name of a hero tree. It's empty for changes to the whole spec.
+	Section string
+	// NodeId is the changed node, or zero for changes outside of nodes.
+	NodeId int
+	// TalentId is the changed talent, or zero for changes to a whole node.
+	TalentId int
+	// Old and New are the values before and after the change, where the
+	// change is to a single value such as a description.
+	Old     string
+	New     string
+	Message string
+}
+
+// SpecDiff is every change to a single spec.
+type SpecDiff struct {
+	ClassName string
+	SpecName  string
+	SpecId    int
+	Changes   []Change
+}
+
+// Diff is every change between two sets of trees. Specs without changes are
+// left out.
+type Diff struct {
+	Specs []SpecDiff
+}
+
+// Compare finds the changes between two sets of trees, matching specs by id.
+func Compare(oldTrees []wow.TalentTree, newTrees []wow.TalentTree) Diff {
+	oldSpecs := make(map[int]*wow.TalentTree, len(oldTrees))
+	for i := range oldTrees {
+		oldSpecs[oldTrees[i].SpecId] = &oldTrees[i]
+	}
+	newSpecs := make(map[int]*wow.TalentTree, len(newTrees))
+	for i := range newTrees {
+		newSpecs[newTrees[i].SpecId] = &newTrees[i]
+	}
+
+	specs := make([]SpecDiff, 0)
+	for i := range newTrees {
+		newTree := &newTrees[i]
+		oldTree, ok := oldSpecs[newTree.SpecId]
+		if !ok {
+			specs = append(specs, specDiff(newTree, []Change{{
+				Kind:    KindSpecAdded,
+				Message: "spec added",
+			}}))
+			continue
+		}
+
+		changes := compareTrees(oldTree, newTree)
+		if len(changes) != 0 {
+			specs = append(specs, specDiff(newTree, changes))
+		}
+	}
+	for i := range oldTrees {
+		oldTree := &oldTrees[i]
+		if _, ok := newSpecs[oldTree.SpecId]; !ok {
+			specs = append(specs, specDiff(oldTree, []Change{{
+				Kind:    KindSpecRemoved,
+				Message: "spec removed",
+			}}))
+		}
+	}
+
+	slices.SortFunc(specs, func(a, b SpecDiff) int {
+		return cmp.Or(
+			cmp.Compare(a.ClassName, b.ClassName),
+			cmp.Compare(a.SpecName, b.SpecName),
+		)
+	})
+	return Diff{Specs: specs}
+}
+
+// WriteText writes a human readable summary of a diff, grouping changes by
+// spec.
+func WriteText(w io.Writer, diff *Diff) error {
+	if len(diff.Specs) == 0 {
+		_, err := fmt.Fprintln(w, "No changes")
+		return err
+	}
+
+	for i, spec := range diff.Specs {
+		if i != 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s - %s\n", spec.ClassName, spec.SpecName); err != nil {
+			return err
+		}
+		for _, change := range spec.Changes {
+			line := change.Message
+			if change.Section != "" {
+				line = fmt.Sprintf("[%s] %s", change.Section, change.Message)
+			}
+			if _, err := fmt.Fprintf(w, "  %s\n", line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func specDiff(tree *wow.TalentTree, changes []Change) SpecDiff {
+	return SpecDiff{
+		ClassName: tree.ClassName,
+		SpecName:  tree.SpecName,
+		SpecId:    tree.SpecId,
+		Changes:   changes,
+	}
+}
+
+func compareTrees(oldTree *wow.TalentTree, newTree *wow.TalentTree) []Change {
+	changes := make([]Change, 0)
+	changes = compareNodes(changes, SectionClass, oldTree.ClassNodes, newTree.ClassNodes)
+	changes = compareNodes(changes, SectionSpec, oldTree.SpecNodes, newTree.SpecNodes)
+	changes = compareHeroTrees(changes, oldTree.HeroTrees, newTree.HeroTrees)
+	changes = compareTalents(changes, SectionApex, KindApexTalentAdded, KindApexTalentRemoved, oldTree.ApexTalents, newTree.ApexTalents)
+	changes = compareTalents(changes, SectionPvp, KindPvpTalentAdded, KindPvpTalentRemoved, oldTree.PvpTalents, newTree.PvpTalents)
+	return changes
+}
+
+func compareNodes(changes []Change, section string, oldNodes []wow.TalentNode, newNodes []wow.TalentNode) []Change {
+	oldNodeMap := make(map[int]*wow.TalentNode, len(oldNodes))
+	for i := range oldNodes {
+		oldNodeMap[oldNodes[i].Id] = &oldNodes[i]
+	}
+	newNodeIds := make(map[int]bool, len(newNodes))
+
+	for i := range newNodes {
+		newNode := &newNodes[i]
+		newNodeIds[newNode.Id] = true
+		oldNode, ok := oldNodeMap[newNode.Id]
+		if !ok {
+			changes = append(changes, Change{
+				Kind:    KindNodeAdded,
+				Section: section,
+				NodeId:  newNode.Id,
+				Message: fmt.Sprintf("added %s (node %d)", talentNames(newNode.Talents), newNode.Id),
+			})
+			continue
+		}
+		changes = compareNode(changes, section, oldNode, newNode)
+	}
+
+	for i := range oldNodes {
+		oldNode := &oldNodes[i]
+		if !newNodeIds[oldNode.Id] {
+			changes = append(changes, Change{
+				Kind:    KindNodeRemoved,
+				Section: section,
+				NodeId:  oldNode.Id,
+				Message: fmt.Sprintf("removed %s (node %d)", talentNames(oldNode.Talents), oldNode.Id),
+			})
+		}
+	}
+	return changes
+}
+
+func compareNode(changes []Change, section string, oldNode *wow.TalentNode, newNode *wow.TalentNode) []Change {
+	name := talentNames(newNode.Talents)
+	if oldNode.Row != newNode.Row || oldNode.Col != newNode.Col {
+		changes = append(changes, Change{
+			Kind:    KindNodeMoved,
+			Section: section,
+			NodeId:  newNode.Id,
+			Old:     fmt.Sprintf("%d,%d", oldNode.Row, oldNode.Col),
+			New:     fmt.Sprintf("%d,%d", newNode.Row, newNode.Col),
+			Message: fmt.Sprintf(
+				"moved %s from row %d, column %d to row %d, column %d",
+				name,
+				oldNode.Row,
+				oldNode.Col,
+				newNode.Row,
+				newNode.Col,
+			),
+		})
+	}
+
+	if oldNode.MaxRank != newNode.MaxRank {
+		changes = append(changes, Change{
+			Kind:    KindMaxRank,
+			Section: section,
+			NodeId:  newNode.Id,
+			Old:     fmt.Sprint(oldNode.MaxRank),
+			New:     fmt.Sprint(newNode.MaxRank),
+			Message: fmt.Sprintf("%s max rank changed from %d to %d", name, oldNode.MaxRank, newNode.MaxRank),
+		})
+	}
+
+	if !slices.Equal(talentIds(oldNode.Talents), talentIds(newNode.Talents)) {
+		kind := KindTalentReplaced
+		if len(oldNode.Talents) > 1 || len(newNode.Talents) > 1 {
+			kind = KindChoice
+		}
+		oldNames := talentNames(oldNode.Talents)
+		changes = append(changes, Change{
+			Kind:    kind,
+			Section: section,
+			NodeId:  newNode.Id,
+			Old:     oldNames,
+			New:     name,
+			Message: fmt.Sprintf("node %d changed from %s to %s", newNode.Id, oldNames, name),
+		})
+	}
+
+	for i := range newNode.Talents {
+		newTalent := &newNode.Talents[i]
+		for j := range oldNode.Talents {
+			if oldNode.Talents[j].Id == newTalent.Id {
+				changes = compareTalent(changes, section, newNode.Id, &oldNode.Talents[j], newTalent)
+				break
+			}
+		}
+	}
+	return changes
+}
+
+func compareTalent(changes []Change, section string, nodeId int, oldTalent *wow.Talent, newTalent *wow.Talent) []Change {
+	if oldTalent.Name != newTalent.Name {
+		changes = append(changes, Change{
+			Kind:     KindTalentRenamed,
+			Section:  section,
+			NodeId:   nodeId,
+			TalentId: newTalent.Id,
+			Old:      oldTalent.Name,
+			New:      newTalent.Name,
+			Message:  fmt.Sprintf("%s renamed to %s", oldTalent.Name, newTalent.Name),
+		})
+	}
+
+	oldRanks := oldTalent.Spell.Ranks
+	newRanks := newTalent.Spell.Ranks
+	for rank := 0; rank < max(len(oldRanks), len(newRanks)); rank++ {
+		oldDescription := ""
+		if rank < len(oldRanks) {
+			oldDescription = oldRanks[rank].Description
+		}
+		newDescription := ""
+		if rank < len(newRanks) {
+			newDescription = newRanks[rank].Description
+		}
+		if oldDescription == newDescription {
+			continue
+		}
+
+		message := fmt.Sprintf("%s description changed", newTalent.Name)
+		if len(oldRanks) > 1 || len(newRanks) > 1 {
+			message = fmt.Sprintf("%s rank %d description changed", newTalent.Name, rank+1)
+		}
+		changes = append(changes, Change{
+			Kind:     KindDescription,
+			Section:  section,
+			NodeId:   nodeId,
+			TalentId: newTalent.Id,
+			Old:      oldDescription,
+			New:      newDescription,
+			Message:  message,
+		})
+	}
+	return changes
+}
+
+func compareHeroTrees(changes []Change, oldTrees []wow.HeroTree, newTrees []wow.HeroTree) []Change {
+	for i := range newTrees {
+		newTree := &newTrees[i]
+		oldIndex := slices.IndexFunc(oldTrees, func(tree wow.HeroTree) bool {
+			return tree.Id == newTree.Id
+		})
+		if oldIndex == -1 {
+			changes = append(changes, Change{
+				Kind:    KindHeroTreeAdded,
+				Section: newTree.Name,
+				Message: fmt.Sprintf("added hero tree %s", newTree.Name),
+			})
+			continue
+		}
+
+		oldTree := &oldTrees[oldIndex]
+		if oldTree.Name != newTree.Name {
+			changes = append(changes, Change{
+				Kind:    KindHeroTreeRenamed,
+				Section: newTree.Name,
+				Old:     oldTree.Name,
+				New:     newTree.Name,
+				Message: fmt.Sprintf("hero tree %s renamed to %s", oldTree.Name, newTree.Name),
+			})
+		}
+		changes = compareNodes(changes, newTree.Name, oldTree.Nodes, newTree.Nodes)
+	}
+
+	for i := range oldTrees {
+		oldTree := &oldTrees[i]
+		found := slices.ContainsFunc(newTrees, func(tree wow.HeroTree) bool {
+			return tree.Id == oldTree.Id
+		})
+		if !found {
+			changes = append(changes, Change{
+				Kind:    KindHeroTreeRemoved,
+				Section: oldTree.Name,
+				Message: fmt.Sprintf("removed hero tree %s", oldTree.Name),
+			})
+		}
+	}
+	return changes
+}
+
+// compareTalents compares talents which sit outside of nodes, such as PvP and
+// apex talents, matching them by id.
+func compareTalents(
+	changes []Change,
+	section string,
+	addedKind ChangeKind,
+	removedKind ChangeKind,
+	oldTalents []wow.Talent,
+	newTalents []wow.Talent,
+) []Change {
+	for i := range newTalents {
+		newTalent := &newTalents[i]
+		oldIndex := slices.IndexFunc(oldTalents, func(talent wow.Talent) bool {
+			return talent.Id == newTalent.Id
+		})
+		if oldIndex == -1 {
+			changes = append(changes, Change{
+				Kind:     addedKind,
+				Section:  section,
+				TalentId: newTalent.Id,
+				Message:  fmt.Sprintf("added %s", newTalent.Name),
+			})
+			continue
+		}
+		changes = compareTalent(changes, section, 0, &oldTalents[oldIndex], newTalent)
+	}
+
+	for i := range oldTalents {
+		oldTalent := &oldTalents[i]
+		found := slices.ContainsFunc(newTalents, func(talent wow.Talent) bool {
+			return talent.Id == oldTalent.Id
+		})
+		if !found {
+			changes = append(changes, Change{
+				Kind:     removedKind,
+				Section:  section,
+				TalentId: oldTalent.Id,
+				Message:  fmt.Sprintf("removed %s", oldTalent.Name),
+			})
+		}
+	}
+	return changes
+}
+
+func talentIds(talents []wow.Talent) []int {
+	ids := make([]int, len(talents))
+	for i, talent := range talents {
+		ids[i] = talent.Id
+	}
+	return ids
+}
+
+func talentNames(talents []wow.Talent) string {
+	names := make([]string, len(talents))
+	for i, talent := range talents {
+		names[i] = talent.Name
+	}
+	return strings.Join(names, " / ")
+}
@@ -0,0 +1,233 @@
+package talentdiff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+func mockTalent(id int, name string, descriptions ...string) wow.Talent {
+	ranks := make([]wow.Rank, len(descriptions))
+	for i, description := range descriptions {
+		ranks[i] = wow.Rank{Description: description}
+	}
+	return wow.Talent{
+		Id:    id,
+		Name:  name,
+		Spell: wow.Spell{Id: id, Name: name, Ranks: ranks},
+	}
+}
+
+func mockNode(id int, row int, col int, talents ...wow.Talent) wow.TalentNode {
+	return wow.TalentNode{
+		Id:      id,
+		Row:     row,
+		Col:     col,
+		MaxRank: len(talents[0].Spell.Ranks),
+		Talents: talents,
+	}
+}
+
+// mockTree is a small tree with one of everything which can be compared.
+func mockTree() wow.TalentTree {
+	return wow.TalentTree{
+		ClassName: "Druid",
+		SpecName:  "Balance",
+		SpecId:    102,
+		ClassNodes: []wow.TalentNode{
+			mockNode(1, 1, 1, mockTalent(10, "Rake", "Rake")),
+		},
+		SpecNodes: []wow.TalentNode{
+			mockNode(2, 1, 1, mockTalent(20, "Starfall", "Starfall")),
+			mockNode(3, 2, 1, mockTalent(30, "Force of Nature", "Trees"), mockTalent(31, "Warrior of Elune", "Elune")),
+			mockNode(4, 3, 1, mockTalent(40, "Starlord", "Starlord 1", "Starlord 2")),
+		},
+		HeroTrees: []wow.HeroTree{
+			{Id: 24, Name: "Elune's Chosen", Nodes: []wow.TalentNode{
+				mockNode(100, 1, 1, mockTalent(1000, "Lunar Calling", "Lunar")),
+			}},
+		},
+		ApexTalents: []wow.Talent{
+			mockTalent(4000, "Sundered Firmament", "Firmament"),
+		},
+		PvpTalents: []wow.Talent{
+			mockTalent(5000, "Faerie Swarm", "Swarm"),
+		},
+	}
+}
+
+func expectChange(t *testing.T, diff Diff, kind ChangeKind, section string) Change {
+	t.Helper()
+	if len(diff.Specs) != 1 {
+		t.Fatalf("expected 1 changed spec, got %d", len(diff.Specs))
+	}
+	for _, change := range diff.Specs[0].Changes {
+		if change.Kind == kind && change.Section == section {
+			return change
+		}
+	}
+	t.Fatalf("expected %s change in %q, got %v", kind, section, diff.Specs[0].Changes)
+	return Change{}
+}
+
+func TestCompareIdenticalTrees(t *testing.T) {
+	diff := Compare([]wow.TalentTree{mockTree()}, []wow.TalentTree{mockTree()})
+	if len(diff.Specs) != 0 {
+		t.Fatalf("expected no changes, got %v", diff.Specs)
+	}
+}
+
+func TestCompareAddedAndRemovedNodes(t *testing.T) {
+	newTree := mockTree()
+	newTree.SpecNodes = append(newTree.SpecNodes[1:], mockNode(5, 4, 1, mockTalent(50, "Sundered Firmament", "Sunder")))
+
+	diff := Compare([]wow.TalentTree{mockTree()}, []wow.TalentTree{newTree})
+	added := expectChange(t, diff, KindNodeAdded, SectionSpec)
+	if added.NodeId != 5 {
+		t.Errorf("expected node 5 added, got %d", added.NodeId)
+	}
+	removed := expectChange(t, diff, KindNodeRemoved, SectionSpec)
+	if removed.NodeId != 2 {
+		t.Errorf("expected node 2 removed, got %d", removed.NodeId)
+	}
+}
+
+func TestCompareMovedNode(t *testing.T) {
+	newTree := mockTree()
+	newTree.ClassNodes[0].Col = 3
+
+	change := expectChange(t, Compare([]wow.TalentTree{mockTree()}, []wow.TalentTree{newTree}), KindNodeMoved, SectionClass)
+	if change.Old != "1,1" || change.New != "1,3" {
+		t.Errorf("expected move from 1,1 to 1,3, got %s to %s", change.Old, change.New)
+	}
+}
+
+func TestCompareMaxRankAndDescriptions(t *testing.T) {
+	newTree := mockTree()
+	newTree.SpecNodes[2] = mockNode(4, 3, 1, mockTalent(40, "Starlord", "Starlord 1", "Starlord 2 buffed", "Starlord 3"))
+
+	diff := Compare([]wow.TalentTree{mockTree()}, []wow.TalentTree{newTree})
+	expectChange(t, diff, KindMaxRank, SectionSpec)
+
+	descriptions := 0
+	for _, change := range diff.Specs[0].Changes {
+		if change.Kind == KindDescription {
+			descriptions++
+		}
+	}
+	if descriptions != 2 {
+		t.Errorf("expected 2 changed descriptions, got %d", descriptions)
+	}
+}
+
+func TestCompareChoiceOptions(t *testing.T) {
+	newTree := mockTree()
+	newTree.SpecNodes[1].Talents[1] = mockTalent(32, "Astral Communion", "Communion")
+
+	change := expectChange(t, Compare([]wow.TalentTree{mockTree()}, []wow.TalentTree{newTree}), KindChoice, SectionSpec)
+	if change.Old != "Force of Nature / Warrior of Elune" || change.New != "Force of Nature / Astral Communion" {
+		t.Errorf("unexpected choice change from %q to %q", change.Old, change.New)
+	}
+}
+
+func TestCompareReplacedTalent(t *testing.T) {
+	newTree := mockTree()
+	newTree.SpecNodes[0].Talents[0] = mockTalent(21, "Starsurge", "Starsurge")
+
+	expectChange(t, Compare([]wow.TalentTree{mockTree()}, []wow.TalentTree{newTree}), KindTalentReplaced, SectionSpec)
+}
+
+func TestCompareHeroTrees(t *testing.T) {
+	newTree := mockTree()
+	newTree.HeroTrees[0].Nodes[0].Talents[0].Spell.Ranks[0].Description = "Lunar buffed"
+	newTree.HeroTrees = append(newTree.HeroTrees, wow.HeroTree{Id: 23, Name: "Keeper of the Grove"})
+
+	diff := Compare([]wow.TalentTree{mockTree()}, []wow.TalentTree{newTree})
+	expectChange(t, diff, KindHeroTreeAdded, "Keeper of the Grove")
+	expectChange(t, diff, KindDescription, "Elune's Chosen")
+
+	diff = Compare([]wow.TalentTree{newTree}, []wow.TalentTree{mockTree()})
+	expectChange(t, diff, KindHeroTreeRemoved, "Keeper of the Grove")
+}
+
+func TestCompareRenamedHeroTree(t *testing.T) {
+	newTree := mockTree()
+	newTree.HeroTrees[0].Name = "Chosen of Elune"
+
+	diff := Compare([]wow.TalentTree{mockTree()}, []wow.TalentTree{newTree})
+	change := expectChange(t, diff, KindHeroTreeRenamed, "Chosen of Elune")
+	if change.Old != "Elune's Chosen" || change.New != "Chosen of Elune" {
+		t.Errorf("unexpected rename from %q to %q", change.Old, change.New)
+	}
+	if len(diff.Specs[0].Changes) != 1 {
+		t.Errorf("expected only the rename, got %v", diff.Specs[0].Changes)
+	}
+}
+
+func TestCompareApexTalents(t *testing.T) {
+	newTree := mockTree()
+	newTree.ApexTalents[0].Spell.Ranks[0].Description = "Firmament buffed"
+	newTree.ApexTalents = append(newTree.ApexTalents, mockTalent(4001, "Boundless Moonlight", "Moonlight"))
+
+	diff := Compare([]wow.TalentTree{mockTree()}, []wow.TalentTree{newTree})
+	added := expectChange(t, diff, KindApexTalentAdded, SectionApex)
+	if added.TalentId != 4001 {
+		t.Errorf("expected talent 4001 added, got %d", added.TalentId)
+	}
+	expectChange(t, diff, KindDescription, SectionApex)
+
+	diff = Compare([]wow.TalentTree{newTree}, []wow.TalentTree{mockTree()})
+	expectChange(t, diff, KindApexTalentRemoved, SectionApex)
+}
+
+func TestComparePvpTalents(t *testing.T) {
+	newTree := mockTree()
+	newTree.PvpTalents = []wow.Talent{mockTalent(5001, "Thorns", "Thorns")}
+
+	diff := Compare([]wow.TalentTree{mockTree()}, []wow.TalentTree{newTree})
+	expectChange(t, diff, KindPvpTalentAdded, SectionPvp)
+	expectChange(t, diff, KindPvpTalentRemoved, SectionPvp)
+}
+
+func TestCompareAddedAndRemovedSpecs(t *testing.T) {
+	feral := mockTree()
+	feral.SpecName = "Feral"
+	feral.SpecId = 103
+
+	diff := Compare([]wow.TalentTree{mockTree()}, []wow.TalentTree{feral})
+	if len(diff.Specs) != 2 {
+		t.Fatalf("expected 2 changed specs, got %d", len(diff.Specs))
+	}
+	if diff.Specs[0].SpecName != "Balance" || diff.Specs[0].Changes[0].Kind != KindSpecRemoved {
+		t.Errorf("expected Balance to be removed, got %v", diff.Specs[0])
+	}
+	if diff.Specs[1].SpecName != "Feral" || diff.Specs[1].Changes[0].Kind != KindSpecAdded {
+		t.Errorf("expected Feral to be added, got %v", diff.Specs[1])
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	newTree := mockTree()
+	newTree.ClassNodes[0].Row = 2
+
+	diff := Compare([]wow.TalentTree{mockTree()}, []wow.TalentTree{newTree})
+	var output bytes.Buffer
+	err := WriteText(&output, &diff)
+	if err != nil {
+		t.Fatalf("failed to write diff: %v", err)
+	}
+
+	expected := "Druid - Balance\n  [class] moved Rake from row 1, column 1 to row 2, column 1\n"
+	if output.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, output.String())
+	}
+
+	output.Reset()
+	empty := Compare(nil, nil)
+	err = WriteText(&output, &empty)
+	if err != nil || !strings.HasPrefix(output.String(), "No changes") {
+		t.Fatalf("expected no changes, got %q (%v)", output.String(), err)
+	}
+}
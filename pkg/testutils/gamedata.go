@@ -0,0 +1,62 @@
+package testutils
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/crbednarz/moonkinmetrics/pkg/wow"
+)
+
+//go:embed gamedata/druid-11.0.2.json
+var druidGameDataJson []byte
+
+// GameLoadout is a loadout saved in game, as reported by the specializations
+// API alongside its loadout code.
+type GameLoadout struct {
+	wow.Loadout
+	// GrantedNodeIds are the selected nodes granted for free by the spec.
+	// Most codes leave them out.
+	GrantedNodeIds []int `json:"granted_node_ids"`
+	HeroTreeId     int   `json:"hero_tree_id"`
+}
+
+// GameData is a set of in-game loadouts along with the talent trees they were
+// saved for.
+type GameData struct {
+	Patch string `json:"patch"`
+	// NodeOrder is every node id of the class's in-game tree, as walked by
+	// loadout codes.
+	NodeOrder []int            `json:"node_order"`
+	Trees     []wow.TalentTree `json:"trees"`
+	Loadouts  []GameLoadout    `json:"loadouts"`
+}
+
+// LoadDruidGameData returns the saved Druid loadouts of
+// pkg/retrieve/players/testdata/valid-player.json, from patch 11.0.2.
+//
+// The API doesn't serve trees from past patches, so the trees were recovered
+// from the loadouts themselves. They only hold the nodes and talents selected
+// by at least one loadout, and choice options which were never selected are
+// left with a zero talent id. Max ranks of nodes which were never fully ranked
+// are taken from the current trees. Node ids in NodeOrder which no loadout
+// selects were filled in from the current trees where possible, and with
+// unused ids otherwise, so only their count is reliable.
+func LoadDruidGameData() (*GameData, error) {
+	var data GameData
+	err := json.Unmarshal(druidGameDataJson, &data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse game data: %w", err)
+	}
+	return &data, nil
+}
+
+// Tree returns the tree of the given spec, or nil if there is none.
+func (d *GameData) Tree(specName string) *wow.TalentTree {
+	for i := range d.Trees {
+		if d.Trees[i].SpecName == specName {
+			return &d.Trees[i]
+		}
+	}
+	return nil
+}
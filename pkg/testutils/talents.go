@@ -25,6 +25,10 @@ func NewMockTalentScanner() (*scan.Scanner, error) {
 			return MockSpellMediaJson(id), true
 		}
 
+		if strings.HasPrefix(requestPath, "/data/wow/media/talent-tree/") {
+			return MockHeroTreeMediaJson(requestPath), true
+		}
+
 		if id, found := strings.CutPrefix(requestPath, "/data/wow/pvp-talent/"); found {
 			return strings.ReplaceAll(pvpTalentJson, "100", id), true
 		}
@@ -50,3 +54,22 @@ func MockSpellMediaJson(id string) string {
     "id": %[1]v
   }`, id)
 }
+
+// MockHeroTreeMediaJson returns a hero tree media document whose icon is the
+// requested path, so each hero tree can be told apart.
+func MockHeroTreeMediaJson(path string) string {
+	return fmt.Sprintf(`{
+    "_links": {
+      "self": {
+        "href": "https://us.api.blizzard.com%[1]v?namespace=static-11.0.2_55938-us"
+      }
+    },
+    "assets": [
+      {
+        "key": "icon",
+        "value": "%[1]v",
+        "file_data_id": 5927656
+      }
+    ]
+  }`, path)
+}
@@ -12,6 +12,24 @@ type JsonSchemaValidator[T any] struct {
 	schema *gojsonschema.Schema
 }
 
+// SchemaError is returned when an object fails schema validation.
+type SchemaError struct {
+	Errors []gojsonschema.ResultError
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("failed schema validation: %v", e.Errors)
+}
+
+// Paths returns the field path of each validation failure.
+func (e *SchemaError) Paths() []string {
+	paths := make([]string, len(e.Errors))
+	for i, resultError := range e.Errors {
+		paths[i] = resultError.Field()
+	}
+	return paths
+}
+
 type jsonGoLoader struct {
 	source interface{}
 }
@@ -69,7 +87,7 @@ func (v *JsonSchemaValidator[T]) IsValid(object *T) error {
 	}
 
 	if !result.Valid() {
-		return fmt.Errorf("failed schema validation: %v", result.Errors())
+		return &SchemaError{Errors: result.Errors()}
 	}
 
 	return nil
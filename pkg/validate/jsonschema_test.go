@@ -1,6 +1,7 @@
 package validate
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -30,3 +31,21 @@ func TestSchemaCanFailValidation(t *testing.T) {
 		t.Fatal("expected validation to fail")
 	}
 }
+
+func TestSchemaErrorHasPaths(t *testing.T) {
+	v, err := NewSchemaValidator[testStruct](`{"type": "object","properties": {"foo": {"type": "string", "minLength": 3}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	object := testStruct{}
+	err = v.IsValid(&object)
+
+	var schemaError *SchemaError
+	if !errors.As(err, &schemaError) {
+		t.Fatalf("expected SchemaError, got %v", err)
+	}
+	paths := schemaError.Paths()
+	if len(paths) != 1 || paths[0] != "foo" {
+		t.Fatalf("expected paths [foo], got %v", paths)
+	}
+}
@@ -0,0 +1,100 @@
+package wow
+
+import (
+	"slices"
+	"strings"
+)
+
+type Class struct {
+	Name  string
+	Slug  string
+	Specs []Spec
+	Id    int
+}
+
+type Spec struct {
+	Name      string
+	Slug      string
+	ClassName string
+	// Role is one of "TANK", "HEALER" or "DAMAGE", or empty if unknown.
+	Role    string
+	Id      int
+	ClassId int
+}
+
+// ClassRegistry holds every playable class and specialization.
+type ClassRegistry struct {
+	Classes []Class
+}
+
+// Slugify converts a class or spec name to the form used in bracket names,
+// such as "Death Knight" to "deathknight".
+func Slugify(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, " ", ""))
+}
+
+// NewClassRegistry creates a registry from the given classes, sorted by id
+// and then name so iteration is stable.
+func NewClassRegistry(classes []Class) *ClassRegistry {
+	classes = slices.Clone(classes)
+	for i := range classes {
+		classes[i].Specs = slices.Clone(classes[i].Specs)
+		slices.SortFunc(classes[i].Specs, func(a, b Spec) int {
+			if a.Id != b.Id {
+				return a.Id - b.Id
+			}
+			return strings.Compare(a.Name, b.Name)
+		})
+	}
+	slices.SortFunc(classes, func(a, b Class) int {
+		if a.Id != b.Id {
+			return a.Id - b.Id
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return &ClassRegistry{Classes: classes}
+}
+
+// DefaultClassRegistry builds a registry from the hardcoded SpecByClass.
+// It lacks ids and roles, so should only be used when the API is unavailable.
+func DefaultClassRegistry() *ClassRegistry {
+	classes := make([]Class, 0, len(ClassNames))
+	for _, className := range ClassNames {
+		class := Class{
+			Name: className,
+			Slug: Slugify(className),
+		}
+		for _, specName := range SpecByClass[className] {
+			class.Specs = append(class.Specs, Spec{
+				Name:      specName,
+				Slug:      Slugify(specName),
+				ClassName: className,
+			})
+		}
+		classes = append(classes, class)
+	}
+	return NewClassRegistry(classes)
+}
+
+// Specs returns every specialization of every class.
+func (r *ClassRegistry) Specs() []Spec {
+	specs := make([]Spec, 0, len(r.Classes)*4)
+	for _, class := range r.Classes {
+		specs = append(specs, class.Specs...)
+	}
+	return specs
+}
+
+func (r *ClassRegistry) SpecById(id int) (Spec, bool) {
+	if id == 0 {
+		return Spec{}, false
+	}
+	for _, class := range r.Classes {
+		for _, spec := range class.Specs {
+			if spec.Id == id {
+				return spec, true
+			}
+		}
+	}
+	return Spec{}, false
+}
@@ -1,5 +1,7 @@
 package wow
 
+// ClassNames and SpecByClass are maintained by hand, and are only used to build
+// DefaultClassRegistry when the playable class indexes can't be retrieved.
 var ClassNames = []string{
 	"Hunter",
 	"Shaman",
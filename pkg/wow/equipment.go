@@ -0,0 +1,42 @@
+package wow
+
+// Equipment is a compact summary of the items a character has equipped.
+type Equipment struct {
+	Items []EquippedItem
+	Sets  []EquippedSet
+}
+
+type EquippedItem struct {
+	Slot      string
+	Enchants  []int
+	Gems      []int
+	Id        int
+	ItemLevel int
+}
+
+// EquippedSet is an item set the character is wearing pieces of, such as a
+// tier set, along with how many of its bonuses are active.
+type EquippedSet struct {
+	Name          string
+	Id            int
+	EquippedCount int
+	ActiveBonuses int
+}
+
+// AverageItemLevel returns the mean item level of the equipped items,
+// ignoring cosmetic slots.
+func (e *Equipment) AverageItemLevel() float64 {
+	total := 0
+	count := 0
+	for _, item := range e.Items {
+		if item.Slot == "SHIRT" || item.Slot == "TABARD" {
+			continue
+		}
+		total += item.ItemLevel
+		count++
+	}
+	if count == 0 {
+		return 0
+	}
+	return float64(total) / float64(count)
+}
@@ -1,5 +1,7 @@
 package wow
 
+import "time"
+
 type Loadout struct {
 	ClassName  string
 	SpecName   string
@@ -20,3 +22,31 @@ type LoadoutNode struct {
 type LoadoutPvpTalent struct {
 	Id int
 }
+
+// PvpBracketStatistics is a character's standing in a single PvP bracket.
+// Per-spec brackets, such as shuffle, also report the specialization played.
+type PvpBracketStatistics struct {
+	Bracket      string
+	SpecName     string
+	Rating       uint
+	SeasonId     int
+	TierId       int
+	SpecId       int
+	Season       MatchStatistics
+	Weekly       MatchStatistics
+	SeasonRounds MatchStatistics
+	WeeklyRounds MatchStatistics
+}
+
+// CharacterSummary is the overview shown on a character's profile.
+// Characters without a guild or title leave them empty.
+type CharacterSummary struct {
+	Guild             string
+	GuildRealm        string
+	Title             string
+	LastLogin         time.Time
+	Level             int
+	AverageItemLevel  int
+	EquippedItemLevel int
+	TitleId           int
+}
@@ -3,6 +3,7 @@ package wow
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/crbednarz/moonkinmetrics/pkg/api"
 )
@@ -11,12 +12,39 @@ type Leaderboard struct {
 	Bracket string
 	Region  api.Region
 	Entries []LeaderboardEntry
+	// Season is the PvP season the leaderboard belongs to, or zero for
+	// leaderboards which aren't tied to a season.
+	Season int
+	// Timestamp is when the leaderboard was retrieved from the API, as the API
+	// doesn't report when leaderboards are generated.
+	Timestamp time.Time
 }
 
 type LeaderboardEntry struct {
-	Player  PlayerLink
-	Faction string
-	Rating  uint
+	Player          PlayerLink
+	Faction         string
+	Rating          uint
+	Rank            uint
+	TierId          int
+	MatchStatistics MatchStatistics
+	// SpecId is the specialization the entry was ranked as, for leaderboards
+	// which rank players by specialization. Zero otherwise.
+	SpecId int
+}
+
+type MatchStatistics struct {
+	Played uint
+	Won    uint
+	Lost   uint
+}
+
+// WinRate returns the fraction of played matches which were won, or zero if
+// no matches were played.
+func (m MatchStatistics) WinRate() float64 {
+	if m.Played == 0 {
+		return 0
+	}
+	return float64(m.Won) / float64(m.Played)
 }
 
 type PlayerLink struct {
@@ -39,6 +67,18 @@ func (p PlayerLink) SpecializationUrl() string {
 	return fmt.Sprintf("/profile/wow/character/%s/%s/specializations", p.Realm.Slug, strings.ToLower(p.Name))
 }
 
+func (p PlayerLink) SummaryUrl() string {
+	return fmt.Sprintf("/profile/wow/character/%s/%s", p.Realm.Slug, strings.ToLower(p.Name))
+}
+
+func (p PlayerLink) EquipmentUrl() string {
+	return fmt.Sprintf("/profile/wow/character/%s/%s/equipment", p.Realm.Slug, strings.ToLower(p.Name))
+}
+
+func (p PlayerLink) PvpBracketUrl(bracket string) string {
+	return fmt.Sprintf("/profile/wow/character/%s/%s/pvp-bracket/%s", p.Realm.Slug, strings.ToLower(p.Name), bracket)
+}
+
 func (l *Leaderboard) GetUniqueRealms() []RealmLink {
 	realmLinkMap := make(map[string]RealmLink)
 
@@ -58,17 +98,39 @@ func (l *Leaderboard) GetUniqueRealms() []RealmLink {
 }
 
 func (l *Leaderboard) FilterByMinRating(minRating uint) Leaderboard {
+	return l.filter(func(entry *LeaderboardEntry) bool {
+		return entry.Rating >= minRating
+	})
+}
+
+func (l *Leaderboard) FilterByMinGamesPlayed(minPlayed uint) Leaderboard {
+	return l.filter(func(entry *LeaderboardEntry) bool {
+		return entry.MatchStatistics.Played >= minPlayed
+	})
+}
+
+// FilterByMinWinRate keeps entries whose win rate, between 0 and 1, is at
+// least minWinRate.
+func (l *Leaderboard) FilterByMinWinRate(minWinRate float64) Leaderboard {
+	return l.filter(func(entry *LeaderboardEntry) bool {
+		return entry.MatchStatistics.WinRate() >= minWinRate
+	})
+}
+
+func (l *Leaderboard) filter(keep func(entry *LeaderboardEntry) bool) Leaderboard {
 	entries := make([]LeaderboardEntry, 0, len(l.Entries))
 
 	for i := range l.Entries {
-		if l.Entries[i].Rating >= minRating {
+		if keep(&l.Entries[i]) {
 			entries = append(entries, l.Entries[i])
 		}
 	}
 
 	return Leaderboard{
-		Bracket: l.Bracket,
-		Region:  l.Region,
-		Entries: entries,
+		Bracket:   l.Bracket,
+		Region:    l.Region,
+		Entries:   entries,
+		Season:    l.Season,
+		Timestamp: l.Timestamp,
 	}
 }
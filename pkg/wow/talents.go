@@ -36,10 +36,20 @@ type TalentNode struct {
 }
 
 type HeroTree struct {
-	Name  string
-	Icon  string
-	Nodes []TalentNode
-	Id    int
+	Name string
+	Icon string
+	// MediaUrl is the API link to the hero tree's media, which Icon is
+	// resolved from.
+	MediaUrl string
+	Nodes    []TalentNode
+	Id       int
+}
+
+// TalentGate requires points to be spent in the rows above Row before any
+// node on or below it can be selected.
+type TalentGate struct {
+	Row            int
+	RequiredPoints int
 }
 
 type TalentTree struct {
@@ -47,9 +57,14 @@ type TalentTree struct {
 	SpecName    string
 	ClassNodes  []TalentNode
 	SpecNodes   []TalentNode
+	ClassGates  []TalentGate
+	SpecGates   []TalentGate
 	HeroTrees   []HeroTree
 	ApexTalents []Talent
 	PvpTalents  []Talent
 	ClassId     int
 	SpecId      int
+	// HeroSelectionNodeId is the hidden class node which records the chosen
+	// hero tree in loadout codes, or zero if the tree has none.
+	HeroSelectionNodeId int
 }
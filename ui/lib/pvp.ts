@@ -121,6 +121,8 @@ interface V1Leaderboard {
   }
   entries: string[];
   timestamp: number;
+  // Set for past seasons, whose talents are as of the timestamp.
+  historical?: boolean;
 }
 
 function decodeLoadoutsV1(encodedLeaderboard: any, tree: TalentTree, region: string): RatedLoadout[] {
@@ -203,6 +205,10 @@ function decodeRegionLeaderboard(regionLeaderboard: any, tree: TalentTree, regio
     case 0:
       return decodeLoadoutsV0(regionLeaderboard.entries, tree, region);
     case 1:
+    // Version 2 appends rank, tier and match statistics, and version 3 appends
+    // entry flags and item level. Neither are displayed yet.
+    case 2:
+    case 3:
       return decodeLoadoutsV1(regionLeaderboard, tree, region);
     default:
       throw new Error(`Unknown encoding version: ${version}`);